@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var alertProviderCmd = &cobra.Command{
+	Use:     "alert-provider",
+	Aliases: []string{"provider"},
+	Short:   "Operate on Provider resources",
+	Long:    "The alert-provider sub-commands operate on the notification Provider resources.",
+}
+
+var alertProviderVerifyCmd = &cobra.Command{
+	Use:   "verify [name]",
+	Short: "Verify that a Provider can deliver notifications",
+	Long: `The alert-provider verify command creates a temporary Alert for the Provider, posts a test event
+to the notification-controller and reports the errors the controller logged while dispatching it,
+such as invalid tokens or unreachable addresses. The temporary Alert is deleted afterwards.
+Other Alerts in the namespace matching all Kustomizations receive the test event too.`,
+	Example: `  # Verify the credentials and address of the slack Provider
+  flux alert-provider verify slack`,
+	ValidArgsFunction: resourceNamesCompletionFunc(notificationv1.GroupVersion.WithKind(notificationv1.ProviderKind)),
+	RunE:              alertProviderVerifyCmdRun,
+}
+
+type alertProviderVerifyFlags struct {
+	wait          time.Duration
+	fluxNamespace string
+}
+
+var alertProviderVerifyArgs alertProviderVerifyFlags
+
+func init() {
+	alertProviderVerifyCmd.Flags().DurationVar(&alertProviderVerifyArgs.wait, "wait", 10*time.Second,
+		"how long to wait for the provider dispatch before reading the controller logs")
+	alertProviderVerifyCmd.Flags().StringVar(&alertProviderVerifyArgs.fluxNamespace, "flux-namespace", rootArgs.defaults.Namespace,
+		"the namespace where the Flux components are running")
+	alertProviderCmd.AddCommand(alertProviderVerifyCmd)
+	rootCmd.AddCommand(alertProviderCmd)
+}
+
+func alertProviderVerifyCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("Provider name is required")
+	}
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	var provider notificationv1.Provider
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: *kubeconfigArgs.Namespace, Name: name}, &provider); err != nil {
+		return err
+	}
+	if c := apimeta.FindStatusCondition(provider.Status.Conditions, meta.ReadyCondition); c == nil || c.Status != metav1.ConditionTrue {
+		return fmt.Errorf("Provider %s is not ready", name)
+	}
+
+	return verifyAlertProvider(ctx, kubeClient, &provider)
+}
+
+// verifyAlertProvider dispatches a test event to the Provider through a
+// temporary Alert and fails if the notification-controller logged errors.
+func verifyAlertProvider(ctx context.Context, kubeClient client.Client, provider *notificationv1.Provider) error {
+	name := provider.Name
+	alert := notificationv1.Alert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-verify", name),
+			Namespace: provider.Namespace,
+		},
+		Spec: notificationv1.AlertSpec{
+			ProviderRef: meta.LocalObjectReference{
+				Name: name,
+			},
+			EventSeverity: "info",
+			EventSources: []notificationv1.CrossNamespaceObjectReference{
+				{
+					Kind: "Kustomization",
+					Name: fmt.Sprintf("%s-verify", name),
+				},
+			},
+		},
+	}
+
+	logger.Actionf("creating temporary Alert %s", alert.Name)
+	if err := kubeClient.Create(ctx, &alert); err != nil {
+		return err
+	}
+	defer func() {
+		if err := kubeClient.Delete(context.Background(), &alert); err != nil {
+			logger.Failuref("deleting temporary Alert %s failed: %s", alert.Name, err.Error())
+		}
+	}()
+
+	if err := waitForObject(ctx, kubeClient, types.NamespacedName{Namespace: alert.Namespace, Name: alert.Name}, &alert,
+		isAlertReady(ctx, kubeClient, types.NamespacedName{Namespace: alert.Namespace, Name: alert.Name}, &alert)); err != nil {
+		return err
+	}
+
+	event, err := makeAlertTestEvent(alert, fmt.Sprintf("Verification of Provider %s by flux alert-provider verify", name))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := utils.KubeConfig(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	since := metav1.Now()
+	if err := postNotificationEvent(ctx, clientset, alertProviderVerifyArgs.fluxNamespace, event); err != nil {
+		return err
+	}
+
+	logger.Waitingf("waiting %s for the provider dispatch", alertProviderVerifyArgs.wait.String())
+	time.Sleep(alertProviderVerifyArgs.wait)
+
+	errs, err := alertTestDispatchErrors(ctx, clientset, alertProviderVerifyArgs.fluxNamespace, since, event)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			logger.Failuref("%s", e)
+		}
+		return fmt.Errorf("Provider %s verification failed", name)
+	}
+
+	logger.Successf("Provider %s verified", name)
+	return nil
+}
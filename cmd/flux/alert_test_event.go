@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var alertCmd = &cobra.Command{
+	Use:   "alert",
+	Short: "Operate on Alert resources",
+	Long:  "The alert sub-commands operate on the Alert resources and their providers.",
+}
+
+var alertTestCmd = &cobra.Command{
+	Use:   "test [name]",
+	Short: "Send a test event through an Alert",
+	Long: `The alert test command posts a synthetic event matching the first event source of an Alert
+to the notification-controller, then inspects the controller logs to report whether the event
+was dispatched to the provider.`,
+	Example: `  # Send a test event to the provider of the flux-system Alert
+  flux alert test flux-system
+
+  # Wait longer for the provider to respond
+  flux alert test slack --wait=30s`,
+	ValidArgsFunction: resourceNamesCompletionFunc(notificationv1.GroupVersion.WithKind(notificationv1.AlertKind)),
+	RunE:              alertTestCmdRun,
+}
+
+type alertTestFlags struct {
+	message       string
+	wait          time.Duration
+	fluxNamespace string
+}
+
+var alertTestArgs alertTestFlags
+
+func init() {
+	alertTestCmd.Flags().StringVar(&alertTestArgs.message, "message", "Test event emitted by flux alert test",
+		"the message of the test event")
+	alertTestCmd.Flags().DurationVar(&alertTestArgs.wait, "wait", 10*time.Second,
+		"how long to wait for the provider dispatch before reading the controller logs")
+	alertTestCmd.Flags().StringVar(&alertTestArgs.fluxNamespace, "flux-namespace", rootArgs.defaults.Namespace,
+		"the namespace where the Flux components are running")
+	alertCmd.AddCommand(alertTestCmd)
+	rootCmd.AddCommand(alertCmd)
+}
+
+func alertTestCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("Alert name is required")
+	}
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	var alert notificationv1.Alert
+	namespacedName := types.NamespacedName{Namespace: *kubeconfigArgs.Namespace, Name: name}
+	if err := kubeClient.Get(ctx, namespacedName, &alert); err != nil {
+		return err
+	}
+
+	if c := apimeta.FindStatusCondition(alert.Status.Conditions, meta.ReadyCondition); c == nil || c.Status != metav1.ConditionTrue {
+		logger.Warningf("Alert %s is not ready, the event may not be dispatched", name)
+	}
+	if alert.Spec.Suspend {
+		return fmt.Errorf("Alert %s is suspended", name)
+	}
+
+	event, err := makeAlertTestEvent(alert, alertTestArgs.message)
+	if err != nil {
+		return err
+	}
+	cfg, err := utils.KubeConfig(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	logger.Actionf("posting test event for %s/%s/%s", event.InvolvedObject.Kind,
+		event.InvolvedObject.Namespace, event.InvolvedObject.Name)
+	since := metav1.Now()
+	if err := postNotificationEvent(ctx, clientset, alertTestArgs.fluxNamespace, event); err != nil {
+		return err
+	}
+
+	logger.Waitingf("waiting %s for the provider dispatch", alertTestArgs.wait.String())
+	time.Sleep(alertTestArgs.wait)
+
+	errs, err := alertTestDispatchErrors(ctx, clientset, alertTestArgs.fluxNamespace, since, event)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			logger.Failuref("%s", e)
+		}
+		return fmt.Errorf("dispatching the test event to Provider %s failed", alert.Spec.ProviderRef.Name)
+	}
+
+	logger.Successf("test event dispatched to Provider %s", alert.Spec.ProviderRef.Name)
+	return nil
+}
+
+// makeAlertTestEvent returns an event involving the first source of the Alert,
+// with a severity that passes the Alert severity filter.
+func makeAlertTestEvent(alert notificationv1.Alert, message string) (events.Event, error) {
+	if len(alert.Spec.EventSources) == 0 {
+		return events.Event{}, fmt.Errorf("Alert %s has no event sources", alert.Name)
+	}
+
+	source := alert.Spec.EventSources[0]
+	name := source.Name
+	if name == "*" {
+		name = "flux-alert-test"
+	}
+	namespace := source.Namespace
+	if namespace == "" {
+		namespace = alert.Namespace
+	}
+
+	severity := events.EventSeverityInfo
+	if alert.Spec.EventSeverity == events.EventSeverityError {
+		severity = events.EventSeverityError
+	}
+
+	return events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      source.Kind,
+			Name:      name,
+			Namespace: namespace,
+		},
+		Severity:            severity,
+		Timestamp:           metav1.Now(),
+		Message:             message,
+		Reason:              "Test",
+		ReportingController: "flux",
+	}, nil
+}
+
+// postNotificationEvent sends the event to the notification-controller
+// service through the API server proxy.
+func postNotificationEvent(ctx context.Context, clientset *kubernetes.Clientset, namespace string, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	result := clientset.CoreV1().RESTClient().Post().
+		Namespace(namespace).
+		Resource("services").
+		Name("notification-controller:http").
+		SubResource("proxy").
+		SetHeader("Content-Type", "application/json").
+		Body(payload).
+		Do(ctx)
+	var statusCode int
+	result.StatusCode(&statusCode)
+	if err := result.Error(); err != nil {
+		return fmt.Errorf("posting the event to notification-controller failed: %w", err)
+	}
+	logger.Successf("event accepted by notification-controller (HTTP %d)", statusCode)
+	return nil
+}
+
+// alertTestDispatchErrors returns the errors logged by notification-controller
+// since the given time about the object involved in the event.
+func alertTestDispatchErrors(ctx context.Context, clientset *kubernetes.Clientset, namespace string,
+	since metav1.Time, event events.Event) ([]string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=notification-controller",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no notification-controller pods found in %s namespace", namespace)
+	}
+
+	var errs []string
+	for _, pod := range pods.Items {
+		stream, err := clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			SinceTime: &since,
+		}).Stream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(stream)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "{") {
+				continue
+			}
+			var entry ControllerLogEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				continue
+			}
+			if entry.Level.String() != "error" || !strings.Contains(line, event.InvolvedObject.Name) {
+				continue
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", entry.Message, entry.Error))
+		}
+		stream.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
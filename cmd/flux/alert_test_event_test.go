@@ -0,0 +1,57 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestMakeAlertTestEvent(t *testing.T) {
+	alert := notificationv1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "apps"},
+		Spec: notificationv1.AlertSpec{
+			EventSeverity: "error",
+			EventSources: []notificationv1.CrossNamespaceObjectReference{
+				{Kind: "Kustomization", Name: "*"},
+			},
+		},
+	}
+
+	event, err := makeAlertTestEvent(alert, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.InvolvedObject.Kind != "Kustomization" || event.InvolvedObject.Name != "flux-alert-test" ||
+		event.InvolvedObject.Namespace != "apps" {
+		t.Errorf("unexpected involved object %v", event.InvolvedObject)
+	}
+	if event.Severity != "error" {
+		t.Errorf("expected error severity, got %s", event.Severity)
+	}
+
+	alert.Spec.EventSources = nil
+	if _, err := makeAlertTestEvent(alert, "test"); err == nil {
+		t.Error("expected error for an Alert without sources")
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fluxcd/flux2/internal/build"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+)
+
+var buildHrCmd = &cobra.Command{
+	Use:     "helmrelease",
+	Aliases: []string{"hr"},
+	Short:   "Build HelmRelease",
+	Long: `The build command queries the Kubernetes API and fetches the specified Flux HelmRelease.
+It then downloads the chart artifact from source-controller, or uses the local chart pointed at by --chart-path,
+merges the values of the HelmRelease with the local values files, renders the chart with the Helm template engine
+and applies the post renderers. Finally it writes the resulting multi-doc YAML to stdout.`,
+	Example: `# Build the manifests of a HelmRelease as they would be applied on the cluster
+flux build helmrelease podinfo
+
+# Build the manifests with local values taking precedence over the values of the HelmRelease
+flux build helmrelease podinfo --values ./values-dev.yaml
+
+# Build the manifests of a local chart
+flux build helmrelease podinfo --chart-path ./charts/podinfo`,
+	ValidArgsFunction: resourceNamesCompletionFunc(helmv2.GroupVersion.WithKind(helmv2.HelmReleaseKind)),
+	RunE:              buildHrCmdRun,
+}
+
+type buildHrFlags struct {
+	chartPath   string
+	valuesFiles []string
+}
+
+var buildHrArgs buildHrFlags
+
+func init() {
+	buildHrCmd.Flags().StringVar(&buildHrArgs.chartPath, "chart-path", "",
+		"path to a local chart directory or archive, used instead of the chart of the HelmRelease")
+	buildHrCmd.Flags().StringSliceVarP(&buildHrArgs.valuesFiles, "values", "f", nil,
+		"local values files merged on top of the values of the HelmRelease, can be specified multiple times")
+	buildCmd.AddCommand(buildHrCmd)
+}
+
+func buildHrCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("%s name is required", helmReleaseType.humanKind)
+	}
+	name := args[0]
+
+	builder, err := build.NewHelmReleaseBuilder(kubeconfigArgs, name,
+		build.WithChartPath(buildHrArgs.chartPath),
+		build.WithValuesFiles(buildHrArgs.valuesFiles),
+		build.WithHelmReleaseTimeout(rootArgs.timeout))
+	if err != nil {
+		return err
+	}
+
+	manifests, err := builder.Build()
+	if err != nil {
+		return err
+	}
+
+	cmd.Print(string(manifests))
+	return nil
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -33,21 +35,51 @@ var buildKsCmd = &cobra.Command{
 	Short:   "Build Kustomization",
 	Long: `The build command queries the Kubernetes API and fetches the specified Flux Kustomization. 
 It then uses the fetched in cluster flux kustomization to perform needed transformation on the local kustomization.yaml
-pointed at by --path. The local kustomization.yaml is generated if it does not exist. Finally it builds the overlays using the local kustomization.yaml, and write the resulting multi-doc YAML to stdout.`,
+pointed at by --path. The local kustomization.yaml is generated if it does not exist. Finally it builds the overlays using the local kustomization.yaml, and write the resulting multi-doc YAML to stdout.
+With --from-source the latest artifact of the GitRepository or Bucket referenced by the Kustomization is downloaded
+from source-controller, and the Kustomization spec.path is built from it instead of a local directory.
+The resources are written as they're rendered, and --max-memory aborts the build of very large
+kustomizations before the process runs out of memory. When the build is aborted, the resources written
+to stdout so far are a truncated output that must be discarded.
+The post-build variables can be set with --substitute and --substitute-from-file, the ConfigMaps and Secrets
+of spec.postBuild.substituteFrom are then not read from the cluster, and the variables override spec.postBuild.substitute.`,
 	Example: `# Build the local manifests as they were built on the cluster
-flux build kustomization my-app --path ./path/to/local/manifests`,
+flux build kustomization my-app --path ./path/to/local/manifests
+
+# Build the manifests of the latest source artifact as the controller sees them
+flux build kustomization my-app --from-source
+
+# Build a large Kustomization with a memory limit
+flux build kustomization my-app --path ./path/to/local/manifests --max-memory=1Gi
+
+# Build the local manifests with stubbed post-build variables
+flux build kustomization my-app --path ./path/to/local/manifests \
+  --substitute-from-file ./ci/vars.env \
+  --substitute cluster_name=ci`,
 	ValidArgsFunction: resourceNamesCompletionFunc(kustomizev1.GroupVersion.WithKind(kustomizev1.KustomizationKind)),
 	RunE:              buildKsCmdRun,
 }
 
 type buildKsFlags struct {
-	path string
+	path                string
+	fromSource          bool
+	maxMemory           string
+	substitute          []string
+	substituteFromFiles []string
 }
 
 var buildKsArgs buildKsFlags
 
 func init() {
 	buildKsCmd.Flags().StringVar(&buildKsArgs.path, "path", "", "Path to the manifests location.)")
+	buildKsCmd.Flags().BoolVar(&buildKsArgs.fromSource, "from-source", false,
+		"build the manifests from the latest artifact of the Kustomization source instead of a local path")
+	buildKsCmd.Flags().StringArrayVar(&buildKsArgs.substitute, "substitute", nil,
+		"post-build variable in the form key=value, can be specified multiple times")
+	buildKsCmd.Flags().StringArrayVar(&buildKsArgs.substituteFromFiles, "substitute-from-file", nil,
+		"file with a post-build variable in the form key=value per line, can be specified multiple times")
+	buildKsCmd.Flags().StringVar(&buildKsArgs.maxMemory, "max-memory", "",
+		"abort the build when the memory usage exceeds this quantity, e.g. 512Mi, the output written until then is truncated")
 	buildCmd.AddCommand(buildKsCmd)
 }
 
@@ -57,15 +89,33 @@ func buildKsCmdRun(cmd *cobra.Command, args []string) error {
 	}
 	name := args[0]
 
-	if buildKsArgs.path == "" {
-		return fmt.Errorf("invalid resource path %q", buildKsArgs.path)
+	maxMemory, err := parseMemoryLimit(buildKsArgs.maxMemory)
+	if err != nil {
+		return err
 	}
 
-	if fs, err := os.Stat(buildKsArgs.path); err != nil || !fs.IsDir() {
-		return fmt.Errorf("invalid resource path %q", buildKsArgs.path)
+	substitutions, err := parseSubstitutions(buildKsArgs.substituteFromFiles, buildKsArgs.substitute)
+	if err != nil {
+		return err
 	}
 
-	builder, err := build.NewBuilder(kubeconfigArgs, name, buildKsArgs.path, build.WithTimeout(rootArgs.timeout))
+	if buildKsArgs.fromSource {
+		if buildKsArgs.path != "" {
+			return fmt.Errorf("the --path and --from-source flags are mutually exclusive")
+		}
+	} else {
+		if buildKsArgs.path == "" {
+			return fmt.Errorf("invalid resource path %q", buildKsArgs.path)
+		}
+
+		if fs, err := os.Stat(buildKsArgs.path); err != nil || !fs.IsDir() {
+			return fmt.Errorf("invalid resource path %q", buildKsArgs.path)
+		}
+	}
+
+	builder, err := build.NewBuilder(kubeconfigArgs, name, buildKsArgs.path,
+		build.WithTimeout(rootArgs.timeout), build.WithFromSource(buildKsArgs.fromSource),
+		build.WithSubstitutions(substitutions))
 	if err != nil {
 		return err
 	}
@@ -74,21 +124,27 @@ func buildKsCmdRun(cmd *cobra.Command, args []string) error {
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, os.Interrupt)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	memc := watchMemory(ctx, maxMemory)
+
 	errChan := make(chan error)
 	go func() {
-		manifests, err := builder.Build()
-		if err != nil {
-			errChan <- err
-		}
-
-		cmd.Print(string(manifests))
-		errChan <- nil
+		// the manifests are written as they're rendered
+		errChan <- builder.BuildTo(ctx, cmd.OutOrStdout())
 	}()
 
 	select {
 	case <-sigc:
 		fmt.Println("Build cancelled... exiting.")
 		return builder.Cancel()
+	case err := <-memc:
+		// stop writing the resources, stdout is left truncated
+		cancel()
+		if errc := builder.Cancel(); errc != nil {
+			return errc
+		}
+		return fmt.Errorf("build aborted: %w", err)
 	case err := <-errChan:
 		if err != nil {
 			return err
@@ -98,3 +154,48 @@ func buildKsCmdRun(cmd *cobra.Command, args []string) error {
 	return nil
 
 }
+
+// parseSubstitutions returns the post-build variables of the files, then of
+// the key=value pairs. The files have a key=value pair per line, the empty
+// lines and the lines starting with # are skipped.
+func parseSubstitutions(files, pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read substitutions file: %w", err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, err := parseSubstitution(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", file, i+1, err)
+			}
+			vars[key] = value
+		}
+	}
+
+	for _, pair := range pairs {
+		key, value, err := parseSubstitution(pair)
+		if err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	return vars, nil
+}
+
+func parseSubstitution(pair string) (string, string, error) {
+	kv := strings.SplitN(pair, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return "", "", fmt.Errorf("invalid substitution '%s', must be in the form key=value", pair)
+	}
+	return strings.TrimSpace(kv[0]), kv[1], nil
+}
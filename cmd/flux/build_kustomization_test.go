@@ -20,7 +20,11 @@ limitations under the License.
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func setup(t *testing.T, tmpl map[string]string) {
@@ -81,3 +85,35 @@ func TestBuildKustomization(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSubstitutions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vars.env")
+	data := "# cluster vars\ncluster_name=ci\n\ncluster_env = staging\nlabels=app=podinfo\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := parseSubstitutions([]string{file}, []string{"cluster_env=dev", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"cluster_name": "ci",
+		"cluster_env":  "dev",
+		"labels":       "app=podinfo",
+		"empty":        "",
+	}
+	if diff := cmp.Diff(expected, vars); diff != "" {
+		t.Errorf("unexpected substitutions (-want +got):\n%s", diff)
+	}
+
+	if vars, err := parseSubstitutions(nil, nil); err != nil || vars != nil {
+		t.Errorf("expected no substitutions, got %v, %v", vars, err)
+	}
+	if _, err := parseSubstitutions(nil, []string{"cluster_env"}); err == nil {
+		t.Error("expected an error for a substitution without value")
+	}
+	if _, err := parseSubstitutions([]string{filepath.Join(t.TempDir(), "missing.env")}, nil); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -18,12 +18,19 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -39,7 +46,11 @@ var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Check requirements and installation",
 	Long: `The check command will perform a series of checks to validate that
-the local environment is configured correctly and if the installed components are healthy.`,
+the local environment is configured correctly and if the installed components are healthy.
+The pre-installation checks warn when the Kubernetes version has reached end of life and
+list the Flux custom resources stored in a deprecated API version. On OpenShift, they check
+that the controllers are allowed to use the nonroot security context constraints.
+The health of the components is polled every 5s unless --poll-interval is set.`,
 	Example: `  # Run pre-installation checks
   flux check --pre
 
@@ -48,15 +59,27 @@ the local environment is configured correctly and if the installed components ar
 	RunE: runCheckCmd,
 }
 
+// checkPollInterval is the default interval at which the health of the
+// components is polled.
+const checkPollInterval = 5 * time.Second
+
 type checkFlags struct {
 	pre             bool
 	components      []string
 	extraComponents []string
-	pollInterval    time.Duration
 }
 
 var kubernetesConstraints = []string{
-	">=1.20.6-0",
+	">=1.21.0-0",
+}
+
+// kubernetesEndOfLife holds the end of life dates of the Kubernetes minor
+// releases supported by this version of the CLI.
+var kubernetesEndOfLife = map[string]string{
+	"1.20": "2022-02-28",
+	"1.21": "2022-06-28",
+	"1.22": "2022-10-28",
+	"1.23": "2023-02-28",
 }
 
 var checkArgs checkFlags
@@ -68,8 +91,6 @@ func init() {
 		"list of components, accepts comma-separated values")
 	checkCmd.Flags().StringSliceVar(&checkArgs.extraComponents, "components-extra", nil,
 		"list of components in addition to those supplied or defaulted, accepts comma-separated values")
-	checkCmd.Flags().DurationVar(&checkArgs.pollInterval, "poll-interval", 5*time.Second,
-		"how often the health checker should poll the cluster for the latest state of the resources.")
 	rootCmd.AddCommand(checkCmd)
 }
 
@@ -84,6 +105,12 @@ func runCheckCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	if checkArgs.pre {
+		if !openshiftCheck() {
+			checkFailed = true
+		}
+		if !deprecatedAPIsCheck() {
+			checkFailed = true
+		}
 		if checkFailed {
 			os.Exit(1)
 		}
@@ -91,8 +118,13 @@ func runCheckCmd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	pollInterval := checkPollInterval
+	if cmd.Flags().Changed("poll-interval") {
+		pollInterval = rootArgs.pollInterval
+	}
+
 	logger.Actionf("checking controllers")
-	if !componentsCheck() {
+	if !componentsCheck(pollInterval) {
 		checkFailed = true
 	}
 	if checkFailed {
@@ -166,10 +198,309 @@ func kubernetesCheck(constraints []string) bool {
 	}
 
 	logger.Successf("Kubernetes %s %s", v.String(), vrange)
+
+	if eol, ok := kubernetesEndOfLifeDate(v, time.Now()); ok {
+		logger.Warningf("Kubernetes %d.%d reached end of life on %s, please upgrade", v.Major(), v.Minor(), eol)
+	}
 	return true
 }
 
-func componentsCheck() bool {
+// kubernetesEndOfLifeDate returns the end of life date of the given
+// Kubernetes version if the date is before now.
+func kubernetesEndOfLifeDate(v *semver.Version, now time.Time) (string, bool) {
+	eol, ok := kubernetesEndOfLife[fmt.Sprintf("%d.%d", v.Major(), v.Minor())]
+	if !ok {
+		return "", false
+	}
+	t, err := time.Parse("2006-01-02", eol)
+	if err != nil || now.Before(t) {
+		return "", false
+	}
+	return eol, true
+}
+
+// deprecatedAPIsCheck looks for Flux custom resources persisted in a
+// version other than the storage version of their CRD. Those objects
+// must be migrated before upgrading the controllers.
+func deprecatedAPIsCheck() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		logger.Failuref("Kubernetes client initialization failed: %s", err.Error())
+		return false
+	}
+
+	apis, err := findDeprecatedAPIs(ctx, kubeClient)
+	if err != nil {
+		logger.Failuref("Kubernetes API call failed: %s", err.Error())
+		return false
+	}
+
+	for _, api := range apis {
+		logger.Failuref("%s %s/%s is deprecated, %d objects must be migrated to %s/%s, run 'flux migrate' to upgrade them",
+			api.kind, api.group, api.version, len(api.objects), api.group, api.storage)
+		for _, line := range limitObjectList(api.objects, deprecatedObjectsLimit) {
+			logger.Failuref("  %s", line)
+		}
+	}
+	return len(apis) == 0
+}
+
+// deprecatedObjectsLimit is the maximum number of objects listed for each
+// deprecated API version.
+const deprecatedObjectsLimit = 10
+
+// deprecatedAPI is a version of a Flux CRD that is still recorded as stored,
+// along with the namespace/name of the objects that must be migrated.
+type deprecatedAPI struct {
+	kind    string
+	group   string
+	version string
+	storage string
+	objects []string
+}
+
+// findDeprecatedAPIs returns the deprecated versions of the Flux CRDs that
+// are still recorded as stored in the cluster.
+func findDeprecatedAPIs(ctx context.Context, kubeClient client.Client) ([]deprecatedAPI, error) {
+	selector := client.MatchingLabels{manifestgen.PartOfLabelKey: manifestgen.PartOfLabelValue}
+	var list apiextensionsv1.CustomResourceDefinitionList
+	if err := kubeClient.List(ctx, &list, selector); err != nil {
+		return nil, err
+	}
+
+	var apis []deprecatedAPI
+	for _, crd := range list.Items {
+		versions := deprecatedStoredVersions(crd)
+		if len(versions) == 0 {
+			continue
+		}
+
+		objects, err := listCustomResources(ctx, kubeClient, crd)
+		if err != nil {
+			return nil, fmt.Errorf("%s listing failed: %w", crd.Spec.Names.Kind, err)
+		}
+		refs := make([]string, 0, len(objects))
+		for _, obj := range objects {
+			refs = append(refs, fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName()))
+		}
+		sort.Strings(refs)
+
+		for _, v := range versions {
+			apis = append(apis, deprecatedAPI{
+				kind:    crd.Spec.Names.Kind,
+				group:   crd.Spec.Group,
+				version: v,
+				storage: storageVersion(crd),
+				objects: refs,
+			})
+		}
+	}
+	return apis, nil
+}
+
+// limitObjectList returns the first limit items, followed by a line with
+// the number of items left out.
+func limitObjectList(items []string, limit int) []string {
+	if len(items) <= limit {
+		return items
+	}
+	lines := append([]string{}, items[:limit]...)
+	return append(lines, fmt.Sprintf("...and %d more", len(items)-limit))
+}
+
+// openshiftSCC is the security context constraint that allows the
+// controllers to run with the user and fsGroup set in the Flux manifests.
+const openshiftSCC = "nonroot"
+
+// openshiftInstallPatch is the kustomize patch that makes the controllers
+// run under the nonroot SCC, which rejects the seccomp profile and the user
+// ID set in the Flux manifests.
+const openshiftInstallPatch = `patches:
+  - patch: |
+      apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: all
+      spec:
+        template:
+          spec:
+            containers:
+              - name: manager
+                securityContext:
+                  runAsUser: 65534
+                  seccompProfile:
+                    $patch: delete
+    target:
+      kind: Deployment
+      labelSelector: app.kubernetes.io/part-of=flux`
+
+// openshiftCheck validates that the security context constraints and the
+// network settings of an OpenShift cluster allow the Flux controllers to run.
+// On other distributions the check is a no-op.
+func openshiftCheck() bool {
+	cfg, err := utils.KubeConfig(kubeconfigArgs)
+	if err != nil {
+		logger.Failuref("Kubernetes client initialization failed: %s", err.Error())
+		return false
+	}
+
+	clientSet, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		logger.Failuref("Kubernetes client initialization failed: %s", err.Error())
+		return false
+	}
+
+	groups, err := clientSet.Discovery().ServerGroups()
+	if err != nil {
+		logger.Failuref("Kubernetes API call failed: %s", err.Error())
+		return false
+	}
+
+	var hasSCC, hasRoute bool
+	for _, g := range groups.Groups {
+		switch g.Name {
+		case "security.openshift.io":
+			hasSCC = true
+		case "route.openshift.io":
+			hasRoute = true
+		}
+	}
+	if !hasSCC {
+		return true
+	}
+	logger.Actionf("checking OpenShift prerequisites")
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		logger.Failuref("Kubernetes client initialization failed: %s", err.Error())
+		return false
+	}
+
+	namespace := *kubeconfigArgs.Namespace
+	scc := &unstructured.Unstructured{}
+	scc.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "security.openshift.io",
+		Version: "v1",
+		Kind:    "SecurityContextConstraints",
+	})
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: openshiftSCC}, scc); err != nil {
+		logger.Failuref("SecurityContextConstraints/%s not found: %s", openshiftSCC, err.Error())
+		return false
+	}
+	logger.Successf("SecurityContextConstraints/%s found", openshiftSCC)
+
+	// copy the components to not append to the backing array of the flag value
+	components := make([]string, 0, len(checkArgs.components)+len(checkArgs.extraComponents))
+	components = append(components, checkArgs.components...)
+	components = append(components, checkArgs.extraComponents...)
+	denied, err := openshiftSCCDenied(ctx, clientSet, namespace, components)
+	if err != nil {
+		logger.Failuref("SubjectAccessReview failed: %s", err.Error())
+		return false
+	}
+	for _, component := range denied {
+		if component == "source-controller" {
+			logger.Failuref("%s requires the %s SCC to set the fsGroup of its storage volume", component, openshiftSCC)
+		} else {
+			logger.Failuref("%s is not allowed to use the %s SCC", component, openshiftSCC)
+		}
+		logger.Actionf("oc adm policy add-scc-to-user %s -z %s -n %s", openshiftSCC, component, namespace)
+	}
+	ok := len(denied) == 0
+	if !ok {
+		logger.Actionf("add the following patch to the kustomization.yaml of the Flux manifests to run the controllers with the %s SCC:\n%s",
+			openshiftSCC, openshiftInstallPatch)
+	}
+
+	if hasRoute && utils.ContainsItemString(components, "notification-controller") {
+		logger.Warningf("the OpenShift router can't reach the webhook receiver when it runs on the host network")
+		logger.Actionf("patch the allow-webhooks NetworkPolicy with a namespaceSelector for " +
+			"'policy-group.network.openshift.io/host-network' or install with '--network-policy=false'")
+	}
+
+	if ok {
+		logger.Successf("OpenShift prerequisites checks passed")
+	}
+	return ok
+}
+
+// openshiftSCCDenied returns the components whose service account isn't
+// allowed to use the Flux SCC. The access is checked with a SubjectAccessReview
+// as oc adm policy add-scc-to-user grants it with a RoleBinding instead of
+// listing the user in the SCC.
+func openshiftSCCDenied(ctx context.Context, clientSet kubernetes.Interface, namespace string, components []string) ([]string, error) {
+	var denied []string
+	for _, component := range components {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   fmt.Sprintf("system:serviceaccount:%s:%s", namespace, component),
+				Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      "use",
+					Group:     "security.openshift.io",
+					Resource:  "securitycontextconstraints",
+					Name:      openshiftSCC,
+				},
+			},
+		}
+		result, err := clientSet.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if !result.Status.Allowed {
+			denied = append(denied, component)
+		}
+	}
+	return denied, nil
+}
+
+// storageVersion returns the version used to persist the custom resources
+// of the given CRD.
+func storageVersion(crd apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// deprecatedStoredVersions returns the versions listed in the CRD status
+// that differ from the current storage version.
+func deprecatedStoredVersions(crd apiextensionsv1.CustomResourceDefinition) []string {
+	var versions []string
+	storage := storageVersion(crd)
+	for _, v := range crd.Status.StoredVersions {
+		if v != storage {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// listCustomResources returns the objects of the given CRD in all namespaces.
+func listCustomResources(ctx context.Context, kubeClient client.Client,
+	crd apiextensionsv1.CustomResourceDefinition) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   crd.Spec.Group,
+		Version: storageVersion(crd),
+		Kind:    crd.Spec.Names.ListKind,
+	})
+	if err := kubeClient.List(ctx, list, client.InNamespace("")); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func componentsCheck(pollInterval time.Duration) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 
@@ -178,7 +509,7 @@ func componentsCheck() bool {
 		return false
 	}
 
-	statusChecker, err := status.NewStatusChecker(kubeConfig, checkArgs.pollInterval, rootArgs.timeout, logger)
+	statusChecker, err := status.NewStatusChecker(kubeConfig, pollInterval, rootArgs.timeout, logger)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,243 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-cmp/cmp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+	"github.com/fluxcd/flux2/pkg/manifestgen"
+)
+
+func TestKubernetesEndOfLifeDate(t *testing.T) {
+	now := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		version  string
+		expected string
+		eol      bool
+	}{
+		{
+			name:     "reached end of life",
+			version:  "1.21.5",
+			expected: "2022-06-28",
+			eol:      true,
+		},
+		{
+			name:    "supported",
+			version: "1.22.1",
+		},
+		{
+			name:    "unknown release",
+			version: "1.24.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, eol := kubernetesEndOfLifeDate(semver.MustParse(tt.version), now)
+			if eol != tt.eol || got != tt.expected {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.eol, got, eol)
+			}
+		})
+	}
+}
+
+func TestDeprecatedStoredVersions(t *testing.T) {
+	tests := []struct {
+		name           string
+		versions       []apiextensionsv1.CustomResourceDefinitionVersion
+		storedVersions []string
+		expected       []string
+	}{
+		{
+			name: "migrated",
+			versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1"},
+				{Name: "v1beta2", Storage: true},
+			},
+			storedVersions: []string{"v1beta2"},
+		},
+		{
+			name: "not migrated",
+			versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1"},
+				{Name: "v1beta2", Storage: true},
+			},
+			storedVersions: []string{"v1beta1", "v1beta2"},
+			expected:       []string{"v1beta1"},
+		},
+		{
+			name: "removed version",
+			versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", Storage: true},
+			},
+			storedVersions: []string{"v1alpha1", "v1beta1", "v1"},
+			expected:       []string{"v1alpha1", "v1beta1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crd := apiextensionsv1.CustomResourceDefinition{
+				Spec:   apiextensionsv1.CustomResourceDefinitionSpec{Versions: tt.versions},
+				Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: tt.storedVersions},
+			}
+			if diff := cmp.Diff(tt.expected, deprecatedStoredVersions(crd)); diff != "" {
+				t.Errorf("unexpected versions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindDeprecatedAPIs(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		newTestCRD("Widget", "v1beta1", "v1beta2"),
+		newTestCRD("Gadget", "v1beta2"),
+		newTestCustomResource("Widget", "flux-system", "podinfo"),
+		newTestCustomResource("Widget", "apps", "backend"),
+		newTestCustomResource("Gadget", "apps", "frontend"),
+	).Build()
+
+	apis, err := findDeprecatedAPIs(context.TODO(), kubeClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []deprecatedAPI{
+		{
+			kind:    "Widget",
+			group:   "example.toolkit.fluxcd.io",
+			version: "v1beta1",
+			storage: "v1beta2",
+			objects: []string{"apps/backend", "flux-system/podinfo"},
+		},
+	}
+	if diff := cmp.Diff(expected, apis, cmp.AllowUnexported(deprecatedAPI{})); diff != "" {
+		t.Errorf("unexpected deprecated APIs (-want +got):\n%s", diff)
+	}
+}
+
+// newTestCRD returns a Flux CRD of the example.toolkit.fluxcd.io group,
+// served in v1beta1 and v1beta2 and stored in v1beta2.
+func newTestCRD(kind string, storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   strings.ToLower(kind) + "s.example.toolkit.fluxcd.io",
+			Labels: map[string]string{manifestgen.PartOfLabelKey: manifestgen.PartOfLabelValue},
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.toolkit.fluxcd.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: kind, ListKind: kind + "List"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1"},
+				{Name: "v1beta2", Storage: true},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+}
+
+// newTestCustomResource returns an object of a CRD created with newTestCRD.
+func newTestCustomResource(kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("example.toolkit.fluxcd.io/v1beta2")
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestLimitObjectList(t *testing.T) {
+	items := []string{"apps/a", "apps/b", "apps/c", "apps/d"}
+	tests := []struct {
+		name     string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "under the limit",
+			limit:    10,
+			expected: items,
+		},
+		{
+			name:     "at the limit",
+			limit:    4,
+			expected: items,
+		},
+		{
+			name:     "over the limit",
+			limit:    2,
+			expected: []string{"apps/a", "apps/b", "...and 2 more"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, limitObjectList(items, tt.limit)); diff != "" {
+				t.Errorf("unexpected list (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOpenshiftSCCDenied(t *testing.T) {
+	clientSet := kubefake.NewSimpleClientset()
+	var reviews []authorizationv1.SubjectAccessReview
+	clientSet.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		reviews = append(reviews, *review)
+		// only source-controller is granted the SCC
+		review.Status.Allowed = strings.HasSuffix(review.Spec.User, ":source-controller")
+		return true, review, nil
+	})
+
+	denied, err := openshiftSCCDenied(context.TODO(), clientSet, "flux-system",
+		[]string{"source-controller", "kustomize-controller"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"kustomize-controller"}, denied); diff != "" {
+		t.Errorf("unexpected denied components (-want +got):\n%s", diff)
+	}
+
+	if len(reviews) != 2 {
+		t.Fatalf("expected a review per component, got %d", len(reviews))
+	}
+	attrs := reviews[1].Spec.ResourceAttributes
+	if reviews[1].Spec.User != "system:serviceaccount:flux-system:kustomize-controller" ||
+		attrs.Verb != "use" || attrs.Resource != "securitycontextconstraints" || attrs.Name != openshiftSCC ||
+		attrs.Namespace != "flux-system" {
+		t.Errorf("unexpected review %+v %+v", reviews[1].Spec, attrs)
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -33,15 +32,17 @@ import (
 )
 
 var createCmd = &cobra.Command{
-	Use:   "create",
-	Short: "Create or update sources and resources",
-	Long:  "The create sub-commands generate sources and resources.",
+	Use:               "create",
+	Short:             "Create or update sources and resources",
+	Long:              "The create sub-commands generate sources and resources.",
+	PersistentPreRunE: createInteractivePreRun,
 }
 
 type createFlags struct {
-	interval time.Duration
-	export   bool
-	labels   []string
+	interval    time.Duration
+	export      bool
+	labels      []string
+	interactive bool
 }
 
 var createArgs createFlags
@@ -51,6 +52,8 @@ func init() {
 	createCmd.PersistentFlags().BoolVar(&createArgs.export, "export", false, "export in YAML format to stdout")
 	createCmd.PersistentFlags().StringSliceVar(&createArgs.labels, "label", nil,
 		"set labels on the resource (can specify multiple labels with commas: label1=value1,label2=value2)")
+	createCmd.PersistentFlags().BoolVarP(&createArgs.interactive, "interactive", "i", false,
+		"prompt for the name and required flags, print the generated YAML and ask for confirmation before applying")
 	rootCmd.AddCommand(createCmd)
 }
 
@@ -118,7 +121,7 @@ func (names apiType) upsertAndWait(object upsertWaitable, mutate func() error) e
 	}
 
 	logger.Waitingf("waiting for %s reconciliation", names.kind)
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, object.asClientObject(),
 		isReady(ctx, kubeClient, namespacedName, object)); err != nil {
 		return err
 	}
@@ -19,6 +19,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -43,7 +44,16 @@ var createAlertCmd = &cobra.Command{
   --event-severity info \
   --event-source Kustomization/flux-system \
   --provider-ref slack \
-  flux-system`,
+  --summary "production cluster" \
+  flux-system
+
+  # Create an Alert for HelmRelease errors, excluding the messages about missing charts
+  flux create alert \
+  --event-severity error \
+  --event-source HelmRelease/* \
+  --exclusion-list ".*chart not found.*" \
+  --provider-ref slack \
+  helm-releases`,
 	RunE: createAlertCmdRun,
 }
 
@@ -51,6 +61,8 @@ type alertFlags struct {
 	providerRef   string
 	eventSeverity string
 	eventSources  []string
+	exclusionList []string
+	summary       string
 }
 
 var alertArgs alertFlags
@@ -59,6 +71,10 @@ func init() {
 	createAlertCmd.Flags().StringVar(&alertArgs.providerRef, "provider-ref", "", "reference to provider")
 	createAlertCmd.Flags().StringVar(&alertArgs.eventSeverity, "event-severity", "", "severity of events to send alerts for")
 	createAlertCmd.Flags().StringSliceVar(&alertArgs.eventSources, "event-source", []string{}, "sources that should generate alerts (<kind>/<name>), also accepts comma-separated values")
+	createAlertCmd.Flags().StringArrayVar(&alertArgs.exclusionList, "exclusion-list", nil,
+		"regular expression matching the event messages that should not generate alerts, may be repeated")
+	createAlertCmd.Flags().StringVar(&alertArgs.summary, "summary", "",
+		"short description of the impact and affected cluster, included in the notifications")
 	createCmd.AddCommand(createAlertCmd)
 }
 
@@ -90,6 +106,12 @@ func createAlertCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("at least one event source is required")
 	}
 
+	for _, expr := range alertArgs.exclusionList {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid exclusion expression '%s': %w", expr, err)
+		}
+	}
+
 	sourceLabels, err := parseLabels()
 	if err != nil {
 		return err
@@ -111,6 +133,8 @@ func createAlertCmdRun(cmd *cobra.Command, args []string) error {
 			},
 			EventSeverity: alertArgs.eventSeverity,
 			EventSources:  eventSources,
+			ExclusionList: alertArgs.exclusionList,
+			Summary:       alertArgs.summary,
 			Suspend:       false,
 		},
 	}
@@ -119,6 +143,12 @@ func createAlertCmdRun(cmd *cobra.Command, args []string) error {
 		return printExport(exportAlert(&alert))
 	}
 
+	if err := confirmCreate(func() error {
+		return printExport(exportAlert(&alert))
+	}); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 
@@ -134,7 +164,7 @@ func createAlertCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for Alert reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, &alert,
 		isAlertReady(ctx, kubeClient, namespacedName, &alert)); err != nil {
 		return err
 	}
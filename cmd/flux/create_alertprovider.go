@@ -19,8 +19,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/url"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -49,7 +51,14 @@ var createAlertProviderCmd = &cobra.Command{
   flux create alert-provider github-podinfo \
   --type github \
   --address https://github.com/stefanprodan/podinfo \
-  --secret-ref github-token`,
+  --secret-ref github-token
+
+  # Create a Provider for a Prometheus Alertmanager protected by basic auth
+  flux create alert-provider alertmanager \
+  --type alertmanager \
+  --address https://alertmanager.example.com/api/v2/alerts \
+  --basic-auth-username flux \
+  --basic-auth-password ${ALERTMANAGER_PASSWORD}`,
 	RunE: createAlertProviderCmdRun,
 }
 
@@ -59,6 +68,10 @@ type alertProviderFlags struct {
 	username  string
 	address   string
 	secretRef string
+
+	basicAuthUsername string
+	basicAuthPassword string
+	verify            bool
 }
 
 var alertProviderArgs alertProviderFlags
@@ -69,6 +82,12 @@ func init() {
 	createAlertProviderCmd.Flags().StringVar(&alertProviderArgs.username, "username", "", "bot username used by the provider")
 	createAlertProviderCmd.Flags().StringVar(&alertProviderArgs.address, "address", "", "path to either the git repository, chat provider or webhook")
 	createAlertProviderCmd.Flags().StringVar(&alertProviderArgs.secretRef, "secret-ref", "", "name of secret containing authentication token")
+	createAlertProviderCmd.Flags().StringVar(&alertProviderArgs.basicAuthUsername, "basic-auth-username", "",
+		"basic authentication username, stored with the address in a secret, can only be used with the alertmanager and generic types")
+	createAlertProviderCmd.Flags().StringVar(&alertProviderArgs.basicAuthPassword, "basic-auth-password", "",
+		"basic authentication password, stored with the address in a secret, can only be used with the alertmanager and generic types")
+	createAlertProviderCmd.Flags().BoolVar(&alertProviderArgs.verify, "verify", false,
+		"send a test event through the Provider once it is ready, see 'flux alert-provider verify'")
 	createCmd.AddCommand(createAlertProviderCmd)
 }
 
@@ -82,6 +101,40 @@ func createAlertProviderCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Provider type is required")
 	}
 
+	if alertProviderArgs.address != "" {
+		if err := validateProviderAddress(alertProviderArgs.address); err != nil {
+			return err
+		}
+	}
+
+	var addressWithAuth string
+	if alertProviderArgs.basicAuthUsername != "" || alertProviderArgs.basicAuthPassword != "" {
+		switch alertProviderArgs.alertType {
+		case notificationv1.AlertManagerProvider, notificationv1.GenericProvider:
+		default:
+			return fmt.Errorf("basic authentication can only be used with the %s and %s types",
+				notificationv1.AlertManagerProvider, notificationv1.GenericProvider)
+		}
+		if alertProviderArgs.basicAuthUsername == "" || alertProviderArgs.basicAuthPassword == "" {
+			return fmt.Errorf("both --basic-auth-username and --basic-auth-password are required")
+		}
+		if alertProviderArgs.address == "" {
+			return fmt.Errorf("--address is required with basic authentication")
+		}
+		if alertProviderArgs.secretRef != "" {
+			return fmt.Errorf("--basic-auth-username and --basic-auth-password cannot be used with --secret-ref")
+		}
+		if createArgs.export {
+			return fmt.Errorf("basic authentication can't be used when exporting, create the secret and use --secret-ref instead")
+		}
+		var err error
+		addressWithAuth, err = providerAddressWithBasicAuth(alertProviderArgs.address,
+			alertProviderArgs.basicAuthUsername, alertProviderArgs.basicAuthPassword)
+		if err != nil {
+			return err
+		}
+	}
+
 	sourceLabels, err := parseLabels()
 	if err != nil {
 		return err
@@ -115,6 +168,34 @@ func createAlertProviderCmdRun(cmd *cobra.Command, args []string) error {
 		return printExport(exportAlertProvider(&provider))
 	}
 
+	var addressSecret *corev1.Secret
+	if addressWithAuth != "" {
+		addressSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("provider-%s", name),
+				Namespace: *kubeconfigArgs.Namespace,
+				Labels:    sourceLabels,
+			},
+			StringData: map[string]string{
+				"address": addressWithAuth,
+			},
+		}
+		provider.Spec.SecretRef = &meta.LocalObjectReference{
+			Name: addressSecret.Name,
+		}
+	}
+
+	if err := confirmCreate(func() error {
+		if addressSecret != nil {
+			if err := printSecret(*addressSecret); err != nil {
+				return err
+			}
+		}
+		return printExport(exportAlertProvider(&provider))
+	}); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 
@@ -123,6 +204,14 @@ func createAlertProviderCmdRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if addressSecret != nil {
+		logger.Actionf("applying secret with the provider address")
+		if err := upsertSecret(ctx, kubeClient, *addressSecret); err != nil {
+			return err
+		}
+		logger.Successf("authentication configured")
+	}
+
 	logger.Actionf("applying Provider")
 	namespacedName, err := upsertAlertProvider(ctx, kubeClient, &provider)
 	if err != nil {
@@ -130,16 +219,45 @@ func createAlertProviderCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for Provider reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, &provider,
 		isAlertProviderReady(ctx, kubeClient, namespacedName, &provider)); err != nil {
 		return err
 	}
 
 	logger.Successf("Provider %s is ready", name)
 
+	if alertProviderArgs.verify {
+		return verifyAlertProvider(ctx, kubeClient, &provider)
+	}
+
 	return nil
 }
 
+// validateProviderAddress checks that the address is an absolute HTTP/S URL,
+// as required by the Provider API.
+func validateProviderAddress(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s': %w", address, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid address '%s': must be an http or https URL", address)
+	}
+	return nil
+}
+
+// providerAddressWithBasicAuth returns the address with the given credentials
+// set as the URL user info, which is how the notification-controller
+// authenticates against Alertmanager and generic webhooks.
+func providerAddressWithBasicAuth(address, username, password string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address '%s': %w", address, err)
+	}
+	u.User = url.UserPassword(username, password)
+	return u.String(), nil
+}
+
 func upsertAlertProvider(ctx context.Context, kubeClient client.Client,
 	provider *notificationv1.Provider) (types.NamespacedName, error) {
 	namespacedName := types.NamespacedName{
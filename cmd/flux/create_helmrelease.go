@@ -24,6 +24,7 @@ import (
 
 	"github.com/fluxcd/flux2/internal/flags"
 	"github.com/fluxcd/flux2/internal/utils"
+	"github.com/fluxcd/pkg/apis/kustomize"
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/transform"
 
@@ -79,6 +80,14 @@ var createHelmReleaseCmd = &cobra.Command{
     --chart=podinfo \
     --values-from=Secret/my-secret-values
 
+  # Create a HelmRelease with values layered from multiple ConfigMaps and Secrets,
+  # the optional references are merged last and can be missing from the cluster
+  flux create hr podinfo \
+    --source=HelmRepository/podinfo \
+    --chart=podinfo \
+    --values-from=ConfigMap/common-values,Secret/my-secret-values:secrets.yaml \
+    --values-from-optional=ConfigMap/cluster-overrides
+
   # Create a HelmRelease with a custom release name
   flux create hr podinfo \
     --release-name=podinfo-dev
@@ -98,6 +107,22 @@ var createHelmReleaseCmd = &cobra.Command{
     --source=HelmRepository/podinfo.flux-system \
     --chart=podinfo
 
+  # Create a HelmRelease with post-renderer patches read from local files
+  flux create hr podinfo \
+    --source=HelmRepository/podinfo \
+    --chart=podinfo \
+    --post-renderer-patch-file=./patches/labels.yaml
+
+  # Create a HelmRelease that retries failed installs and upgrades,
+  # and rolls back the release when the upgrade retries are exhausted
+  flux create hr podinfo \
+    --source=HelmRepository/podinfo \
+    --chart=podinfo \
+    --install-retries=3 \
+    --upgrade-retries=3 \
+    --upgrade-remediation=rollback \
+    --cleanup-on-fail
+
   # Create a HelmRelease definition on disk without applying it on the cluster
   flux create hr podinfo \
     --source=HelmRepository/podinfo \
@@ -116,7 +141,13 @@ type helmReleaseFlags struct {
 	targetNamespace string
 	createNamespace bool
 	valuesFiles     []string
-	valuesFrom      flags.HelmReleaseValuesFrom
+	valuesFrom      []string
+	valuesFromOpt   []string
+	patchFiles      []string
+	installRetries  int
+	upgradeRetries  int
+	upgradeStrategy flags.RemediationStrategy
+	cleanupOnFail   bool
 	saName          string
 	crds            flags.CRDsPolicy
 }
@@ -133,7 +164,17 @@ func init() {
 	createHelmReleaseCmd.Flags().BoolVar(&helmReleaseArgs.createNamespace, "create-target-namespace", false, "create the target namespace if it does not exist")
 	createHelmReleaseCmd.Flags().StringVar(&helmReleaseArgs.saName, "service-account", "", "the name of the service account to impersonate when reconciling this HelmRelease")
 	createHelmReleaseCmd.Flags().StringSliceVar(&helmReleaseArgs.valuesFiles, "values", nil, "local path to values.yaml files, also accepts comma-separated values")
-	createHelmReleaseCmd.Flags().Var(&helmReleaseArgs.valuesFrom, "values-from", helmReleaseArgs.valuesFrom.Description())
+	createHelmReleaseCmd.Flags().StringSliceVar(&helmReleaseArgs.valuesFrom, "values-from", nil,
+		new(flags.HelmReleaseValuesFrom).Description()+", also accepts comma-separated values")
+	createHelmReleaseCmd.Flags().StringSliceVar(&helmReleaseArgs.valuesFromOpt, "values-from-optional", nil,
+		"same as --values-from but the references are marked as optional and merged after the required ones")
+	createHelmReleaseCmd.Flags().StringSliceVar(&helmReleaseArgs.patchFiles, "post-renderer-patch-file", nil,
+		"path to a file containing strategic merge patches or a list of JSON6902 patches with targets, "+
+			"applied to the rendered chart with kustomize, also accepts comma-separated values")
+	createHelmReleaseCmd.Flags().IntVar(&helmReleaseArgs.installRetries, "install-retries", 0, "number of retries that should be attempted on install failures, a negative value means infinite retries")
+	createHelmReleaseCmd.Flags().IntVar(&helmReleaseArgs.upgradeRetries, "upgrade-retries", 0, "number of retries that should be attempted on upgrade failures, a negative value means infinite retries")
+	createHelmReleaseCmd.Flags().Var(&helmReleaseArgs.upgradeStrategy, "upgrade-remediation", helmReleaseArgs.upgradeStrategy.Description())
+	createHelmReleaseCmd.Flags().BoolVar(&helmReleaseArgs.cleanupOnFail, "cleanup-on-fail", false, "delete the new resources created during a failed upgrade")
 	createHelmReleaseCmd.Flags().Var(&helmReleaseArgs.crds, "crds", helmReleaseArgs.crds.Description())
 	createCmd.AddCommand(createHelmReleaseCmd)
 }
@@ -207,6 +248,33 @@ func createHelmReleaseCmdRun(cmd *cobra.Command, args []string) error {
 		helmRelease.Spec.Upgrade = &helmv2.Upgrade{CRDs: helmv2.CRDsPolicy(helmReleaseArgs.crds.String())}
 	}
 
+	if helmReleaseArgs.installRetries != 0 {
+		if helmRelease.Spec.Install == nil {
+			helmRelease.Spec.Install = &helmv2.Install{}
+		}
+
+		helmRelease.Spec.Install.Remediation = &helmv2.InstallRemediation{
+			Retries: helmReleaseArgs.installRetries,
+		}
+	}
+
+	if helmReleaseArgs.upgradeRetries != 0 || helmReleaseArgs.upgradeStrategy != "" || helmReleaseArgs.cleanupOnFail {
+		if helmRelease.Spec.Upgrade == nil {
+			helmRelease.Spec.Upgrade = &helmv2.Upgrade{}
+		}
+
+		helmRelease.Spec.Upgrade.CleanupOnFail = helmReleaseArgs.cleanupOnFail
+		if helmReleaseArgs.upgradeRetries != 0 || helmReleaseArgs.upgradeStrategy != "" {
+			helmRelease.Spec.Upgrade.Remediation = &helmv2.UpgradeRemediation{
+				Retries: helmReleaseArgs.upgradeRetries,
+			}
+			if helmReleaseArgs.upgradeStrategy != "" {
+				strategy := helmv2.RemediationStrategy(helmReleaseArgs.upgradeStrategy.String())
+				helmRelease.Spec.Upgrade.Remediation.Strategy = &strategy
+			}
+		}
+	}
+
 	if len(helmReleaseArgs.valuesFiles) > 0 {
 		valuesMap := make(map[string]interface{})
 		for _, v := range helmReleaseArgs.valuesFiles {
@@ -236,17 +304,34 @@ func createHelmReleaseCmdRun(cmd *cobra.Command, args []string) error {
 		helmRelease.Spec.Values = &apiextensionsv1.JSON{Raw: jsonRaw}
 	}
 
-	if helmReleaseArgs.valuesFrom.String() != "" {
-		helmRelease.Spec.ValuesFrom = []helmv2.ValuesReference{{
-			Kind: helmReleaseArgs.valuesFrom.Kind,
-			Name: helmReleaseArgs.valuesFrom.Name,
-		}}
+	valuesFrom, err := makeValuesReferences(helmReleaseArgs.valuesFrom, false)
+	if err != nil {
+		return err
+	}
+	optionalValuesFrom, err := makeValuesReferences(helmReleaseArgs.valuesFromOpt, true)
+	if err != nil {
+		return err
+	}
+	helmRelease.Spec.ValuesFrom = append(valuesFrom, optionalValuesFrom...)
+
+	if len(helmReleaseArgs.patchFiles) > 0 {
+		postRenderer, err := makeKustomizePostRenderer(helmReleaseArgs.patchFiles)
+		if err != nil {
+			return err
+		}
+		helmRelease.Spec.PostRenderers = []helmv2.PostRenderer{postRenderer}
 	}
 
 	if createArgs.export {
 		return printExport(exportHelmRelease(&helmRelease))
 	}
 
+	if err := confirmCreate(func() error {
+		return printExport(exportHelmRelease(&helmRelease))
+	}); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 
@@ -262,7 +347,7 @@ func createHelmReleaseCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for HelmRelease reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, &helmRelease,
 		isHelmReleaseReady(ctx, kubeClient, namespacedName, &helmRelease)); err != nil {
 		return err
 	}
@@ -272,6 +357,58 @@ func createHelmReleaseCmdRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// makeValuesReferences parses the given '<kind>/<name>[:<values-key>]'
+// references, in the order they are merged by helm-controller.
+func makeValuesReferences(refs []string, optional bool) ([]helmv2.ValuesReference, error) {
+	var valuesRefs []helmv2.ValuesReference
+	for _, r := range refs {
+		var ref flags.HelmReleaseValuesFrom
+		if err := ref.Set(r); err != nil {
+			return nil, err
+		}
+		valuesRefs = append(valuesRefs, helmv2.ValuesReference{
+			Kind:      ref.Kind,
+			Name:      ref.Name,
+			ValuesKey: ref.ValuesKey,
+			Optional:  optional,
+		})
+	}
+	return valuesRefs, nil
+}
+
+// makeKustomizePostRenderer returns a kustomize post-renderer with the patches
+// read from the given files, the patches with a target must be JSON6902 patches.
+func makeKustomizePostRenderer(files []string) (helmv2.PostRenderer, error) {
+	kustomization := &helmv2.Kustomize{}
+	for _, file := range files {
+		patches, err := readKustomizationPatches(file)
+		if err != nil {
+			return helmv2.PostRenderer{}, err
+		}
+		for _, p := range patches {
+			if p.Target == (kustomize.Selector{}) {
+				data, err := yaml.YAMLToJSON([]byte(p.Patch))
+				if err != nil {
+					return helmv2.PostRenderer{}, fmt.Errorf("invalid strategic merge patch in '%s': %w", file, err)
+				}
+				kustomization.PatchesStrategicMerge = append(kustomization.PatchesStrategicMerge, apiextensionsv1.JSON{Raw: data})
+				continue
+			}
+
+			var ops []kustomize.JSON6902
+			if err := yaml.Unmarshal([]byte(p.Patch), &ops); err != nil {
+				return helmv2.PostRenderer{}, fmt.Errorf("invalid JSON6902 patch in '%s', "+
+					"only JSON6902 patches can have a target: %w", file, err)
+			}
+			kustomization.PatchesJSON6902 = append(kustomization.PatchesJSON6902, kustomize.JSON6902Patch{
+				Patch:  ops,
+				Target: p.Target,
+			})
+		}
+	}
+	return helmv2.PostRenderer{Kustomize: kustomization}, nil
+}
+
 func upsertHelmRelease(ctx context.Context, kubeClient client.Client,
 	helmRelease *helmv2.HelmRelease) (types.NamespacedName, error) {
 	namespacedName := types.NamespacedName{
@@ -0,0 +1,109 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMakeValuesReferences(t *testing.T) {
+	cases := []struct {
+		name      string
+		refs      []string
+		optional  bool
+		expected  []helmv2.ValuesReference
+		expectErr bool
+	}{
+		{
+			name: "required references",
+			refs: []string{"ConfigMap/common-values", "secret/my-secret-values:secrets.yaml"},
+			expected: []helmv2.ValuesReference{
+				{Kind: "ConfigMap", Name: "common-values"},
+				{Kind: "Secret", Name: "my-secret-values", ValuesKey: "secrets.yaml"},
+			},
+		},
+		{
+			name:     "optional references",
+			refs:     []string{"ConfigMap/cluster-overrides"},
+			optional: true,
+			expected: []helmv2.ValuesReference{
+				{Kind: "ConfigMap", Name: "cluster-overrides", Optional: true},
+			},
+		},
+		{
+			name:      "invalid reference",
+			refs:      []string{"Deployment/values"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			refs, err := makeValuesReferences(tc.refs, tc.optional)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if diff := cmp.Diff(tc.expected, refs); diff != "" {
+				t.Errorf("values references mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMakeKustomizePostRenderer(t *testing.T) {
+	cases := []struct {
+		name       string
+		files      []string
+		expectSMP  int
+		expectJSON int
+		expectErr  bool
+	}{
+		{
+			name:       "strategic merge and JSON6902 patches",
+			files:      []string{"testdata/kustomization/patches/replicas.yaml", "testdata/kustomization/patches/targets.yaml"},
+			expectSMP:  2,
+			expectJSON: 1,
+		},
+		{
+			name:      "strategic merge patch with target",
+			files:     []string{"testdata/helmrelease/patches/targeted-smp.yaml"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			postRenderer, err := makeKustomizePostRenderer(tc.files)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if tc.expectErr {
+				return
+			}
+			if n := len(postRenderer.Kustomize.PatchesStrategicMerge); n != tc.expectSMP {
+				t.Errorf("expected %d strategic merge patches, got %d", tc.expectSMP, n)
+			}
+			if n := len(postRenderer.Kustomize.PatchesJSON6902); n != tc.expectJSON {
+				t.Errorf("expected %d JSON6902 patches, got %d", tc.expectJSON, n)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"regexp/syntax"
 	"strings"
 	"unicode"
@@ -50,7 +51,16 @@ the status of the object.`,
     --image-ref=podinfo \
     --select-numeric=asc \
 	--filter-regex='^main-[a-f0-9]+-(?P<ts>[0-9]+)' \
-	--filter-extract='$ts'`,
+	--filter-extract='$ts'
+
+  # Check the filter against sample tags before creating the ImagePolicy
+  flux create image policy podinfo \
+    --image-ref=podinfo \
+    --select-numeric=asc \
+	--filter-regex='^main-[a-f0-9]+-(?P<ts>[0-9]+)' \
+	--filter-extract='$ts' \
+	--filter-test-tags=main-3e2a1f0-1650000000,dev-3e2a1f0-1650000001 \
+	--export`,
 	RunE: createImagePolicyRun}
 
 type imagePolicyFlags struct {
@@ -61,6 +71,7 @@ type imagePolicyFlags struct {
 	filterRegex     string
 	filterExtract   string
 	filterNumerical string
+	filterTestTags  []string
 }
 
 var imagePolicyArgs = imagePolicyFlags{}
@@ -74,6 +85,9 @@ func init() {
 	flags.StringVar(&imagePolicyArgs.filterRegex, "filter-regex", "", "regular expression pattern used to filter the image tags")
 	flags.StringVar(&imagePolicyArgs.filterExtract, "filter-extract", "", "replacement pattern (using capture groups from --filter-regex) to use for sorting")
 
+	flags.StringSliceVar(&imagePolicyArgs.filterTestTags, "filter-test-tags", nil,
+		"sample tags to run --filter-regex and --filter-extract against before creating the policy, fails if none of them match")
+
 	createImageCmd.AddCommand(createImagePolicyCmd)
 }
 
@@ -112,9 +126,9 @@ func createImagePolicyRun(cmd *cobra.Command, args []string) error {
 	}
 
 	switch {
-	case imagePolicyArgs.semver != "" && imagePolicyArgs.alpha != "":
-	case imagePolicyArgs.semver != "" && imagePolicyArgs.numeric != "":
-	case imagePolicyArgs.alpha != "" && imagePolicyArgs.numeric != "":
+	case imagePolicyArgs.semver != "" && imagePolicyArgs.alpha != "",
+		imagePolicyArgs.semver != "" && imagePolicyArgs.numeric != "",
+		imagePolicyArgs.alpha != "" && imagePolicyArgs.numeric != "":
 		return fmt.Errorf("only one of --select-semver, --select-alpha or --select-numeric can be specified")
 	case imagePolicyArgs.semver != "":
 		policy.Spec.Policy.SemVer = &imagev1.SemVerPolicy{
@@ -157,10 +171,38 @@ func createImagePolicyRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot specify --filter-extract without specifying --filter-regex")
 	}
 
+	if len(imagePolicyArgs.filterTestTags) > 0 {
+		if policy.Spec.FilterTags == nil {
+			return fmt.Errorf("cannot specify --filter-test-tags without specifying --filter-regex")
+		}
+		results, err := filterImageTags(policy.Spec.FilterTags.Pattern, policy.Spec.FilterTags.Extract, imagePolicyArgs.filterTestTags)
+		if err != nil {
+			return err
+		}
+		matched := 0
+		for _, tag := range imagePolicyArgs.filterTestTags {
+			if value, ok := results[tag]; ok {
+				logger.Successf("tag %s matches, sorted by '%s'", tag, value)
+				matched++
+			} else {
+				logger.Failuref("tag %s is filtered out", tag)
+			}
+		}
+		if matched == 0 {
+			return fmt.Errorf("none of the tags given with --filter-test-tags match --filter-regex")
+		}
+	}
+
 	if createArgs.export {
 		return printExport(exportImagePolicy(&policy))
 	}
 
+	if err := confirmCreate(func() error {
+		return printExport(exportImagePolicy(&policy))
+	}); err != nil {
+		return err
+	}
+
 	var existing imagev1.ImagePolicy
 	copyName(&existing, &policy)
 	err = imagePolicyType.upsertAndWait(imagePolicyAdapter{&existing}, func() error {
@@ -171,6 +213,29 @@ func createImagePolicyRun(cmd *cobra.Command, args []string) error {
 	return err
 }
 
+// filterImageTags applies the tag filter the way image-reflector-controller
+// does, returning the value used for sorting of each matching tag.
+func filterImageTags(pattern, extract string, tags []string) (map[string]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("--filter-regex is an invalid regex pattern")
+	}
+
+	results := make(map[string]string)
+	for _, tag := range tags {
+		submatches := re.FindStringSubmatchIndex(tag)
+		if submatches == nil {
+			continue
+		}
+		if extract == "" {
+			results[tag] = tag
+			continue
+		}
+		results[tag] = string(re.ExpandString(nil, extract, tag, submatches))
+	}
+	return results, nil
+}
+
 // Performs a dry-run of the extract function in Regexp to validate the template
 func validateExtractStr(template string, capNames []string) error {
 	for len(template) > 0 {
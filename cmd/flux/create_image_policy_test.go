@@ -0,0 +1,64 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterImageTags(t *testing.T) {
+	tags := []string{"main-3e2a1f0-1650000000", "main-a1b2c3d-1650000100", "dev-3e2a1f0-1650000001", "latest"}
+	cases := []struct {
+		name    string
+		pattern string
+		extract string
+		expect  map[string]string
+	}{
+		{
+			name:    "extract timestamp",
+			pattern: `^main-[a-f0-9]+-(?P<ts>[0-9]+)`,
+			extract: "$ts",
+			expect: map[string]string{
+				"main-3e2a1f0-1650000000": "1650000000",
+				"main-a1b2c3d-1650000100": "1650000100",
+			},
+		},
+		{
+			name:    "no extract",
+			pattern: `^dev-`,
+			expect: map[string]string{
+				"dev-3e2a1f0-1650000001": "dev-3e2a1f0-1650000001",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := filterImageTags(tc.pattern, tc.extract, tags)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Errorf("unexpected results (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,16 +17,23 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fluxcd/pkg/apis/meta"
 
 	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
 var createImageRepositoryCmd = &cobra.Command{
@@ -57,7 +64,12 @@ An ImageRepository object specifies an image repository to scan.`,
     --cert-file client.crt --key-file client.key
   flux create image repository app-repo \
     --cert-secret-ref client-cert \
-    --image registry.example.com/private/app --interval 5m`,
+    --image registry.example.com/private/app --interval 5m
+
+  # Create an image repository for an ECR image, the credentials being
+  # obtained by image-reflector-controller with --aws-autologin-for-ecr
+  flux create image repository app-repo \
+    --image 123456789000.dkr.ecr.eu-west-1.amazonaws.com/app --interval 5m`,
 	RunE: createImageRepositoryRun,
 }
 
@@ -66,6 +78,7 @@ type imageRepoFlags struct {
 	secretRef     string
 	certSecretRef string
 	timeout       time.Duration
+	fluxNamespace string
 }
 
 var imageRepoArgs = imageRepoFlags{}
@@ -78,6 +91,8 @@ func init() {
 	// NB there is already a --timeout in the global flags, for
 	// controlling timeout on operations while e.g., creating objects.
 	flags.DurationVar(&imageRepoArgs.timeout, "scan-timeout", 0, "a timeout for scanning; this defaults to the interval if not set")
+	flags.StringVar(&imageRepoArgs.fluxNamespace, "flux-namespace", rootArgs.defaults.Namespace,
+		"the namespace where the Flux components are running")
 
 	createImageCmd.AddCommand(createImageRepositoryCmd)
 }
@@ -92,10 +107,18 @@ func createImageRepositoryRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("an image repository (--image) is required")
 	}
 
-	if _, err := name.NewRepository(imageRepoArgs.image); err != nil {
+	ref, err := name.NewRepository(imageRepoArgs.image)
+	if err != nil {
 		return fmt.Errorf("unable to parse image value: %w", err)
 	}
 
+	// the credentials of the cloud registries are obtained by
+	// image-reflector-controller unless a secret is given
+	provider := "generic"
+	if imageRepoArgs.secretRef == "" {
+		provider = imageRepositoryProvider(ref.RegistryStr())
+	}
+
 	labels, err := parseLabels()
 	if err != nil {
 		return err
@@ -130,6 +153,18 @@ func createImageRepositoryRun(cmd *cobra.Command, args []string) error {
 		return printExport(exportImageRepository(&repo))
 	}
 
+	if provider != "generic" {
+		if err := checkImageRepositoryAutoLogin(provider); err != nil {
+			return err
+		}
+	}
+
+	if err := confirmCreate(func() error {
+		return printExport(exportImageRepository(&repo))
+	}); err != nil {
+		return err
+	}
+
 	// a temp value for use with the rest
 	var existing imagev1.ImageRepository
 	copyName(&existing, &repo)
@@ -140,3 +175,61 @@ func createImageRepositoryRun(cmd *cobra.Command, args []string) error {
 	})
 	return err
 }
+
+// imageRepositoryAutoLoginFlags maps the cloud providers to the
+// image-reflector-controller flag enabling the registry auto login.
+var imageRepositoryAutoLoginFlags = map[string]string{
+	"aws":   "--aws-autologin-for-ecr",
+	"azure": "--azure-autologin-for-acr",
+	"gcp":   "--gcp-autologin-for-gcr",
+}
+
+var (
+	ecrHostPattern = regexp.MustCompile(`^[0-9]{12}\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+	acrHostPattern = regexp.MustCompile(`\.azurecr\.(io|cn|de|us)$`)
+	gcrHostPattern = regexp.MustCompile(`(^|\.)gcr\.io$|-docker\.pkg\.dev$`)
+)
+
+// imageRepositoryProvider returns the cloud provider of the registry host.
+func imageRepositoryProvider(host string) string {
+	switch {
+	case ecrHostPattern.MatchString(host):
+		return "aws"
+	case acrHostPattern.MatchString(host):
+		return "azure"
+	case gcrHostPattern.MatchString(host):
+		return "gcp"
+	}
+	return "generic"
+}
+
+// checkImageRepositoryAutoLogin warns when image-reflector-controller is not
+// configured to obtain the registry credentials from the cloud provider,
+// as the ImageRepository API has no field to enable it per object.
+func checkImageRepositoryAutoLogin(provider string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	autoLoginFlag := imageRepositoryAutoLoginFlags[provider]
+	var deployment appsv1.Deployment
+	key := client.ObjectKey{Namespace: imageRepoArgs.fluxNamespace, Name: "image-reflector-controller"}
+	if err := kubeClient.Get(ctx, key, &deployment); err != nil {
+		logger.Warningf("unable to check image-reflector-controller for %s: %s", autoLoginFlag, err.Error())
+		return nil
+	}
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		for _, arg := range c.Args {
+			if arg == autoLoginFlag || strings.HasPrefix(arg, autoLoginFlag+"=true") {
+				return nil
+			}
+		}
+	}
+	logger.Warningf("image-reflector-controller is not running with %s, the %s registry credentials will not be obtained",
+		autoLoginFlag, provider)
+	return nil
+}
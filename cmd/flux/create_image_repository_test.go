@@ -0,0 +1,47 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestImageRepositoryProvider(t *testing.T) {
+	cases := []struct {
+		host   string
+		expect string
+	}{
+		{"123456789000.dkr.ecr.eu-west-1.amazonaws.com", "aws"},
+		{"123456789000.dkr.ecr.cn-north-1.amazonaws.com.cn", "aws"},
+		{"example.azurecr.io", "azure"},
+		{"gcr.io", "gcp"},
+		{"eu.gcr.io", "gcp"},
+		{"europe-west1-docker.pkg.dev", "gcp"},
+		{"ghcr.io", "generic"},
+		{"index.docker.io", "generic"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.host, func(t *testing.T) {
+			if got := imageRepositoryProvider(tc.host); got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
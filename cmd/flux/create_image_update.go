@@ -17,13 +17,26 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/kustomize/kyaml/resid"
 
 	autov1 "github.com/fluxcd/image-automation-controller/api/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
 var createImageUpdateCmd = &cobra.Command{
@@ -62,19 +75,51 @@ mentioned in YAMLs in a git repository.`,
     --author-name=flux \
     --author-email=flux@example.com \
     --commit-template="{{range .Updated.Images}}{{println .}}{{end}}"
+
+  # Configure image updates with a multi-line commit message template read from a file
+  flux create image update flux-system \
+    --git-repo-ref=flux-system \
+    --checkout-branch=main \
+    --author-name=flux \
+    --author-email=flux@example.com \
+    --commit-template-file=./commit-message.tmpl
+
+  # Push the image updates to a dedicated branch and write a GitHub Actions
+  # workflow opening a pull request to the checkout branch
+  flux create image update flux-system \
+    --git-repo-ref=flux-system \
+    --checkout-branch=main \
+    --push-branch=image-updates \
+    --author-name=flux \
+    --author-email=flux@example.com \
+    --pull-request-workflow=github
+
+  # Sign the image update commits with an OpenPGP key read from a local keyring file
+  flux create image update flux-system \
+    --git-repo-ref=flux-system \
+    --checkout-branch=main \
+    --author-name=flux \
+    --author-email=flux@example.com \
+    --gpg-key-file=./private.asc
 `,
 	RunE: createImageUpdateRun,
 }
 
 type imageUpdateFlags struct {
-	gitRepoName      string
-	gitRepoNamespace string
-	gitRepoPath      string
-	checkoutBranch   string
-	pushBranch       string
-	commitTemplate   string
-	authorName       string
-	authorEmail      string
+	gitRepoName        string
+	gitRepoNamespace   string
+	gitRepoPath        string
+	checkoutBranch     string
+	pushBranch         string
+	commitTemplate     string
+	commitTemplateFile string
+	authorName         string
+	authorEmail        string
+	gpgKeySecret       string
+	gpgKeyFile         string
+
+	pullRequestWorkflow     string
+	pullRequestWorkflowFile string
 }
 
 var imageUpdateArgs = imageUpdateFlags{}
@@ -87,8 +132,17 @@ func init() {
 	flags.StringVar(&imageUpdateArgs.checkoutBranch, "checkout-branch", "", "the branch to checkout")
 	flags.StringVar(&imageUpdateArgs.pushBranch, "push-branch", "", "the branch to push commits to, defaults to the checkout branch if not specified")
 	flags.StringVar(&imageUpdateArgs.commitTemplate, "commit-template", "", "a template for commit messages")
+	flags.StringVar(&imageUpdateArgs.commitTemplateFile, "commit-template-file", "", "path to a file containing the template for commit messages")
 	flags.StringVar(&imageUpdateArgs.authorName, "author-name", "", "the name to use for commit author")
 	flags.StringVar(&imageUpdateArgs.authorEmail, "author-email", "", "the email to use for commit author")
+	flags.StringVar(&imageUpdateArgs.gpgKeySecret, "gpg-key-secret", "",
+		"the name of the Kubernetes secret containing the armored OpenPGP private key used to sign commits under the 'git.asc' key")
+	flags.StringVar(&imageUpdateArgs.gpgKeyFile, "gpg-key-file", "",
+		"path to an armored OpenPGP private key file, the key is stored in the signing key secret which defaults to '<name>-signing-key'")
+	flags.StringVar(&imageUpdateArgs.pullRequestWorkflow, "pull-request-workflow", "",
+		"write a CI workflow opening a pull request from the push branch to the checkout branch, can be 'github' or 'gitlab'")
+	flags.StringVar(&imageUpdateArgs.pullRequestWorkflowFile, "pull-request-workflow-file", "",
+		"path of the pull request workflow file, defaults to .github/workflows/flux-image-updates.yaml for github and flux-image-updates.gitlab-ci.yml for gitlab")
 
 	createImageCmd.AddCommand(createImageUpdateCmd)
 }
@@ -115,11 +169,60 @@ func createImageUpdateRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("the author email is required (--author-email)")
 	}
 
+	if imageUpdateArgs.commitTemplate != "" && imageUpdateArgs.commitTemplateFile != "" {
+		return fmt.Errorf("only one of --commit-template or --commit-template-file can be specified")
+	}
+
+	if imageUpdateArgs.gpgKeyFile != "" && createArgs.export {
+		return fmt.Errorf("--gpg-key-file can't be used when exporting, create the secret and use --gpg-key-secret instead")
+	}
+
+	var workflow []byte
+	if imageUpdateArgs.pullRequestWorkflow != "" {
+		if imageUpdateArgs.pushBranch == "" || imageUpdateArgs.pushBranch == imageUpdateArgs.checkoutBranch {
+			return fmt.Errorf("--pull-request-workflow requires a --push-branch different from the checkout branch")
+		}
+		var err error
+		workflow, err = makePullRequestWorkflow(imageUpdateArgs.pullRequestWorkflow,
+			imageUpdateArgs.pushBranch, imageUpdateArgs.checkoutBranch)
+		if err != nil {
+			return err
+		}
+	}
+
+	commitTemplate := imageUpdateArgs.commitTemplate
+	if imageUpdateArgs.commitTemplateFile != "" {
+		b, err := os.ReadFile(imageUpdateArgs.commitTemplateFile)
+		if err != nil {
+			return fmt.Errorf("unable to read commit template file: %w", err)
+		}
+		commitTemplate = string(b)
+	}
+
+	if commitTemplate != "" {
+		if err := validateCommitTemplate(commitTemplate); err != nil {
+			return err
+		}
+	}
+
 	labels, err := parseLabels()
 	if err != nil {
 		return err
 	}
 
+	var signingKey *corev1.Secret
+	signingKeySecret := imageUpdateArgs.gpgKeySecret
+	if imageUpdateArgs.gpgKeyFile != "" {
+		if signingKeySecret == "" {
+			signingKeySecret = fmt.Sprintf("%s-signing-key", objectName)
+		}
+		secret, err := makeSigningKeySecret(signingKeySecret, *kubeconfigArgs.Namespace, imageUpdateArgs.gpgKeyFile)
+		if err != nil {
+			return err
+		}
+		signingKey = &secret
+	}
+
 	var update = autov1.ImageUpdateAutomation{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      objectName,
@@ -144,7 +247,7 @@ func createImageUpdateRun(cmd *cobra.Command, args []string) error {
 						Name:  imageUpdateArgs.authorName,
 						Email: imageUpdateArgs.authorEmail,
 					},
-					MessageTemplate: imageUpdateArgs.commitTemplate,
+					MessageTemplate: commitTemplate,
 				},
 			},
 			Interval: metav1.Duration{
@@ -159,6 +262,12 @@ func createImageUpdateRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if signingKeySecret != "" {
+		update.Spec.GitSpec.Commit.SigningKey = &autov1.SigningKey{
+			SecretRef: meta.LocalObjectReference{Name: signingKeySecret},
+		}
+	}
+
 	if imageUpdateArgs.gitRepoPath != "" {
 		update.Spec.Update = &autov1.UpdateStrategy{
 			Path:     imageUpdateArgs.gitRepoPath,
@@ -167,7 +276,51 @@ func createImageUpdateRun(cmd *cobra.Command, args []string) error {
 	}
 
 	if createArgs.export {
+		if workflow != nil {
+			logger.Warningf("the pull request workflow isn't written when exporting")
+		}
+		return printExport(exportImageUpdate(&update))
+	}
+
+	if err := confirmCreate(func() error {
+		if signingKey != nil {
+			if err := printSecret(*signingKey); err != nil {
+				return err
+			}
+		}
 		return printExport(exportImageUpdate(&update))
+	}); err != nil {
+		return err
+	}
+
+	if workflow != nil {
+		path := imageUpdateArgs.pullRequestWorkflowFile
+		if path == "" {
+			path = pullRequestWorkflowPaths[imageUpdateArgs.pullRequestWorkflow]
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, workflow, 0o644); err != nil {
+			return err
+		}
+		logger.Successf("pull request workflow written to %s, commit it to the %s branch", path, imageUpdateArgs.checkoutBranch)
+	}
+
+	if signingKey != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+		defer cancel()
+
+		kubeClient, err := utils.KubeClient(kubeconfigArgs)
+		if err != nil {
+			return err
+		}
+
+		logger.Actionf("applying secret with signing key")
+		if err := upsertSecret(ctx, kubeClient, *signingKey); err != nil {
+			return err
+		}
+		logger.Successf("commit signing configured")
 	}
 
 	var existing autov1.ImageUpdateAutomation
@@ -179,3 +332,206 @@ func createImageUpdateRun(cmd *cobra.Command, args []string) error {
 	})
 	return err
 }
+
+// makeSigningKeySecret returns a secret containing the armored OpenPGP
+// private key read from the given file under the 'git.asc' key.
+func makeSigningKeySecret(name, namespace, keyFile string) (corev1.Secret, error) {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		StringData: map[string]string{},
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return secret, fmt.Errorf("unable to read OpenPGP key file: %w", err)
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return secret, fmt.Errorf("invalid OpenPGP key file '%s': %w", keyFile, err)
+	}
+	if len(entities) != 1 {
+		return secret, fmt.Errorf("invalid OpenPGP key file '%s': expected a single key, found %d", keyFile, len(entities))
+	}
+	if entities[0].PrivateKey == nil {
+		return secret, fmt.Errorf("invalid OpenPGP key file '%s': no private key found", keyFile)
+	}
+	if entities[0].PrivateKey.Encrypted {
+		return secret, fmt.Errorf("invalid OpenPGP key file '%s': the private key must not be protected by a passphrase", keyFile)
+	}
+	secret.StringData["git.asc"] = string(data)
+	return secret, nil
+}
+
+var pullRequestWorkflowPaths = map[string]string{
+	"github": ".github/workflows/flux-image-updates.yaml",
+	"gitlab": "flux-image-updates.gitlab-ci.yml",
+}
+
+var pullRequestWorkflowTemplates = map[string]string{
+	"github": `name: flux-image-updates
+on:
+  push:
+    branches:
+      - {{ .PushBranch }}
+permissions:
+  contents: read
+  pull-requests: write
+jobs:
+  pull-request:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v2
+      - name: Open a pull request
+        env:
+          GH_TOKEN: ${{"{{"}} secrets.GITHUB_TOKEN {{"}}"}}
+        run: |
+          if [ -z "$(gh pr list --head {{ .PushBranch }} --base {{ .BaseBranch }} --json number -q '.[].number')" ]; then
+            gh pr create --head {{ .PushBranch }} --base {{ .BaseBranch }} \
+              --title "Update images" --body "Image updates pushed by Flux to {{ .PushBranch }}."
+          fi
+`,
+	"gitlab": `flux-image-updates:
+  image: curlimages/curl:latest
+  rules:
+    - if: $CI_COMMIT_BRANCH == "{{ .PushBranch }}"
+  script:
+    # GITLAB_TOKEN must be a CI/CD variable holding a token with the api scope
+    - >
+      curl --silent --fail -X POST -H "PRIVATE-TOKEN: $GITLAB_TOKEN"
+      "$CI_API_V4_URL/projects/$CI_PROJECT_ID/merge_requests"
+      --data-urlencode "source_branch={{ .PushBranch }}"
+      --data-urlencode "target_branch={{ .BaseBranch }}"
+      --data-urlencode "title=Update images"
+      --data-urlencode "description=Image updates pushed by Flux to {{ .PushBranch }}."
+      || echo "merge request already open"
+`,
+}
+
+// makePullRequestWorkflow returns the CI workflow of the given Git provider
+// opening a pull request from the push branch to the base branch.
+func makePullRequestWorkflow(provider, pushBranch, baseBranch string) ([]byte, error) {
+	text, ok := pullRequestWorkflowTemplates[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported pull request workflow '%s', must be one of: github, gitlab", provider)
+	}
+	tmpl, err := template.New(provider).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ PushBranch, BaseBranch string }{pushBranch, baseBranch}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validateCommitTemplate executes the commit message template against sample
+// data shaped like the data image-automation-controller passes to it, so that
+// unknown fields and methods are reported before the object is applied.
+func validateCommitTemplate(text string) error {
+	tmpl, err := template.New("commit message").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid commit template: %w", err)
+	}
+
+	image := commitTemplateImageRef{
+		registry:   "ghcr.io",
+		repository: "example/app",
+		identifier: "1.0.1",
+		policy:     types.NamespacedName{Namespace: "flux-system", Name: "app"},
+	}
+	object := commitTemplateObjectIdentifier{
+		ResId: resid.NewResIdWithNamespace(resid.NewGvk("apps", "v1", "Deployment"), "app", "default"),
+	}
+	data := commitTemplateData{
+		AutomationObject: types.NamespacedName{Namespace: "flux-system", Name: "flux-system"},
+		Updated: commitTemplateResult{
+			Files: map[string]commitTemplateFileResult{
+				"apps/app.yaml": {
+					Objects: map[commitTemplateObjectIdentifier][]commitTemplateImageRef{
+						object: {image},
+					},
+				},
+			},
+		},
+	}
+
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return fmt.Errorf("invalid commit template: %w", err)
+	}
+	return nil
+}
+
+// commitTemplateData mirrors the template data of image-automation-controller.
+type commitTemplateData struct {
+	AutomationObject types.NamespacedName
+	Updated          commitTemplateResult
+}
+
+type commitTemplateResult struct {
+	Files map[string]commitTemplateFileResult
+}
+
+type commitTemplateFileResult struct {
+	Objects map[commitTemplateObjectIdentifier][]commitTemplateImageRef
+}
+
+// commitTemplateObjectIdentifier mirrors update.ObjectIdentifier, which
+// embeds the kyaml resource ID.
+type commitTemplateObjectIdentifier struct {
+	resid.ResId
+}
+
+func (r commitTemplateResult) Images() []commitTemplateImageRef {
+	var images []commitTemplateImageRef
+	for _, file := range r.Files {
+		for _, refs := range file.Objects {
+			images = append(images, refs...)
+		}
+	}
+	return images
+}
+
+func (r commitTemplateResult) Objects() map[commitTemplateObjectIdentifier][]commitTemplateImageRef {
+	objects := make(map[commitTemplateObjectIdentifier][]commitTemplateImageRef)
+	for _, file := range r.Files {
+		for id, refs := range file.Objects {
+			objects[id] = append(objects[id], refs...)
+		}
+	}
+	return objects
+}
+
+type commitTemplateImageRef struct {
+	registry   string
+	repository string
+	identifier string
+	policy     types.NamespacedName
+}
+
+func (i commitTemplateImageRef) String() string {
+	return i.Name() + ":" + i.identifier
+}
+
+func (i commitTemplateImageRef) Name() string {
+	return i.registry + "/" + i.repository
+}
+
+func (i commitTemplateImageRef) Registry() string {
+	return i.registry
+}
+
+func (i commitTemplateImageRef) Repository() string {
+	return i.repository
+}
+
+func (i commitTemplateImageRef) Identifier() string {
+	return i.identifier
+}
+
+func (i commitTemplateImageRef) Policy() types.NamespacedName {
+	return i.policy
+}
@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommitTemplate(t *testing.T) {
+	file, err := os.ReadFile("testdata/image_update/commit-template.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		template  string
+		expectErr bool
+	}{
+		{"file", string(file), false},
+		{"inline", "{{range .Updated.Images}}{{println .}}{{end}}", false},
+		{"unknown field", "{{ .Updated.Commits }}", true},
+		{"unknown method", "{{range .Updated.Images}}{{ .Tag }}{{end}}", true},
+		{"object kind", "{{range $id, $_ := .Updated.Objects}}{{ $id.Gvk.Kind }} {{ $id.Name }}{{end}}", false},
+		{"unknown object field", "{{range $id, $_ := .Updated.Objects}}{{ $id.APIVersion }}{{end}}", true},
+		{"syntax error", "{{ range .Updated.Images }}", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCommitTemplate(tc.template)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestMakePullRequestWorkflow(t *testing.T) {
+	cases := []struct {
+		provider  string
+		expect    []string
+		expectErr bool
+	}{
+		{"github", []string{"- image-updates", "--head image-updates --base main", "GH_TOKEN: ${{ secrets.GITHUB_TOKEN }}"}, false},
+		{"gitlab", []string{`$CI_COMMIT_BRANCH == "image-updates"`, "target_branch=main"}, false},
+		{"bitbucket", nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider, func(t *testing.T) {
+			got, err := makePullRequestWorkflow(tc.provider, "image-updates", "main")
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			for _, s := range tc.expect {
+				if !strings.Contains(string(got), s) {
+					t.Errorf("expected %s in:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeSigningKeySecret(t *testing.T) {
+	cases := []struct {
+		name      string
+		keyFile   string
+		expectErr string
+	}{
+		{"private key", "testdata/kustomization/decryption/private.asc", ""},
+		{"public key", "testdata/kustomization/decryption/public.asc", "no private key found"},
+		{"not a keyring", "testdata/kustomization/decryption/age.agekey", "invalid OpenPGP key file"},
+		{"missing file", "testdata/kustomization/decryption/missing.asc", "unable to read OpenPGP key file"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := makeSigningKeySecret("podinfo-signing-key", "default", tc.keyFile)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := secret.StringData["git.asc"]; !ok {
+				t.Errorf("expected git.asc key in secret, got %v", secret.StringData)
+			}
+		})
+	}
+}
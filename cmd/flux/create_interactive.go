@@ -0,0 +1,311 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+// interactiveField is a flag prompted for in interactive mode when it is
+// not set on the command line.
+type interactiveField struct {
+	flag string
+	// kinds of the objects in the namespace offered as completions,
+	// the completions are prefixed with the kind when withKind is true.
+	kinds    []string
+	withKind bool
+	// skipIf lists the flags that make this field optional when set.
+	skipIf []string
+}
+
+var interactiveFields = map[string][]interactiveField{
+	"flux create source git": {
+		{flag: "url"},
+		{flag: "branch", skipIf: []string{"tag", "tag-semver"}},
+	},
+	"flux create source helm": {
+		{flag: "url"},
+	},
+	"flux create source bucket": {
+		{flag: "bucket-name"},
+		{flag: "endpoint"},
+	},
+	"flux create source chart": {
+		{flag: "source", kinds: []string{sourcev1.HelmRepositoryKind, sourcev1.GitRepositoryKind, sourcev1.BucketKind}, withKind: true},
+		{flag: "chart"},
+	},
+	"flux create kustomization": {
+		{flag: "source", kinds: []string{sourcev1.GitRepositoryKind, sourcev1.BucketKind}, withKind: true},
+		{flag: "path"},
+	},
+	"flux create helmrelease": {
+		{flag: "source", kinds: []string{sourcev1.HelmRepositoryKind, sourcev1.GitRepositoryKind, sourcev1.BucketKind}, withKind: true},
+		{flag: "chart"},
+	},
+	"flux create tenant": {
+		{flag: "with-namespace", kinds: []string{"Namespace"}},
+	},
+	"flux create alert-provider": {
+		{flag: "type"},
+	},
+	"flux create alert": {
+		{flag: "provider-ref", kinds: []string{notificationv1.ProviderKind}},
+		{flag: "event-source"},
+	},
+	"flux create receiver": {
+		{flag: "type"},
+		{flag: "secret-ref"},
+		{flag: "resource"},
+	},
+	"flux create image repository": {
+		{flag: "image"},
+	},
+	"flux create image policy": {
+		{flag: "image-ref", kinds: []string{imagev1.ImageRepositoryKind}},
+		{flag: "select-semver", skipIf: []string{"select-alpha", "select-numeric"}},
+	},
+	"flux create image update": {
+		{flag: "git-repo-ref", kinds: []string{sourcev1.GitRepositoryKind}},
+		{flag: "checkout-branch"},
+		{flag: "author-name"},
+		{flag: "author-email"},
+	},
+	"flux create secret git": {
+		{flag: "url"},
+	},
+}
+
+var interactiveKinds = map[string]schema.GroupVersion{
+	"Namespace":                 corev1.SchemeGroupVersion,
+	sourcev1.GitRepositoryKind:  sourcev1.GroupVersion,
+	sourcev1.HelmRepositoryKind: sourcev1.GroupVersion,
+	sourcev1.BucketKind:         sourcev1.GroupVersion,
+	imagev1.ImageRepositoryKind: imagev1.GroupVersion,
+	notificationv1.ProviderKind: notificationv1.GroupVersion,
+}
+
+// interactiveClusterKinds are the cluster-scoped kinds of interactiveKinds,
+// their completions are listed in all namespaces.
+var interactiveClusterKinds = map[string]bool{
+	"Namespace": true,
+}
+
+// runPrompt and runSelect run the interactive prompts, they return the
+// entered or selected value.
+var (
+	runPrompt = func(prompt promptui.Prompt) (string, error) {
+		return prompt.Run()
+	}
+	runSelect = func(sel promptui.Select) (string, error) {
+		_, value, err := sel.Run()
+		return value, err
+	}
+)
+
+// createInteractivePreRun wraps the command with the interactive wizard,
+// which prompts for the name and the required flags before running it.
+// The commands ask for confirmation with confirmCreate before applying.
+func createInteractivePreRun(cmd *cobra.Command, args []string) error {
+	if !createArgs.interactive || cmd.RunE == nil {
+		return nil
+	}
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		args, err := promptCreateArgs(cmd, args, func() (client.Client, error) {
+			return utils.KubeClient(kubeconfigArgs)
+		})
+		if err != nil {
+			return err
+		}
+		return run(cmd, args)
+	}
+	return nil
+}
+
+// confirmCreate prints the generated objects with preview and asks for
+// confirmation before they are applied. It does nothing unless the command
+// runs in interactive mode, the preview must print the objects that are
+// applied afterwards, including the generated secrets.
+func confirmCreate(preview func() error) error {
+	if !createArgs.interactive || createArgs.export {
+		return nil
+	}
+	if err := preview(); err != nil {
+		return err
+	}
+	prompt := promptui.Prompt{
+		Label:     "Apply the generated resources on the cluster",
+		IsConfirm: true,
+	}
+	if _, err := runPrompt(prompt); err != nil {
+		return fmt.Errorf("aborting")
+	}
+	return nil
+}
+
+// printSecret prints the secret in YAML format like printExport.
+func printSecret(secret corev1.Secret) error {
+	secret.TypeMeta = metav1.TypeMeta{
+		APIVersion: "v1",
+		Kind:       "Secret",
+	}
+	return printExport(secret)
+}
+
+// promptCreateArgs prompts for the name when the command takes one and it is
+// missing, then for the interactive fields of the command that are not set.
+// The completions are listed with the client returned by newClient.
+func promptCreateArgs(cmd *cobra.Command, args []string, newClient func() (client.Client, error)) ([]string, error) {
+	if len(args) == 0 && strings.Contains(cmd.Use, "[name]") {
+		prompt := promptui.Prompt{
+			Label: "Name",
+			Validate: func(s string) error {
+				if errs := validation.IsDNS1123Subdomain(s); len(errs) > 0 {
+					return fmt.Errorf("%s", strings.Join(errs, ", "))
+				}
+				return nil
+			},
+		}
+		name, err := runPrompt(prompt)
+		if err != nil {
+			return nil, fmt.Errorf("aborting")
+		}
+		args = append(args, name)
+	}
+
+	var kubeClient client.Client
+	for _, field := range interactiveFields[cmd.CommandPath()] {
+		flag := cmd.Flags().Lookup(field.flag)
+		if flag == nil || flag.Changed || anyFlagChanged(cmd, field.skipIf) {
+			continue
+		}
+
+		var completions []string
+		if len(field.kinds) > 0 {
+			if kubeClient == nil {
+				c, err := newClient()
+				if err != nil {
+					logger.Warningf("completions are unavailable: %s", err.Error())
+				}
+				kubeClient = c
+			}
+			if kubeClient != nil {
+				completions = listInteractiveCompletions(kubeClient, field)
+			}
+		}
+
+		if err := promptFlag(cmd, flag, completions); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// promptFlag prompts for the flag value until it is accepted by the flag,
+// offering the given completions when there are any.
+func promptFlag(cmd *cobra.Command, flag *pflag.Flag, completions []string) error {
+	const other = "other..."
+	for {
+		var value string
+		var err error
+		if len(completions) > 0 {
+			sel := promptui.Select{
+				Label: flag.Name,
+				Items: append(completions, other),
+			}
+			value, err = runSelect(sel)
+			if err != nil {
+				return fmt.Errorf("aborting")
+			}
+		}
+		if len(completions) == 0 || value == other {
+			prompt := promptui.Prompt{
+				Label: fmt.Sprintf("%s (%s)", flag.Name, flag.Usage),
+				Validate: func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return fmt.Errorf("%s is required", flag.Name)
+					}
+					return nil
+				},
+			}
+			value, err = runPrompt(prompt)
+			if err != nil {
+				return fmt.Errorf("aborting")
+			}
+		}
+
+		if err := cmd.Flags().Set(flag.Name, value); err != nil {
+			logger.Failuref("%s", err.Error())
+			continue
+		}
+		return nil
+	}
+}
+
+func listInteractiveCompletions(kubeClient client.Client, field interactiveField) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	var completions []string
+	for _, kind := range field.kinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(interactiveKinds[kind].WithKind(kind + "List"))
+		var opts []client.ListOption
+		if !interactiveClusterKinds[kind] {
+			opts = append(opts, client.InNamespace(*kubeconfigArgs.Namespace))
+		}
+		if err := kubeClient.List(ctx, list, opts...); err != nil {
+			continue
+		}
+		for _, item := range list.Items {
+			if field.withKind {
+				completions = append(completions, fmt.Sprintf("%s/%s", kind, item.GetName()))
+			} else {
+				completions = append(completions, item.GetName())
+			}
+		}
+	}
+	return completions
+}
+
+func anyFlagChanged(cmd *cobra.Command, names []string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+// stubPrompts replaces the interactive prompts with the given answers, keyed
+// by the prompt or select label, for the duration of the test.
+func stubPrompts(t *testing.T, prompts map[string]string, selects map[string]string) *[]string {
+	var items []string
+	prevPrompt, prevSelect := runPrompt, runSelect
+	runPrompt = func(prompt promptui.Prompt) (string, error) {
+		label := fmt.Sprintf("%v", prompt.Label)
+		value, ok := prompts[label]
+		if !ok {
+			return "", fmt.Errorf("unexpected prompt %q", label)
+		}
+		return value, nil
+	}
+	runSelect = func(sel promptui.Select) (string, error) {
+		label := fmt.Sprintf("%v", sel.Label)
+		value, ok := selects[label]
+		if !ok {
+			return "", fmt.Errorf("unexpected select %q", label)
+		}
+		items = append(items, sel.Items.([]string)...)
+		return value, nil
+	}
+	t.Cleanup(func() {
+		runPrompt, runSelect = prevPrompt, prevSelect
+	})
+	return &items
+}
+
+// newInteractiveTestCmd returns a command with the given path under a flux
+// root command, with string slice flags.
+func newInteractiveTestCmd(use string, parents []string, flags ...string) *cobra.Command {
+	parent := &cobra.Command{Use: "flux"}
+	for _, name := range parents {
+		c := &cobra.Command{Use: name}
+		parent.AddCommand(c)
+		parent = c
+	}
+	cmd := &cobra.Command{Use: use, RunE: func(*cobra.Command, []string) error { return nil }}
+	for _, flag := range flags {
+		cmd.Flags().StringSlice(flag, nil, flag+" usage")
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func TestPromptCreateArgs(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "flux-system"}},
+	).Build()
+	newClient := func() (client.Client, error) { return kubeClient, nil }
+
+	t.Run("tenant name and namespace", func(t *testing.T) {
+		items := stubPrompts(t, map[string]string{"Name": "dev-team"}, map[string]string{"with-namespace": "apps"})
+		cmd := newInteractiveTestCmd("tenant [name]", []string{"create"}, "with-namespace")
+
+		args, err := promptCreateArgs(cmd, nil, newClient)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff([]string{"dev-team"}, args); diff != "" {
+			t.Errorf("unexpected args (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"apps", "flux-system", "other..."}, *items); diff != "" {
+			t.Errorf("unexpected completions (-want +got):\n%s", diff)
+		}
+		if value, _ := cmd.Flags().GetStringSlice("with-namespace"); !cmp.Equal(value, []string{"apps"}) {
+			t.Errorf("expected with-namespace to be [apps], got %v", value)
+		}
+	})
+
+	t.Run("other value", func(t *testing.T) {
+		stubPrompts(t, map[string]string{"with-namespace (with-namespace usage)": "frontend"},
+			map[string]string{"with-namespace": "other..."})
+		cmd := newInteractiveTestCmd("tenant [name]", []string{"create"}, "with-namespace")
+
+		args, err := promptCreateArgs(cmd, []string{"dev-team"}, newClient)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff([]string{"dev-team"}, args); diff != "" {
+			t.Errorf("unexpected args (-want +got):\n%s", diff)
+		}
+		if value, _ := cmd.Flags().GetStringSlice("with-namespace"); !cmp.Equal(value, []string{"frontend"}) {
+			t.Errorf("expected with-namespace to be [frontend], got %v", value)
+		}
+	})
+
+	t.Run("flag set on the command line", func(t *testing.T) {
+		stubPrompts(t, nil, nil)
+		cmd := newInteractiveTestCmd("tenant [name]", []string{"create"}, "with-namespace")
+		if err := cmd.Flags().Set("with-namespace", "backend"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := promptCreateArgs(cmd, []string{"dev-team"}, newClient); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("skipped field", func(t *testing.T) {
+		stubPrompts(t, map[string]string{"url (url usage)": "https://github.com/stefanprodan/podinfo"}, nil)
+		cmd := newInteractiveTestCmd("git [name]", []string{"create", "source"}, "url", "branch", "tag")
+		if err := cmd.Flags().Set("tag", "6.0.0"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := promptCreateArgs(cmd, []string{"podinfo"}, newClient); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestListInteractiveCompletions(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&sourcev1.GitRepository{ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "flux-system"}},
+		&sourcev1.GitRepository{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "apps"}},
+		&sourcev1.Bucket{ObjectMeta: metav1.ObjectMeta{Name: "manifests", Namespace: "flux-system"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+	).Build()
+
+	tests := []struct {
+		name     string
+		field    interactiveField
+		expected []string
+	}{
+		{
+			name:     "with kind",
+			field:    interactiveField{kinds: []string{sourcev1.GitRepositoryKind, sourcev1.BucketKind}, withKind: true},
+			expected: []string{"GitRepository/podinfo", "Bucket/manifests"},
+		},
+		{
+			name:     "without kind",
+			field:    interactiveField{kinds: []string{sourcev1.GitRepositoryKind}},
+			expected: []string{"podinfo"},
+		},
+		{
+			name:     "cluster-scoped kind",
+			field:    interactiveField{kinds: []string{"Namespace"}},
+			expected: []string{"apps"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, listInteractiveCompletions(kubeClient, tt.field)); diff != "" {
+				t.Errorf("unexpected completions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,21 +17,31 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/kustomize"
 	"github.com/fluxcd/pkg/apis/meta"
 
 	"github.com/fluxcd/flux2/internal/flags"
@@ -61,6 +71,17 @@ var createKsCmd = &cobra.Command{
     --prune=true \
     --interval=5m
 
+  # Create a Kustomization resource that waits for all the applied resources to become ready,
+  # and retries every minute after a failure
+  flux create kustomization webapp \
+    --source=GitRepository/webapp \
+    --path="./deploy/overlays/dev" \
+    --prune=true \
+    --wait=true \
+    --health-check-timeout=5m \
+    --interval=10m \
+    --retry-interval=1m
+
   # Create a Kustomization using a source from a different namespace
   flux create kustomization podinfo \
     --namespace=default \
@@ -69,6 +90,32 @@ var createKsCmd = &cobra.Command{
     --prune=true \
     --interval=5m
 
+  # Create a Kustomization resource with patches read from local files
+  flux create kustomization podinfo \
+    --source=GitRepository/podinfo \
+    --path="./kustomize" \
+    --patch-file=./patches/replicas.yaml \
+    --patch-file=./patches/image.yaml \
+    --interval=5m
+
+  # Create a Kustomization resource that decrypts SOPS secrets with an age key,
+  # the key is stored in the sops-age secret in the Kustomization namespace
+  flux create kustomization secrets \
+    --source=GitRepository/secrets \
+    --path="./secrets" \
+    --decryption-provider=sops \
+    --decryption-age-key-file=./age.agekey \
+    --interval=5m
+
+  # Create a Kustomization resource with post-build variable substitutions
+  flux create kustomization podinfo \
+    --source=GitRepository/podinfo \
+    --path="./kustomize" \
+    --substitute=cluster_env=prod \
+    --substitute-file=./cluster-vars.env \
+    --substitute-from=ConfigMap/cluster-vars,Secret/cluster-secret-vars \
+    --interval=5m
+
   # Create a Kustomization resource that references a Bucket
   flux create kustomization secrets \
     --source=Bucket/secrets \
@@ -85,11 +132,18 @@ type kustomizationFlags struct {
 	validation         string
 	healthCheck        []string
 	healthTimeout      time.Duration
+	retryInterval      time.Duration
 	saName             string
 	decryptionProvider flags.DecryptionProvider
 	decryptionSecret   string
+	decryptionAgeKey   string
+	decryptionGPGKey   string
 	targetNamespace    string
 	wait               bool
+	patchFiles         []string
+	substitute         []string
+	substituteFile     string
+	substituteFrom     []string
 }
 
 var kustomizationArgs = NewKustomizationFlags()
@@ -100,13 +154,25 @@ func init() {
 	createKsCmd.Flags().BoolVar(&kustomizationArgs.prune, "prune", false, "enable garbage collection")
 	createKsCmd.Flags().BoolVar(&kustomizationArgs.wait, "wait", false, "enable health checking of all the applied resources")
 	createKsCmd.Flags().StringSliceVar(&kustomizationArgs.healthCheck, "health-check", nil, "workload to be included in the health assessment, in the format '<kind>/<name>.<namespace>'")
-	createKsCmd.Flags().DurationVar(&kustomizationArgs.healthTimeout, "health-check-timeout", 2*time.Minute, "timeout of health checking operations")
+	createKsCmd.Flags().DurationVar(&kustomizationArgs.healthTimeout, "health-check-timeout", 2*time.Minute, "timeout of the apply, health checking and garbage collection operations")
+	createKsCmd.Flags().DurationVar(&kustomizationArgs.retryInterval, "retry-interval", 0, "the interval at which to retry a failed reconciliation, defaults to the interval")
 	createKsCmd.Flags().StringVar(&kustomizationArgs.validation, "validation", "", "validate the manifests before applying them on the cluster, can be 'client' or 'server'")
 	createKsCmd.Flags().StringSliceVar(&kustomizationArgs.dependsOn, "depends-on", nil, "Kustomization that must be ready before this Kustomization can be applied, supported formats '<name>' and '<namespace>/<name>', also accepts comma-separated values")
 	createKsCmd.Flags().StringVar(&kustomizationArgs.saName, "service-account", "", "the name of the service account to impersonate when reconciling this Kustomization")
 	createKsCmd.Flags().Var(&kustomizationArgs.decryptionProvider, "decryption-provider", kustomizationArgs.decryptionProvider.Description())
 	createKsCmd.Flags().StringVar(&kustomizationArgs.decryptionSecret, "decryption-secret", "", "set the Kubernetes secret name that contains the OpenPGP private keys used for sops decryption")
+	createKsCmd.Flags().StringVar(&kustomizationArgs.decryptionAgeKey, "decryption-age-key-file", "", "path to an age private key file, the key is stored in the decryption secret which defaults to 'sops-age'")
+	createKsCmd.Flags().StringVar(&kustomizationArgs.decryptionGPGKey, "decryption-gpg-key-file", "", "path to an armored OpenPGP private key file, the key is stored in the decryption secret which defaults to 'sops-gpg'")
 	createKsCmd.Flags().StringVar(&kustomizationArgs.targetNamespace, "target-namespace", "", "overrides the namespace of all Kustomization objects reconciled by this Kustomization")
+	createKsCmd.Flags().StringSliceVar(&kustomizationArgs.patchFiles, "patch-file", nil,
+		"path to a file containing strategic merge patches or a list of patches with targets, also accepts comma-separated values")
+	createKsCmd.Flags().StringArrayVar(&kustomizationArgs.substitute, "substitute", nil,
+		"variable used for post-build substitution in the format 'key=value', takes precedence over the variables in --substitute-file")
+	createKsCmd.Flags().StringVar(&kustomizationArgs.substituteFile, "substitute-file", "",
+		"path to an env file containing the variables used for post-build substitution")
+	createKsCmd.Flags().StringSliceVar(&kustomizationArgs.substituteFrom, "substitute-from", nil,
+		"Kubernetes objects that contain the variables used for post-build substitution in the format '<kind>/<name>', "+
+			"where kind must be ConfigMap or Secret, also accepts comma-separated values")
 	createKsCmd.Flags().MarkDeprecated("validation", "this arg is no longer used, all resources are validated using server-side apply dry-run")
 
 	createCmd.AddCommand(createKsCmd)
@@ -131,6 +197,10 @@ func createKsCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("path must begin with ./")
 	}
 
+	if (kustomizationArgs.decryptionAgeKey != "" || kustomizationArgs.decryptionGPGKey != "") && createArgs.export {
+		return fmt.Errorf("--decryption-age-key-file and --decryption-gpg-key-file can't be used when exporting, create the secret and use --decryption-secret instead")
+	}
+
 	if !createArgs.export {
 		logger.Generatef("generating Kustomization")
 	}
@@ -211,24 +281,84 @@ func createKsCmdRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if cmd.Flags().Changed("health-check-timeout") {
+		kustomization.Spec.Timeout = &metav1.Duration{
+			Duration: kustomizationArgs.healthTimeout,
+		}
+	}
+
+	if kustomizationArgs.retryInterval > 0 {
+		kustomization.Spec.RetryInterval = &metav1.Duration{
+			Duration: kustomizationArgs.retryInterval,
+		}
+	}
+
 	if kustomizationArgs.saName != "" {
 		kustomization.Spec.ServiceAccountName = kustomizationArgs.saName
 	}
 
+	postBuild, err := makePostBuild(kustomizationArgs.substituteFile, kustomizationArgs.substitute, kustomizationArgs.substituteFrom)
+	if err != nil {
+		return err
+	}
+	kustomization.Spec.PostBuild = postBuild
+
+	var decryptionKeys *corev1.Secret
+	decryptionSecret := kustomizationArgs.decryptionSecret
+	if kustomizationArgs.decryptionAgeKey != "" || kustomizationArgs.decryptionGPGKey != "" {
+		if kustomizationArgs.decryptionProvider == "" {
+			return fmt.Errorf("--decryption-provider is required when setting a decryption key file")
+		}
+		if kustomizationArgs.decryptionAgeKey != "" && kustomizationArgs.decryptionGPGKey != "" {
+			return fmt.Errorf("--decryption-age-key-file and --decryption-gpg-key-file are mutually exclusive")
+		}
+		if decryptionSecret == "" {
+			decryptionSecret = "sops-gpg"
+			if kustomizationArgs.decryptionAgeKey != "" {
+				decryptionSecret = "sops-age"
+			}
+		}
+		secret, err := makeDecryptionSecret(decryptionSecret, *kubeconfigArgs.Namespace,
+			kustomizationArgs.decryptionAgeKey, kustomizationArgs.decryptionGPGKey)
+		if err != nil {
+			return err
+		}
+		decryptionKeys = &secret
+	}
+
 	if kustomizationArgs.decryptionProvider != "" {
 		kustomization.Spec.Decryption = &kustomizev1.Decryption{
 			Provider: kustomizationArgs.decryptionProvider.String(),
 		}
 
-		if kustomizationArgs.decryptionSecret != "" {
-			kustomization.Spec.Decryption.SecretRef = &meta.LocalObjectReference{Name: kustomizationArgs.decryptionSecret}
+		if decryptionSecret != "" {
+			kustomization.Spec.Decryption.SecretRef = &meta.LocalObjectReference{Name: decryptionSecret}
 		}
 	}
 
+	for _, file := range kustomizationArgs.patchFiles {
+		patches, err := readKustomizationPatches(file)
+		if err != nil {
+			return err
+		}
+		kustomization.Spec.Patches = append(kustomization.Spec.Patches, patches...)
+	}
+
 	if createArgs.export {
 		return printExport(exportKs(&kustomization))
 	}
 
+	if err := confirmCreate(func() error {
+		if decryptionKeys != nil {
+			if err := printSecret(*decryptionKeys); err != nil {
+				return err
+			}
+		}
+		return printExport(exportKs(&kustomization))
+	}); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 
@@ -237,6 +367,14 @@ func createKsCmdRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if decryptionKeys != nil {
+		logger.Actionf("applying secret with decryption keys")
+		if err := upsertSecret(ctx, kubeClient, *decryptionKeys); err != nil {
+			return err
+		}
+		logger.Successf("decryption configured")
+	}
+
 	logger.Actionf("applying Kustomization")
 	namespacedName, err := upsertKustomization(ctx, kubeClient, &kustomization)
 	if err != nil {
@@ -244,7 +382,7 @@ func createKsCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for Kustomization reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, &kustomization,
 		isKustomizationReady(ctx, kubeClient, namespacedName, &kustomization)); err != nil {
 		return err
 	}
@@ -309,3 +447,182 @@ func isKustomizationReady(ctx context.Context, kubeClient client.Client,
 		return false, nil
 	}
 }
+
+// makeDecryptionSecret returns a secret containing the age or OpenPGP private
+// key read from the given file, stored under the key suffix expected by SOPS.
+func makeDecryptionSecret(name, namespace, ageKeyFile, gpgKeyFile string) (corev1.Secret, error) {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		StringData: map[string]string{},
+	}
+
+	if ageKeyFile != "" {
+		data, err := os.ReadFile(ageKeyFile)
+		if err != nil {
+			return secret, fmt.Errorf("unable to read age key file: %w", err)
+		}
+		if !bytes.Contains(data, []byte("AGE-SECRET-KEY-")) {
+			return secret, fmt.Errorf("invalid age key file '%s': no secret key found", ageKeyFile)
+		}
+		secret.StringData["age.agekey"] = string(data)
+	}
+
+	if gpgKeyFile != "" {
+		data, err := os.ReadFile(gpgKeyFile)
+		if err != nil {
+			return secret, fmt.Errorf("unable to read OpenPGP key file: %w", err)
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return secret, fmt.Errorf("invalid OpenPGP key file '%s': %w", gpgKeyFile, err)
+		}
+		hasPrivateKey := false
+		for _, e := range entities {
+			if e.PrivateKey != nil {
+				hasPrivateKey = true
+			}
+		}
+		if !hasPrivateKey {
+			return secret, fmt.Errorf("invalid OpenPGP key file '%s': no private key found", gpgKeyFile)
+		}
+		secret.StringData["sops.asc"] = string(data)
+	}
+
+	return secret, nil
+}
+
+var substituteVarName = regexp.MustCompile(`^[_[:alpha:]][_[:alpha:][:digit:]]*$`)
+
+// makePostBuild returns the post-build substitutions made of the variables
+// read from the env file, the key=value pairs and the object references, or
+// nil when none are given.
+func makePostBuild(envFile string, vars []string, refs []string) (*kustomizev1.PostBuild, error) {
+	substitute := map[string]string{}
+	if envFile != "" {
+		data, err := os.ReadFile(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read substitute file: %w", err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, err := parseSubstitute(strings.TrimPrefix(line, "export "))
+			if err != nil {
+				return nil, fmt.Errorf("invalid variable in '%s' at line %d: %w", envFile, i+1, err)
+			}
+			substitute[key] = value
+		}
+	}
+
+	for _, v := range vars {
+		key, value, err := parseSubstitute(v)
+		if err != nil {
+			return nil, err
+		}
+		substitute[key] = value
+	}
+
+	var substituteFrom []kustomizev1.SubstituteReference
+	for _, r := range refs {
+		var ref flags.SubstituteFrom
+		if err := ref.Set(r); err != nil {
+			return nil, err
+		}
+		substituteFrom = append(substituteFrom, kustomizev1.SubstituteReference{
+			Kind: ref.Kind,
+			Name: ref.Name,
+		})
+	}
+
+	if len(substitute) == 0 && len(substituteFrom) == 0 {
+		return nil, nil
+	}
+
+	postBuild := &kustomizev1.PostBuild{
+		SubstituteFrom: substituteFrom,
+	}
+	if len(substitute) > 0 {
+		postBuild.Substitute = substitute
+	}
+	return postBuild, nil
+}
+
+// parseSubstitute splits a 'key=value' pair, removing the quotes around the
+// value if any.
+func parseSubstitute(str string) (string, string, error) {
+	kv := strings.SplitN(str, "=", 2)
+	if len(kv) != 2 {
+		return "", "", fmt.Errorf("invalid variable '%s', must be in the format 'key=value'", str)
+	}
+	key := strings.TrimSpace(kv[0])
+	if !substituteVarName.MatchString(key) {
+		return "", "", fmt.Errorf("invalid variable name '%s', must start with a letter or underscore "+
+			"and contain only letters, digits or underscores", key)
+	}
+	value := kv[1]
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, nil
+}
+
+// readKustomizationPatches reads the patches from the given file. Every YAML
+// document is either a strategic merge patch or a list of patches with their
+// targets, the latter being required for JSON6902 patches.
+func readKustomizationPatches(path string) ([]kustomize.Patch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read patch file: %w", err)
+	}
+
+	var patches []kustomize.Patch
+	reader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("unable to read patch file '%s': %w", path, err)
+		}
+
+		var obj interface{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, fmt.Errorf("invalid patch in '%s': %w", path, err)
+		}
+
+		switch o := obj.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			if _, ok := o["kind"]; !ok {
+				return nil, fmt.Errorf("invalid strategic merge patch in '%s': kind is required", path)
+			}
+			patches = append(patches, kustomize.Patch{Patch: strings.TrimSpace(string(doc)) + "\n"})
+		case []interface{}:
+			var list []kustomize.Patch
+			if err := yaml.UnmarshalStrict(doc, &list); err != nil {
+				return nil, fmt.Errorf("invalid patch list in '%s', JSON6902 patches must be set "+
+					"in the 'patch' field of an entry with a 'target': %w", path, err)
+			}
+			for _, p := range list {
+				if p.Patch == "" {
+					return nil, fmt.Errorf("invalid patch list in '%s': patch is required", path)
+				}
+			}
+			patches = append(patches, list...)
+		default:
+			return nil, fmt.Errorf("invalid patch in '%s'", path)
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no patches found in '%s'", path)
+	}
+	return patches, nil
+}
@@ -0,0 +1,298 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/kustomize"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestReadKustomizationPatches(t *testing.T) {
+	cases := []struct {
+		name      string
+		file      string
+		expected  []kustomize.Patch
+		expectErr string
+	}{
+		{
+			name: "strategic merge patches",
+			file: "testdata/kustomization/patches/replicas.yaml",
+			expected: []kustomize.Patch{
+				{Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: podinfo\nspec:\n  replicas: 2\n"},
+				{Patch: "apiVersion: autoscaling/v2beta2\nkind: HorizontalPodAutoscaler\nmetadata:\n  name: podinfo\nspec:\n  minReplicas: 2\n"},
+			},
+		},
+		{
+			name: "patches with targets",
+			file: "testdata/kustomization/patches/targets.yaml",
+			expected: []kustomize.Patch{
+				{
+					Patch: "- op: replace\n  path: /spec/template/spec/containers/0/image\n  value: ghcr.io/stefanprodan/podinfo:6.0.0\n",
+					Target: kustomize.Selector{
+						Kind: "Deployment",
+						Name: "podinfo",
+					},
+				},
+			},
+		},
+		{
+			name:      "JSON6902 patch without target",
+			file:      "testdata/kustomization/patches/json6902.yaml",
+			expectErr: "invalid patch list in 'testdata/kustomization/patches/json6902.yaml'",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			patches, err := readKustomizationPatches(tc.file)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, patches); diff != "" {
+				t.Errorf("patches mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMakeDecryptionSecret(t *testing.T) {
+	cases := []struct {
+		name       string
+		ageKeyFile string
+		gpgKeyFile string
+		expectKey  string
+		expectErr  string
+	}{
+		{
+			name:       "age key",
+			ageKeyFile: "testdata/kustomization/decryption/age.agekey",
+			expectKey:  "age.agekey",
+		},
+		{
+			name:       "OpenPGP private key",
+			gpgKeyFile: "testdata/kustomization/decryption/private.asc",
+			expectKey:  "sops.asc",
+		},
+		{
+			name:       "OpenPGP public key",
+			gpgKeyFile: "testdata/kustomization/decryption/public.asc",
+			expectErr:  "no private key found",
+		},
+		{
+			name:       "invalid age key",
+			ageKeyFile: "testdata/kustomization/decryption/public.asc",
+			expectErr:  "no secret key found",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := makeDecryptionSecret("sops-keys", "default", tc.ageKeyFile, tc.gpgKeyFile)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := secret.StringData[tc.expectKey]; !ok || len(secret.StringData) != 1 {
+				t.Errorf("expected secret to contain only %q, got keys %v", tc.expectKey, secret.StringData)
+			}
+		})
+	}
+}
+
+func TestMakePostBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		envFile   string
+		vars      []string
+		refs      []string
+		expected  *kustomizev1.PostBuild
+		expectErr string
+	}{
+		{
+			name:     "none",
+			expected: nil,
+		},
+		{
+			name:    "env file with overrides",
+			envFile: "testdata/kustomization/substitute/vars.env",
+			vars:    []string{"cluster_env=prod", "domain=example.com"},
+			expected: &kustomizev1.PostBuild{
+				Substitute: map[string]string{
+					"cluster_env":    "prod",
+					"cluster_region": "eu-west-1",
+					"replicas":       "2",
+					"domain":         "example.com",
+				},
+			},
+		},
+		{
+			name: "references",
+			refs: []string{"ConfigMap/cluster-vars", "secret/cluster-secret-vars"},
+			expected: &kustomizev1.PostBuild{
+				SubstituteFrom: []kustomizev1.SubstituteReference{
+					{Kind: "ConfigMap", Name: "cluster-vars"},
+					{Kind: "Secret", Name: "cluster-secret-vars"},
+				},
+			},
+		},
+		{
+			name:      "invalid variable name",
+			vars:      []string{"cluster-env=prod"},
+			expectErr: "invalid variable name 'cluster-env'",
+		},
+		{
+			name:      "invalid reference",
+			refs:      []string{"Deployment/vars"},
+			expectErr: "reference kind 'Deployment' is not supported",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			postBuild, err := makePostBuild(tc.envFile, tc.vars, tc.refs)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, postBuild); diff != "" {
+				t.Errorf("post-build mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateKustomizationTimeouts(t *testing.T) {
+	command := "create kustomization podinfo --source=GitRepository/podinfo --path=./kustomize --export"
+	duration := func(d time.Duration) *metav1.Duration {
+		return &metav1.Duration{Duration: d}
+	}
+
+	tests := []struct {
+		name          string
+		args          string
+		wait          bool
+		timeout       *metav1.Duration
+		retryInterval *metav1.Duration
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:    "health check timeout",
+			args:    "--health-check-timeout=3m",
+			timeout: duration(3 * time.Minute),
+		},
+		{
+			name:    "health checks",
+			args:    "--health-check=Deployment/podinfo.apps",
+			timeout: duration(2 * time.Minute),
+		},
+		{
+			name:    "health checks with timeout",
+			args:    "--health-check=Deployment/podinfo.apps --health-check-timeout=5m",
+			timeout: duration(5 * time.Minute),
+		},
+		{
+			name:    "wait",
+			args:    "--wait",
+			wait:    true,
+			timeout: duration(2 * time.Minute),
+		},
+		{
+			name:    "wait with timeout",
+			args:    "--wait --health-check-timeout=5m",
+			wait:    true,
+			timeout: duration(5 * time.Minute),
+		},
+		{
+			name:          "retry interval",
+			args:          "--retry-interval=1m",
+			retryInterval: duration(time.Minute),
+		},
+		{
+			name:          "timeout and retry interval",
+			args:          "--health-check-timeout=3m --retry-interval=1m",
+			timeout:       duration(3 * time.Minute),
+			retryInterval: duration(time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the flags keep their value and changed state between the runs
+			defer func() {
+				kustomizationArgs = NewKustomizationFlags()
+				for _, name := range []string{"health-check", "health-check-timeout", "retry-interval", "wait"} {
+					flag := createKsCmd.Flags().Lookup(name)
+					if name != "health-check" {
+						if err := flag.Value.Set(flag.DefValue); err != nil {
+							t.Fatal(err)
+						}
+					}
+					flag.Changed = false
+				}
+			}()
+
+			cmd := cmdTestCase{
+				args: command + " " + tt.args,
+				assert: func(output string, err error) error {
+					if err != nil {
+						return err
+					}
+					var ks kustomizev1.Kustomization
+					if err := yaml.Unmarshal([]byte(output), &ks); err != nil {
+						return err
+					}
+					if ks.Spec.Wait != tt.wait {
+						t.Errorf("expected wait to be %v, got %v", tt.wait, ks.Spec.Wait)
+					}
+					if diff := cmp.Diff(tt.timeout, ks.Spec.Timeout); diff != "" {
+						t.Errorf("timeout mismatch (-want +got):\n%s", diff)
+					}
+					if diff := cmp.Diff(tt.retryInterval, ks.Spec.RetryInterval); diff != "" {
+						t.Errorf("retry interval mismatch (-want +got):\n%s", diff)
+					}
+					return nil
+				},
+			}
+			cmd.runTestCmd(t)
+		})
+	}
+}
@@ -18,9 +18,12 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,6 +34,7 @@ import (
 	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
 	"github.com/fluxcd/pkg/apis/meta"
 
+	"github.com/fluxcd/flux2/internal/flags"
 	"github.com/fluxcd/flux2/internal/utils"
 )
 
@@ -45,13 +49,19 @@ var createReceiverCmd = &cobra.Command{
 	--event push \
 	--secret-ref webhook-token \
 	--resource GitRepository/webapp \
-	--resource HelmRepository/webapp`,
+	--resource HelmRepository/webapp
+
+  # Create a Receiver for Quay push events with a generated token
+  flux create receiver quay-receiver \
+	--type quay \
+	--resource ImageRepository/webapp`,
 	RunE: createReceiverCmdRun,
 }
 
 type receiverFlags struct {
-	receiverType string
+	receiverType flags.ReceiverType
 	secretRef    string
+	token        string
 	events       []string
 	resources    []string
 }
@@ -59,8 +69,11 @@ type receiverFlags struct {
 var receiverArgs receiverFlags
 
 func init() {
-	createReceiverCmd.Flags().StringVar(&receiverArgs.receiverType, "type", "", "")
-	createReceiverCmd.Flags().StringVar(&receiverArgs.secretRef, "secret-ref", "", "")
+	createReceiverCmd.Flags().Var(&receiverArgs.receiverType, "type", receiverArgs.receiverType.Description())
+	createReceiverCmd.Flags().StringVar(&receiverArgs.secretRef, "secret-ref", "",
+		"name of the secret containing the webhook token, when not specified a receiver-<name> secret is generated")
+	createReceiverCmd.Flags().StringVar(&receiverArgs.token, "webhook-token", "",
+		"token stored in the generated secret, when not specified a random token is generated")
 	createReceiverCmd.Flags().StringSliceVar(&receiverArgs.events, "event", []string{}, "also accepts comma-separated values")
 	createReceiverCmd.Flags().StringSliceVar(&receiverArgs.resources, "resource", []string{}, "also accepts comma-separated values")
 	createCmd.AddCommand(createReceiverCmd)
@@ -76,8 +89,12 @@ func createReceiverCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Receiver type is required")
 	}
 
-	if receiverArgs.secretRef == "" {
-		return fmt.Errorf("secret ref is required")
+	if receiverArgs.secretRef != "" && receiverArgs.token != "" {
+		return fmt.Errorf("--webhook-token cannot be used with --secret-ref")
+	}
+
+	if receiverArgs.secretRef == "" && createArgs.export {
+		return fmt.Errorf("secret ref is required when exporting")
 	}
 
 	resources := []notificationv1.CrossNamespaceObjectReference{}
@@ -113,7 +130,7 @@ func createReceiverCmdRun(cmd *cobra.Command, args []string) error {
 			Labels:    sourceLabels,
 		},
 		Spec: notificationv1.ReceiverSpec{
-			Type:      receiverArgs.receiverType,
+			Type:      receiverArgs.receiverType.String(),
 			Events:    receiverArgs.events,
 			Resources: resources,
 			SecretRef: meta.LocalObjectReference{
@@ -135,6 +152,62 @@ func createReceiverCmdRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var tokenSecret *corev1.Secret
+	generatedToken := false
+	if receiverArgs.secretRef == "" {
+		token := receiverArgs.token
+		if token == "" {
+			// the webhook path is derived from the token, keep the token of
+			// an existing receiver so that its URL doesn't change
+			token, err = existingReceiverToken(ctx, kubeClient, types.NamespacedName{
+				Namespace: *kubeconfigArgs.Namespace,
+				Name:      fmt.Sprintf("receiver-%s", name),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if token == "" {
+			if token, err = generateReceiverToken(); err != nil {
+				return err
+			}
+			generatedToken = true
+		}
+
+		tokenSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("receiver-%s", name),
+				Namespace: *kubeconfigArgs.Namespace,
+				Labels:    sourceLabels,
+			},
+			StringData: map[string]string{
+				"token": token,
+			},
+		}
+		receiver.Spec.SecretRef.Name = tokenSecret.Name
+	}
+
+	if err := confirmCreate(func() error {
+		if tokenSecret != nil {
+			if err := printSecret(*tokenSecret); err != nil {
+				return err
+			}
+		}
+		return printExport(exportReceiver(&receiver))
+	}); err != nil {
+		return err
+	}
+
+	if tokenSecret != nil {
+		logger.Actionf("applying secret with the webhook token")
+		if err := upsertSecret(ctx, kubeClient, *tokenSecret); err != nil {
+			return err
+		}
+		if generatedToken {
+			logger.Successf("generated webhook token %s", tokenSecret.StringData["token"])
+		}
+	}
+
 	logger.Actionf("applying Receiver")
 	namespacedName, err := upsertReceiver(ctx, kubeClient, &receiver)
 	if err != nil {
@@ -142,16 +215,60 @@ func createReceiverCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for Receiver reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, &receiver,
 		isReceiverReady(ctx, kubeClient, namespacedName, &receiver)); err != nil {
 		return err
 	}
 	logger.Successf("Receiver %s is ready", name)
 
 	logger.Successf("generated webhook URL %s", receiver.Status.URL)
+	if hint := receiverWebhookHint(receiver.Spec.Type); hint != "" {
+		logger.Actionf(hint)
+	}
 	return nil
 }
 
+// generateReceiverToken returns a random hex encoded token
+// suitable for the Receiver secret.
+// existingReceiverToken returns the token stored in the given secret, or an
+// empty string if the secret doesn't exist.
+func existingReceiverToken(ctx context.Context, kubeClient client.Client, secretName types.NamespacedName) (string, error) {
+	var secret corev1.Secret
+	if err := kubeClient.Get(ctx, secretName, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(secret.Data["token"]), nil
+}
+
+func generateReceiverToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("token generation failed: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// receiverWebhookHint returns how the webhook must be configured on the
+// sender side for the controller to accept the payloads of the given type.
+func receiverWebhookHint(receiverType string) string {
+	switch receiverType {
+	case notificationv1.GitHubReceiver, notificationv1.GitLabReceiver, notificationv1.BitbucketReceiver:
+		return "set the token as the webhook secret to sign the payloads"
+	case notificationv1.GenericHMACReceiver:
+		return "sign the payloads with the token and send the digest in the X-Signature header"
+	case notificationv1.HarborReceiver:
+		return "set the token as the Auth Header of the Harbor webhook"
+	case notificationv1.NexusReceiver:
+		return "set the token as the Secret Key of the Nexus webhook"
+	case notificationv1.QuayReceiver, notificationv1.ACRReceiver, notificationv1.DockerHubReceiver:
+		return "the payloads are not signed, keep the webhook URL private, it embeds the token"
+	}
+	return ""
+}
+
 func upsertReceiver(ctx context.Context, kubeClient client.Client,
 	receiver *notificationv1.Receiver) (types.NamespacedName, error) {
 	namespacedName := types.NamespacedName{
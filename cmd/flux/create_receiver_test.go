@@ -0,0 +1,60 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestExistingReceiverToken(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "receiver-github", Namespace: "flux-system"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		},
+	).Build()
+
+	// updating an existing receiver keeps its token and webhook path
+	token, err := existingReceiverToken(context.TODO(), kubeClient,
+		types.NamespacedName{Namespace: "flux-system", Name: "receiver-github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s3cr3t" {
+		t.Errorf("expected the token of the existing secret, got %q", token)
+	}
+
+	token, err = existingReceiverToken(context.TODO(), kubeClient,
+		types.NamespacedName{Namespace: "flux-system", Name: "receiver-quay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		t.Errorf("expected no token for a new receiver, got %q", token)
+	}
+}
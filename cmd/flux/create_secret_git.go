@@ -165,6 +165,13 @@ func createSecretGitCmdRun(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if err := confirmCreate(func() error {
+		rootCmd.Println(secret.Content)
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	var s corev1.Secret
 	if err := yaml.Unmarshal([]byte(secret.Content), &s); err != nil {
 		return err
@@ -98,6 +98,13 @@ func createSecretHelmCmdRun(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if err := confirmCreate(func() error {
+		rootCmd.Println(secret.Content)
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 	kubeClient, err := utils.KubeClient(kubeconfigArgs)
@@ -95,6 +95,13 @@ func createSecretTLSCmdRun(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if err := confirmCreate(func() error {
+		rootCmd.Print(secret.Content)
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 	kubeClient, err := utils.KubeClient(kubeconfigArgs)
@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -26,7 +27,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fluxcd/pkg/apis/meta"
@@ -56,6 +56,21 @@ For Buckets with static authentication, the credentials are stored in a Kubernet
 	--provider=aws \
     --endpoint=s3.amazonaws.com \
 	--region=us-east-1 \
+    --interval=10m
+
+  # Create a source for a Google Cloud Storage Bucket using a service account key
+  flux create source bucket podinfo \
+	--bucket-name=podinfo \
+	--provider=gcp \
+    --endpoint=storage.googleapis.com \
+	--service-account-file=./sa.json \
+    --interval=10m
+
+  # Create a source for a Google Cloud Storage Bucket using workload identity
+  flux create source bucket podinfo \
+	--bucket-name=podinfo \
+	--provider=gcp \
+    --endpoint=storage.googleapis.com \
     --interval=10m`,
 	RunE: createSourceBucketCmdRun,
 }
@@ -69,6 +84,8 @@ type sourceBucketFlags struct {
 	region    string
 	insecure  bool
 	secretRef string
+
+	serviceAccountFile string
 }
 
 var sourceBucketArgs = NewSourceBucketFlags()
@@ -82,6 +99,8 @@ func init() {
 	createSourceBucketCmd.Flags().StringVar(&sourceBucketArgs.region, "region", "", "the bucket region")
 	createSourceBucketCmd.Flags().BoolVar(&sourceBucketArgs.insecure, "insecure", false, "for when connecting to a non-TLS S3 HTTP endpoint")
 	createSourceBucketCmd.Flags().StringVar(&sourceBucketArgs.secretRef, "secret-ref", "", "the name of an existing secret containing credentials")
+	createSourceBucketCmd.Flags().StringVar(&sourceBucketArgs.serviceAccountFile, "service-account-file", "",
+		"path to a GCP service account JSON key, when not specified with --provider=gcp the controller uses workload identity")
 
 	createSourceCmd.AddCommand(createSourceBucketCmd)
 }
@@ -106,6 +125,27 @@ func createSourceBucketCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("endpoint is required")
 	}
 
+	var serviceAccount []byte
+	if sourceBucketArgs.serviceAccountFile != "" {
+		if sourceBucketArgs.provider.String() != sourcev1.GoogleBucketProvider {
+			return fmt.Errorf("--service-account-file can only be used with --provider=%s", sourcev1.GoogleBucketProvider)
+		}
+		if sourceBucketArgs.accessKey != "" || sourceBucketArgs.secretKey != "" || sourceBucketArgs.secretRef != "" {
+			return fmt.Errorf("--service-account-file cannot be used with --access-key, --secret-key or --secret-ref")
+		}
+		if createArgs.export {
+			return fmt.Errorf("--service-account-file can't be used when exporting, create the secret and use --secret-ref instead")
+		}
+		b, err := os.ReadFile(sourceBucketArgs.serviceAccountFile)
+		if err != nil {
+			return fmt.Errorf("unable to read service account file: %w", err)
+		}
+		if !json.Valid(b) {
+			return fmt.Errorf("service account file '%s' is not a valid JSON key", sourceBucketArgs.serviceAccountFile)
+		}
+		serviceAccount = b
+	}
+
 	sourceLabels, err := parseLabels()
 	if err != nil {
 		return err
@@ -149,16 +189,9 @@ func createSourceBucketCmdRun(cmd *cobra.Command, args []string) error {
 		return printExport(exportBucket(bucket))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
-	defer cancel()
-
-	kubeClient, err := utils.KubeClient(kubeconfigArgs)
-	if err != nil {
-		return err
-	}
-
 	logger.Generatef("generating Bucket source")
 
+	var credentials *corev1.Secret
 	if sourceBucketArgs.secretRef == "" {
 		secretName := fmt.Sprintf("bucket-%s", name)
 
@@ -176,18 +209,45 @@ func createSourceBucketCmdRun(cmd *cobra.Command, args []string) error {
 			secret.StringData["secretkey"] = sourceBucketArgs.secretKey
 		}
 
+		if len(serviceAccount) > 0 {
+			secret.StringData["serviceaccount"] = string(serviceAccount)
+		}
+
 		if len(secret.StringData) > 0 {
-			logger.Actionf("applying secret with the bucket credentials")
-			if err := upsertSecret(ctx, kubeClient, secret); err != nil {
-				return err
-			}
+			credentials = &secret
 			bucket.Spec.SecretRef = &meta.LocalObjectReference{
 				Name: secretName,
 			}
-			logger.Successf("authentication configured")
 		}
 	}
 
+	if err := confirmCreate(func() error {
+		if credentials != nil {
+			if err := printSecret(*credentials); err != nil {
+				return err
+			}
+		}
+		return printExport(exportBucket(bucket))
+	}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	if credentials != nil {
+		logger.Actionf("applying secret with the bucket credentials")
+		if err := upsertSecret(ctx, kubeClient, *credentials); err != nil {
+			return err
+		}
+		logger.Successf("authentication configured")
+	}
+
 	logger.Actionf("applying Bucket source")
 	namespacedName, err := upsertBucket(ctx, kubeClient, bucket)
 	if err != nil {
@@ -195,7 +255,7 @@ func createSourceBucketCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for Bucket source reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, bucket,
 		isBucketReady(ctx, kubeClient, namespacedName, bucket)); err != nil {
 		return err
 	}
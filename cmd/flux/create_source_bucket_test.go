@@ -0,0 +1,66 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateSourceBucketServiceAccountFile(t *testing.T) {
+	saFile := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(saFile, []byte(`{"type": "service_account"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	command := "create source bucket podinfo --bucket-name=podinfo --endpoint=storage.googleapis.com --service-account-file=" + saFile
+
+	tests := []struct {
+		name   string
+		args   string
+		assert assertFunc
+	}{
+		{
+			name:   "export",
+			args:   command + " --provider=gcp --export",
+			assert: assertError("--service-account-file can't be used when exporting, create the secret and use --secret-ref instead"),
+		},
+		{
+			name:   "provider",
+			args:   command + " --provider=aws --export",
+			assert: assertError("--service-account-file can only be used with --provider=gcp"),
+		},
+		{
+			name:   "secret ref",
+			args:   command + " --provider=gcp --secret-ref=gcp-credentials --export",
+			assert: assertError("--service-account-file cannot be used with --access-key, --secret-key or --secret-ref"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := cmdTestCase{
+				args:   tt.args,
+				assert: tt.assert,
+			}
+			cmd.runTestCmd(t)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/flags"
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var createSourceHelmChartCmd = &cobra.Command{
+	Use:   "chart [name]",
+	Short: "Create or update a HelmChart source",
+	Long: `The create source chart command generates a HelmChart resource and waits for the chart to be packaged.
+The source must be in the same namespace as the HelmChart.`,
+	Example: `  # Create a HelmChart for a chart from a HelmRepository source
+  flux create source chart podinfo \
+    --source=HelmRepository/podinfo \
+    --chart=podinfo \
+    --chart-version=6.x
+
+  # Create a HelmChart for a chart from a GitRepository source,
+  # packaging a new artifact on every source revision
+  flux create source chart podinfo \
+    --source=GitRepository/podinfo \
+    --chart=./charts/podinfo \
+    --reconcile-strategy=Revision
+
+  # Create a HelmChart with merged values files from the chart
+  flux create source chart podinfo \
+    --source=GitRepository/podinfo \
+    --chart=./charts/podinfo \
+    --values-files=values.yaml,values-prod.yaml
+
+  # Create a HelmChart definition on disk without applying it on the cluster
+  flux create source chart podinfo \
+    --source=HelmRepository/podinfo \
+    --chart=podinfo \
+    --export > podinfo-chart.yaml`,
+	RunE: createSourceHelmChartCmdRun,
+}
+
+type sourceHelmChartFlags struct {
+	source            flags.HelmChartSource
+	chart             string
+	chartVersion      string
+	reconcileStrategy flags.HelmChartReconcileStrategy
+	valuesFiles       []string
+}
+
+var sourceHelmChartArgs sourceHelmChartFlags
+
+func init() {
+	createSourceHelmChartCmd.Flags().Var(&sourceHelmChartArgs.source, "source", sourceHelmChartArgs.source.Description())
+	createSourceHelmChartCmd.Flags().StringVar(&sourceHelmChartArgs.chart, "chart", "", "Helm chart name or path")
+	createSourceHelmChartCmd.Flags().StringVar(&sourceHelmChartArgs.chartVersion, "chart-version", "", "Helm chart version, accepts a semver range (ignored for charts from GitRepository sources)")
+	createSourceHelmChartCmd.Flags().Var(&sourceHelmChartArgs.reconcileStrategy, "reconcile-strategy", sourceHelmChartArgs.reconcileStrategy.Description())
+	createSourceHelmChartCmd.Flags().StringSliceVar(&sourceHelmChartArgs.valuesFiles, "values-files", nil, "paths of the values files in the chart to merge with the default values, also accepts comma-separated values")
+
+	createSourceCmd.AddCommand(createSourceHelmChartCmd)
+}
+
+func createSourceHelmChartCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("HelmChart source name is required")
+	}
+	name := args[0]
+
+	if sourceHelmChartArgs.source.Name == "" {
+		return fmt.Errorf("source is required")
+	}
+
+	if sourceHelmChartArgs.source.Namespace != "" && sourceHelmChartArgs.source.Namespace != *kubeconfigArgs.Namespace {
+		return fmt.Errorf("source must be in the same namespace as the HelmChart")
+	}
+
+	if sourceHelmChartArgs.chart == "" {
+		return fmt.Errorf("chart name or path is required")
+	}
+
+	sourceLabels, err := parseLabels()
+	if err != nil {
+		return err
+	}
+
+	helmChart := &sourcev1.HelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: *kubeconfigArgs.Namespace,
+			Labels:    sourceLabels,
+		},
+		Spec: sourcev1.HelmChartSpec{
+			Chart:   sourceHelmChartArgs.chart,
+			Version: sourceHelmChartArgs.chartVersion,
+			SourceRef: sourcev1.LocalHelmChartSourceReference{
+				Kind: sourceHelmChartArgs.source.Kind,
+				Name: sourceHelmChartArgs.source.Name,
+			},
+			Interval: metav1.Duration{
+				Duration: createArgs.interval,
+			},
+			ReconcileStrategy: sourceHelmChartArgs.reconcileStrategy.String(),
+			ValuesFiles:       sourceHelmChartArgs.valuesFiles,
+		},
+	}
+
+	if createArgs.export {
+		return printExport(exportHelmChart(helmChart))
+	}
+
+	if err := confirmCreate(func() error {
+		return printExport(exportHelmChart(helmChart))
+	}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Generatef("generating HelmChart source")
+
+	logger.Actionf("applying HelmChart source")
+	namespacedName, err := upsertHelmChart(ctx, kubeClient, helmChart)
+	if err != nil {
+		return err
+	}
+
+	logger.Waitingf("waiting for HelmChart source reconciliation")
+	if err := waitForObject(ctx, kubeClient, namespacedName, helmChart,
+		isHelmChartReady(ctx, kubeClient, namespacedName, helmChart)); err != nil {
+		return err
+	}
+	logger.Successf("HelmChart source reconciliation completed")
+
+	if helmChart.Status.Artifact == nil {
+		return fmt.Errorf("HelmChart source reconciliation completed but no artifact was found")
+	}
+	logger.Successf("fetched revision: %s", helmChart.Status.Artifact.Revision)
+	return nil
+}
+
+func upsertHelmChart(ctx context.Context, kubeClient client.Client,
+	helmChart *sourcev1.HelmChart) (types.NamespacedName, error) {
+	namespacedName := types.NamespacedName{
+		Namespace: helmChart.GetNamespace(),
+		Name:      helmChart.GetName(),
+	}
+
+	var existing sourcev1.HelmChart
+	err := kubeClient.Get(ctx, namespacedName, &existing)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if err := kubeClient.Create(ctx, helmChart); err != nil {
+				return namespacedName, err
+			} else {
+				logger.Successf("source created")
+				return namespacedName, nil
+			}
+		}
+		return namespacedName, err
+	}
+
+	existing.Labels = helmChart.Labels
+	existing.Spec = helmChart.Spec
+	if err := kubeClient.Update(ctx, &existing); err != nil {
+		return namespacedName, err
+	}
+	helmChart = &existing
+	logger.Successf("source updated")
+	return namespacedName, nil
+}
+
+func isHelmChartReady(ctx context.Context, kubeClient client.Client,
+	namespacedName types.NamespacedName, helmChart *sourcev1.HelmChart) wait.ConditionFunc {
+	return func() (bool, error) {
+		err := kubeClient.Get(ctx, namespacedName, helmChart)
+		if err != nil {
+			return false, err
+		}
+
+		// Confirm the state we are observing is for the current generation
+		if helmChart.Generation != helmChart.Status.ObservedGeneration {
+			return false, nil
+		}
+
+		if c := apimeta.FindStatusCondition(helmChart.Status.Conditions, meta.ReadyCondition); c != nil {
+			switch c.Status {
+			case metav1.ConditionTrue:
+				return true, nil
+			case metav1.ConditionFalse:
+				return false, fmt.Errorf(c.Message)
+			}
+		}
+		return false, nil
+	}
+}
@@ -17,12 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/elliptic"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/fluxcd/pkg/apis/meta"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
 	"github.com/manifoldco/promptui"
@@ -56,6 +59,9 @@ type sourceGitFlags struct {
 	caFile            string
 	privateKeyFile    string
 	recurseSubmodules bool
+	verifyMode        flags.GitVerificationMode
+	verifySecretRef   string
+	verifyKeysFiles   []string
 	silent            bool
 }
 
@@ -110,6 +116,13 @@ For private Git repositories, the basic authentication credentials are stored in
     --private-key-file=./private.key \
     --password=<password>
 
+  # Create a source that verifies the OpenPGP signature of the HEAD commit
+  flux create source git podinfo \
+    --url=https://github.com/stefanprodan/podinfo \
+    --branch=master \
+    --verify-mode=head \
+    --verify-keys-file=./author.asc
+
   # Create a source for a Git repository using basic authentication
   flux create source git podinfo \
     --url=https://github.com/stefanprodan/podinfo \
@@ -136,6 +149,11 @@ func init() {
 	createSourceGitCmd.Flags().StringVar(&sourceGitArgs.privateKeyFile, "private-key-file", "", "path to a passwordless private key file used for authenticating to the Git SSH server")
 	createSourceGitCmd.Flags().BoolVar(&sourceGitArgs.recurseSubmodules, "recurse-submodules", false,
 		"when enabled, configures the GitRepository source to initialize and include Git submodules in the artifact it produces")
+	createSourceGitCmd.Flags().Var(&sourceGitArgs.verifyMode, "verify-mode", sourceGitArgs.verifyMode.Description())
+	createSourceGitCmd.Flags().StringVar(&sourceGitArgs.verifySecretRef, "verify-secret-ref", "",
+		"the name of the secret containing the OpenPGP public keys of the trusted Git authors")
+	createSourceGitCmd.Flags().StringSliceVar(&sourceGitArgs.verifyKeysFiles, "verify-keys-file", nil,
+		"list of paths to armored OpenPGP public keyrings, the keys are stored in the verification secret")
 	createSourceGitCmd.Flags().BoolVarP(&sourceGitArgs.silent, "silent", "s", false, "assumes the deploy key is already setup, skips confirmation")
 
 	createSourceCmd.AddCommand(createSourceGitCmd)
@@ -179,6 +197,10 @@ func createSourceGitCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("recurse submodules requires --git-implementation=%s", sourcev1.GoGitImplementation)
 	}
 
+	if len(sourceGitArgs.verifyKeysFiles) > 0 && createArgs.export {
+		return fmt.Errorf("--verify-keys-file can't be used when exporting, create the secret and use --verify-secret-ref instead")
+	}
+
 	tmpDir, err := os.MkdirTemp("", name)
 	if err != nil {
 		return err
@@ -210,6 +232,24 @@ func createSourceGitCmdRun(cmd *cobra.Command, args []string) error {
 		gitRepository.Spec.Timeout = &metav1.Duration{Duration: createSourceArgs.fetchTimeout}
 	}
 
+	verifySecretName := sourceGitArgs.verifySecretRef
+	if len(sourceGitArgs.verifyKeysFiles) > 0 && verifySecretName == "" {
+		verifySecretName = fmt.Sprintf("%s-gpg-keys", name)
+	}
+	if sourceGitArgs.verifyMode != "" || verifySecretName != "" {
+		if verifySecretName == "" {
+			return fmt.Errorf("--verify-secret-ref or --verify-keys-file is required with --verify-mode")
+		}
+		mode := sourceGitArgs.verifyMode.String()
+		if mode == "" {
+			mode = "head"
+		}
+		gitRepository.Spec.Verification = &sourcev1.GitRepositoryVerification{
+			Mode:      mode,
+			SecretRef: meta.LocalObjectReference{Name: verifySecretName},
+		}
+	}
+
 	if sourceGitArgs.gitImplementation != "" {
 		gitRepository.Spec.GitImplementation = sourceGitArgs.gitImplementation.String()
 	}
@@ -232,15 +272,8 @@ func createSourceGitCmdRun(cmd *cobra.Command, args []string) error {
 		return printExport(exportGit(&gitRepository))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
-	defer cancel()
-
-	kubeClient, err := utils.KubeClient(kubeconfigArgs)
-	if err != nil {
-		return err
-	}
-
 	logger.Generatef("generating GitRepository source")
+	var credentials *corev1.Secret
 	if sourceGitArgs.secretRef == "" {
 		secretOpts := sourcesecret.Options{
 			Name:         name,
@@ -288,17 +321,60 @@ func createSourceGitCmdRun(cmd *cobra.Command, args []string) error {
 					}
 				}
 			}
-			logger.Actionf("applying secret with repository credentials")
-			if err := upsertSecret(ctx, kubeClient, s); err != nil {
-				return err
-			}
+			credentials = &s
 			gitRepository.Spec.SecretRef = &meta.LocalObjectReference{
 				Name: s.Name,
 			}
-			logger.Successf("authentication configured")
 		}
 	}
 
+	var verificationKeys *corev1.Secret
+	if len(sourceGitArgs.verifyKeysFiles) > 0 {
+		secret, err := makeVerificationSecret(verifySecretName, *kubeconfigArgs.Namespace, sourceGitArgs.verifyKeysFiles)
+		if err != nil {
+			return err
+		}
+		verificationKeys = &secret
+	}
+
+	if err := confirmCreate(func() error {
+		for _, secret := range []*corev1.Secret{credentials, verificationKeys} {
+			if secret == nil {
+				continue
+			}
+			if err := printSecret(*secret); err != nil {
+				return err
+			}
+		}
+		return printExport(exportGit(&gitRepository))
+	}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	if credentials != nil {
+		logger.Actionf("applying secret with repository credentials")
+		if err := upsertSecret(ctx, kubeClient, *credentials); err != nil {
+			return err
+		}
+		logger.Successf("authentication configured")
+	}
+
+	if verificationKeys != nil {
+		logger.Actionf("applying secret with OpenPGP public keys")
+		if err := upsertSecret(ctx, kubeClient, *verificationKeys); err != nil {
+			return err
+		}
+		logger.Successf("signature verification configured")
+	}
+
 	logger.Actionf("applying GitRepository source")
 	namespacedName, err := upsertGitRepository(ctx, kubeClient, &gitRepository)
 	if err != nil {
@@ -306,7 +382,7 @@ func createSourceGitCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for GitRepository source reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, &gitRepository,
 		isGitRepositoryReady(ctx, kubeClient, namespacedName, &gitRepository)); err != nil {
 		return err
 	}
@@ -319,6 +395,29 @@ func createSourceGitCmdRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// makeVerificationSecret returns a secret containing the armored OpenPGP
+// keyrings read from the given files, each file is stored under its base name.
+func makeVerificationSecret(name, namespace string, keysFiles []string) (corev1.Secret, error) {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		StringData: map[string]string{},
+	}
+	for _, keysFile := range keysFiles {
+		data, err := os.ReadFile(keysFile)
+		if err != nil {
+			return secret, fmt.Errorf("unable to read keys file: %w", err)
+		}
+		if _, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data)); err != nil {
+			return secret, fmt.Errorf("invalid OpenPGP keyring '%s': %w", keysFile, err)
+		}
+		secret.StringData[filepath.Base(keysFile)] = string(data)
+	}
+	return secret, nil
+}
+
 func upsertGitRepository(ctx context.Context, kubeClient client.Client,
 	gitRepository *sourcev1.GitRepository) (types.NamespacedName, error) {
 	namespacedName := types.NamespacedName{
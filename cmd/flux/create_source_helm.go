@@ -144,15 +144,8 @@ func createSourceHelmCmdRun(cmd *cobra.Command, args []string) error {
 		return printExport(exportHelmRepository(helmRepository))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
-	defer cancel()
-
-	kubeClient, err := utils.KubeClient(kubeconfigArgs)
-	if err != nil {
-		return err
-	}
-
 	logger.Generatef("generating HelmRepository source")
+	var credentials *corev1.Secret
 	if sourceHelmArgs.secretRef == "" {
 		secretName := fmt.Sprintf("helm-%s", name)
 		secretOpts := sourcesecret.Options{
@@ -174,18 +167,41 @@ func createSourceHelmCmdRun(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		if len(s.StringData) > 0 {
-			logger.Actionf("applying secret with repository credentials")
-			if err := upsertSecret(ctx, kubeClient, s); err != nil {
-				return err
-			}
+			credentials = &s
 			helmRepository.Spec.SecretRef = &meta.LocalObjectReference{
 				Name: secretName,
 			}
 			helmRepository.Spec.PassCredentials = sourceHelmArgs.passCredentials
-			logger.Successf("authentication configured")
 		}
 	}
 
+	if err := confirmCreate(func() error {
+		if credentials != nil {
+			if err := printSecret(*credentials); err != nil {
+				return err
+			}
+		}
+		return printExport(exportHelmRepository(helmRepository))
+	}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	if credentials != nil {
+		logger.Actionf("applying secret with repository credentials")
+		if err := upsertSecret(ctx, kubeClient, *credentials); err != nil {
+			return err
+		}
+		logger.Successf("authentication configured")
+	}
+
 	logger.Actionf("applying HelmRepository source")
 	namespacedName, err := upsertHelmRepository(ctx, kubeClient, helmRepository)
 	if err != nil {
@@ -193,7 +209,7 @@ func createSourceHelmCmdRun(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Waitingf("waiting for HelmRepository source reconciliation")
-	if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+	if err := waitForObject(ctx, kubeClient, namespacedName, helmRepository,
 		isHelmRepositoryReady(ctx, kubeClient, namespacedName, helmRepository)); err != nil {
 		return err
 	}
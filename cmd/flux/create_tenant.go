@@ -17,25 +17,33 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/fluxcd/flux2/internal/utils"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 )
 
 var createTenantCmd = &cobra.Command{
-	Use:   "tenant",
+	Use:   "tenant [name]",
 	Short: "Create or update a tenant",
 	Long: `The create tenant command generates namespaces, service accounts and role bindings to limit the
 reconcilers scope to the tenant namespaces.`,
@@ -44,6 +52,29 @@ reconcilers scope to the tenant namespaces.`,
     --with-namespace=frontend \
     --label=environment=dev
 
+  # Create a tenant with a resource quota preset and default container limits
+  flux create tenant dev-team \
+    --with-namespace=frontend \
+    --with-resource-quota=small \
+    --with-limit-range=default
+
+  # Create a tenant with a resource quota read from a ResourceQuota manifest
+  flux create tenant dev-team \
+    --with-namespace=frontend \
+    --with-resource-quota=./quota.yaml
+
+  # Create a tenant with namespaces isolated from the rest of the cluster
+  flux create tenant dev-team \
+    --with-namespace=frontend \
+    --with-network-policy=isolated
+
+  # Create a tenant with a service account that can pull images from a private registry
+  # and impersonate a cloud identity
+  flux create tenant dev-team \
+    --with-namespace=frontend \
+    --image-pull-secret=regcred \
+    --sa-annotation=eks.amazonaws.com/role-arn=arn:aws:iam::111122223333:role/dev-team
+
   # Generate tenant namespaces and role bindings in YAML format
   flux create tenant dev-team \
     --with-namespace=frontend \
@@ -57,8 +88,13 @@ const (
 )
 
 type tenantFlags struct {
-	namespaces  []string
-	clusterRole string
+	namespaces    []string
+	clusterRole   string
+	resourceQuota string
+	limitRange    string
+	networkPolicy string
+	pullSecrets   []string
+	saAnnotations []string
 }
 
 var tenantArgs tenantFlags
@@ -66,6 +102,17 @@ var tenantArgs tenantFlags
 func init() {
 	createTenantCmd.Flags().StringSliceVar(&tenantArgs.namespaces, "with-namespace", nil, "namespace belonging to this tenant")
 	createTenantCmd.Flags().StringVar(&tenantArgs.clusterRole, "cluster-role", "cluster-admin", "cluster role of the tenant role binding")
+	createTenantCmd.Flags().StringVar(&tenantArgs.resourceQuota, "with-resource-quota", "",
+		"resource quota of the tenant namespaces, can be a preset (small, medium, large) or the path to a ResourceQuota manifest")
+	createTenantCmd.Flags().StringVar(&tenantArgs.limitRange, "with-limit-range", "",
+		"limit range of the tenant namespaces, can be a preset (default) or the path to a LimitRange manifest")
+	createTenantCmd.Flags().StringVar(&tenantArgs.networkPolicy, "with-network-policy", "",
+		"network policies of the tenant namespaces, can be 'isolated' to deny the traffic from and to other namespaces "+
+			"except DNS, or the path to a file containing NetworkPolicy manifests")
+	createTenantCmd.Flags().StringSliceVar(&tenantArgs.pullSecrets, "image-pull-secret", nil,
+		"name of a secret in the tenant namespaces referenced by the service account for pulling images, also accepts comma-separated values")
+	createTenantCmd.Flags().StringArrayVar(&tenantArgs.saAnnotations, "sa-annotation", nil,
+		"annotation of the tenant service account in the format 'key=value'")
 	createCmd.AddCommand(createTenantCmd)
 }
 
@@ -86,9 +133,54 @@ func createTenantCmdRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("with-namespace is required")
 	}
 
+	var quotaSpec *corev1.ResourceQuotaSpec
+	if tenantArgs.resourceQuota != "" {
+		spec, err := tenantResourceQuotaSpec(tenantArgs.resourceQuota)
+		if err != nil {
+			return err
+		}
+		quotaSpec = &spec
+	}
+
+	var limitRangeSpec *corev1.LimitRangeSpec
+	if tenantArgs.limitRange != "" {
+		spec, err := tenantLimitRangeSpec(tenantArgs.limitRange)
+		if err != nil {
+			return err
+		}
+		limitRangeSpec = &spec
+	}
+
+	saAnnotations, err := parseTenantAnnotations(tenantArgs.saAnnotations)
+	if err != nil {
+		return err
+	}
+
+	var pullSecrets []corev1.LocalObjectReference
+	for _, name := range tenantArgs.pullSecrets {
+		if err := validation.IsDNS1123Subdomain(name); len(err) > 0 {
+			return fmt.Errorf("invalid image pull secret name '%s': %v", name, err)
+		}
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: name})
+	}
+
+	var networkPolicies []networkingv1.NetworkPolicy
+	switch tenantArgs.networkPolicy {
+	case "":
+	case tenantIsolatedNetworkPolicy:
+		networkPolicies = tenantIsolationPolicies(tenant)
+	default:
+		p, err := readTenantNetworkPolicies(tenantArgs.networkPolicy)
+		if err != nil {
+			return err
+		}
+		networkPolicies = p
+	}
+
 	var namespaces []corev1.Namespace
 	var accounts []corev1.ServiceAccount
 	var roleBindings []rbacv1.RoleBinding
+	var policies []client.Object
 
 	for _, ns := range tenantArgs.namespaces {
 		if err := validation.IsQualifiedName(ns); len(err) > 0 {
@@ -112,10 +204,12 @@ func createTenantCmdRun(cmd *cobra.Command, args []string) error {
 
 		account := corev1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      tenant,
-				Namespace: ns,
-				Labels:    objLabels,
+				Name:        tenant,
+				Namespace:   ns,
+				Labels:      objLabels,
+				Annotations: saAnnotations,
 			},
+			ImagePullSecrets: pullSecrets,
 		}
 
 		accounts = append(accounts, account)
@@ -145,17 +239,75 @@ func createTenantCmdRun(cmd *cobra.Command, args []string) error {
 			},
 		}
 		roleBindings = append(roleBindings, roleBinding)
+
+		if quotaSpec != nil {
+			policies = append(policies, &corev1.ResourceQuota{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "ResourceQuota",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tenant,
+					Namespace: ns,
+					Labels:    objLabels,
+				},
+				Spec: *quotaSpec,
+			})
+		}
+
+		if limitRangeSpec != nil {
+			policies = append(policies, &corev1.LimitRange{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "LimitRange",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tenant,
+					Namespace: ns,
+					Labels:    objLabels,
+				},
+				Spec: *limitRangeSpec,
+			})
+		}
+
+		for _, networkPolicy := range networkPolicies {
+			policy := networkPolicy.DeepCopy()
+			policy.Namespace = ns
+			if policy.Labels == nil {
+				policy.Labels = map[string]string{}
+			}
+			for k, v := range objLabels {
+				policy.Labels[k] = v
+			}
+			policies = append(policies, policy)
+		}
 	}
 
-	if createArgs.export {
+	printTenant := func() error {
 		for i := range tenantArgs.namespaces {
 			if err := exportTenant(namespaces[i], accounts[i], roleBindings[i]); err != nil {
 				return err
 			}
 		}
+		for _, policy := range policies {
+			data, err := yaml.Marshal(policy)
+			if err != nil {
+				return err
+			}
+			fmt.Println("---")
+			fmt.Println(resourceToString(data))
+		}
 		return nil
 	}
 
+	if createArgs.export {
+		return printTenant()
+	}
+
+	if err := confirmCreate(printTenant); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
 	defer cancel()
 
@@ -181,6 +333,14 @@ func createTenantCmdRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	for _, policy := range policies {
+		logger.Actionf("applying %s %s/%s", strings.ToLower(policy.GetObjectKind().GroupVersionKind().Kind),
+			policy.GetNamespace(), policy.GetName())
+		if err := upsertTenantObject(ctx, kubeClient, policy); err != nil {
+			return err
+		}
+	}
+
 	logger.Successf("tenant setup completed")
 	return nil
 }
@@ -233,8 +393,17 @@ func upsertServiceAccount(ctx context.Context, kubeClient client.Client, account
 		return err
 	}
 
-	if !equality.Semantic.DeepDerivative(account.Labels, existing.Labels) {
+	if !equality.Semantic.DeepDerivative(account.Labels, existing.Labels) ||
+		!equality.Semantic.DeepDerivative(account.Annotations, existing.Annotations) ||
+		!equality.Semantic.DeepDerivative(account.ImagePullSecrets, existing.ImagePullSecrets) {
 		existing.Labels = account.Labels
+		if len(account.Annotations) > 0 && existing.Annotations == nil {
+			existing.Annotations = map[string]string{}
+		}
+		for k, v := range account.Annotations {
+			existing.Annotations[k] = v
+		}
+		existing.ImagePullSecrets = account.ImagePullSecrets
 		if err := kubeClient.Update(ctx, &existing); err != nil {
 			return err
 		}
@@ -276,6 +445,203 @@ func upsertRoleBinding(ctx context.Context, kubeClient client.Client, roleBindin
 	return nil
 }
 
+// parseTenantAnnotations validates the given 'key=value' annotations.
+func parseTenantAnnotations(annotations []string) (map[string]string, error) {
+	if len(annotations) == 0 {
+		return nil, nil
+	}
+
+	result := make(map[string]string)
+	for _, annotation := range annotations {
+		parts := strings.SplitN(annotation, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid annotation format '%s', must be key=value", annotation)
+		}
+
+		if errors := validation.IsQualifiedName(parts[0]); len(errors) > 0 {
+			return nil, fmt.Errorf("invalid annotation '%s': %v", parts[0], errors)
+		}
+
+		result[parts[0]] = parts[1]
+	}
+
+	return result, nil
+}
+
+// upsertTenantObject creates the given object or replaces the existing one.
+func upsertTenantObject(ctx context.Context, kubeClient client.Client, obj client.Object) error {
+	existing := obj.DeepCopyObject().(client.Object)
+	err := kubeClient.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return kubeClient.Create(ctx, obj)
+		}
+		return err
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return kubeClient.Update(ctx, obj)
+}
+
+var tenantResourceQuotaPresets = map[string]corev1.ResourceQuotaSpec{
+	"small":  tenantResourceQuota("2", "4Gi", "4", "8Gi", "20"),
+	"medium": tenantResourceQuota("8", "16Gi", "16", "32Gi", "100"),
+	"large":  tenantResourceQuota("32", "64Gi", "64", "128Gi", "500"),
+}
+
+var tenantLimitRangePresets = map[string]corev1.LimitRangeSpec{
+	"default": {
+		Limits: []corev1.LimitRangeItem{
+			{
+				Type: corev1.LimitTypeContainer,
+				Default: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("512Mi"),
+				},
+				DefaultRequest: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
+		},
+	},
+}
+
+func tenantResourceQuota(requestsCPU, requestsMemory, limitsCPU, limitsMemory, pods string) corev1.ResourceQuotaSpec {
+	return corev1.ResourceQuotaSpec{
+		Hard: corev1.ResourceList{
+			corev1.ResourceRequestsCPU:    resource.MustParse(requestsCPU),
+			corev1.ResourceRequestsMemory: resource.MustParse(requestsMemory),
+			corev1.ResourceLimitsCPU:      resource.MustParse(limitsCPU),
+			corev1.ResourceLimitsMemory:   resource.MustParse(limitsMemory),
+			corev1.ResourcePods:           resource.MustParse(pods),
+		},
+	}
+}
+
+// tenantResourceQuotaSpec returns the spec of the given preset, or the spec
+// of the ResourceQuota read from the given file.
+func tenantResourceQuotaSpec(presetOrFile string) (corev1.ResourceQuotaSpec, error) {
+	if spec, ok := tenantResourceQuotaPresets[presetOrFile]; ok {
+		return spec, nil
+	}
+
+	var quota corev1.ResourceQuota
+	if err := readTenantObject(presetOrFile, "ResourceQuota", &quota); err != nil {
+		return corev1.ResourceQuotaSpec{}, err
+	}
+	return quota.Spec, nil
+}
+
+// tenantLimitRangeSpec returns the spec of the given preset, or the spec
+// of the LimitRange read from the given file.
+func tenantLimitRangeSpec(presetOrFile string) (corev1.LimitRangeSpec, error) {
+	if spec, ok := tenantLimitRangePresets[presetOrFile]; ok {
+		return spec, nil
+	}
+
+	var limitRange corev1.LimitRange
+	if err := readTenantObject(presetOrFile, "LimitRange", &limitRange); err != nil {
+		return corev1.LimitRangeSpec{}, err
+	}
+	return limitRange.Spec, nil
+}
+
+const tenantIsolatedNetworkPolicy = "isolated"
+
+// tenantIsolationPolicies returns the network policies denying all traffic
+// except within the namespace and to the cluster DNS.
+func tenantIsolationPolicies(tenant string) []networkingv1.NetworkPolicy {
+	typeMeta := metav1.TypeMeta{
+		APIVersion: "networking.k8s.io/v1",
+		Kind:       "NetworkPolicy",
+	}
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+	dns := intstr.FromInt(53)
+	return []networkingv1.NetworkPolicy{
+		{
+			TypeMeta:   typeMeta,
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-deny-all", tenant)},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			},
+		},
+		{
+			TypeMeta:   typeMeta,
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-allow-same-namespace", tenant)},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{
+					{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+				},
+				Egress: []networkingv1.NetworkPolicyEgressRule{
+					{To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+					{
+						To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+						Ports: []networkingv1.NetworkPolicyPort{
+							{Protocol: &udp, Port: &dns},
+							{Protocol: &tcp, Port: &dns},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// readTenantNetworkPolicies reads the NetworkPolicy manifests from the given
+// multi-document YAML file.
+func readTenantNetworkPolicies(path string) ([]networkingv1.NetworkPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read NetworkPolicy manifests: %w", err)
+	}
+
+	var policies []networkingv1.NetworkPolicy
+	reader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("unable to read NetworkPolicy manifests '%s': %w", path, err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		var policy networkingv1.NetworkPolicy
+		if err := yaml.UnmarshalStrict(doc, &policy); err != nil {
+			return nil, fmt.Errorf("invalid NetworkPolicy manifest in '%s': %w", path, err)
+		}
+		if policy.Kind != "NetworkPolicy" || policy.Name == "" {
+			return nil, fmt.Errorf("invalid NetworkPolicy manifest in '%s': kind must be NetworkPolicy and name is required", path)
+		}
+		policies = append(policies, policy)
+	}
+
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no NetworkPolicy found in '%s'", path)
+	}
+	return policies, nil
+}
+
+func readTenantObject(path, kind string, obj client.Object) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s manifest: %w", kind, err)
+	}
+	if err := yaml.UnmarshalStrict(data, obj); err != nil {
+		return fmt.Errorf("invalid %s manifest '%s': %w", kind, path, err)
+	}
+	if k := obj.GetObjectKind().GroupVersionKind().Kind; k != kind {
+		return fmt.Errorf("invalid %s manifest '%s': unexpected kind '%s'", kind, path, k)
+	}
+	return nil
+}
+
 func exportTenant(namespace corev1.Namespace, account corev1.ServiceAccount, roleBinding rbacv1.RoleBinding) error {
 	namespace.TypeMeta = metav1.TypeMeta{
 		APIVersion: "v1",
@@ -0,0 +1,138 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestTenantResourceQuotaSpec(t *testing.T) {
+	cases := []struct {
+		name       string
+		value      string
+		expectPods string
+		expectErr  bool
+	}{
+		{"preset", "small", "20", false},
+		{"manifest", "testdata/tenant/quota.yaml", "10", false},
+		{"unexpected kind", "testdata/tenant/limit-range.yaml", "", true},
+		{"unknown preset", "huge", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := tenantResourceQuotaSpec(tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if tc.expectErr {
+				return
+			}
+			pods := spec.Hard[corev1.ResourcePods]
+			if pods.Cmp(resource.MustParse(tc.expectPods)) != 0 {
+				t.Errorf("expected %s pods, got %s", tc.expectPods, pods.String())
+			}
+		})
+	}
+}
+
+func TestTenantLimitRangeSpec(t *testing.T) {
+	cases := []struct {
+		name      string
+		value     string
+		expectErr bool
+	}{
+		{"preset", "default", false},
+		{"manifest", "testdata/tenant/limit-range.yaml", false},
+		{"unexpected kind", "testdata/tenant/quota.yaml", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := tenantLimitRangeSpec(tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && len(spec.Limits) != 1 {
+				t.Errorf("expected one limit, got %d", len(spec.Limits))
+			}
+		})
+	}
+}
+
+func TestReadTenantNetworkPolicies(t *testing.T) {
+	cases := []struct {
+		name        string
+		file        string
+		expectNames []string
+		expectErr   bool
+	}{
+		{"manifests", "testdata/tenant/network-policies.yaml", []string{"deny-ingress", "allow-ingress-controller"}, false},
+		{"unexpected kind", "testdata/tenant/quota.yaml", nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policies, err := readTenantNetworkPolicies(tc.file)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			var names []string
+			for _, p := range policies {
+				names = append(names, p.Name)
+			}
+			if diff := cmp.Diff(tc.expectNames, names); diff != "" {
+				t.Errorf("policies mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseTenantAnnotations(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations []string
+		expected    map[string]string
+		expectErr   bool
+	}{
+		{"none", nil, nil, false},
+		{
+			"workload identity",
+			[]string{"iam.gke.io/gcp-service-account=dev@project.iam.gserviceaccount.com", "owner=dev=team"},
+			map[string]string{"iam.gke.io/gcp-service-account": "dev@project.iam.gserviceaccount.com", "owner": "dev=team"},
+			false,
+		},
+		{"invalid format", []string{"owner"}, nil, true},
+		{"invalid key", []string{"-owner=dev"}, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations, err := parseTenantAnnotations(tc.annotations)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if diff := cmp.Diff(tc.expected, annotations); diff != "" {
+				t.Errorf("annotations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
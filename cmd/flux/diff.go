@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +28,23 @@ var diffCmd = &cobra.Command{
 	Long:  "The diff command is used to do a server-side dry-run on flux resources, then prints the diff.",
 }
 
+// externalDiffEnv is the environment variable that sets the default of
+// the --external-diff flags.
+const externalDiffEnv = "FLUX_EXTERNAL_DIFF"
+
 func init() {
+	// usage errors exit with 2 like the diff errors, 1 is reserved for drift
+	diffCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &RequestError{StatusCode: 2, Err: err}
+	})
 	rootCmd.AddCommand(diffCmd)
 }
+
+// externalDiffCommand returns the command given with --external-diff, or
+// the one set in the FLUX_EXTERNAL_DIFF environment variable.
+func externalDiffCommand(command string) string {
+	if command != "" {
+		return command
+	}
+	return os.Getenv(externalDiffEnv)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fluxcd/flux2/internal/build"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+)
+
+var diffHrCmd = &cobra.Command{
+	Use:     "helmrelease",
+	Aliases: []string{"hr"},
+	Short:   "Diff HelmRelease",
+	Long: `The diff command renders the HelmRelease like the build command, then it performs a server-side dry-run
+of the rendered objects and prints the diff.
+The objects of the last release that are no longer rendered are printed as deleted, or orphaned when
+they're annotated with helm.sh/resource-policy: keep. The Helm hooks are skipped.
+With --external-diff or FLUX_EXTERNAL_DIFF, the command is run with the live and merged YAML files of each
+changed object as the last arguments to print their diff, it should exit with 1 when the files differ.
+Exit status: 0 No differences were found. 1 Differences were found. >1 diff failed with an error.`,
+	Example: `# Preview the changes of a HelmRelease as they would be applied on the cluster
+flux diff helmrelease podinfo
+
+# Preview the changes made by local values
+flux diff helmrelease podinfo --values ./values-dev.yaml
+
+# Preview the changes made by a local chart
+flux diff helmrelease podinfo --chart-path ./charts/podinfo
+
+# Print the changes with an external diff tool
+flux diff helmrelease podinfo --external-diff="diff -u"`,
+	ValidArgsFunction: resourceNamesCompletionFunc(helmv2.GroupVersion.WithKind(helmv2.HelmReleaseKind)),
+	RunE:              diffHrCmdRun,
+}
+
+type diffHrFlags struct {
+	chartPath    string
+	valuesFiles  []string
+	externalDiff string
+}
+
+var diffHrArgs diffHrFlags
+
+func init() {
+	diffHrCmd.Flags().StringVar(&diffHrArgs.chartPath, "chart-path", "",
+		"path to a local chart directory or archive, used instead of the chart of the HelmRelease")
+	diffHrCmd.Flags().StringSliceVarP(&diffHrArgs.valuesFiles, "values", "f", nil,
+		"local values files merged on top of the values of the HelmRelease, can be specified multiple times")
+	diffHrCmd.Flags().StringVar(&diffHrArgs.externalDiff, "external-diff", "",
+		"command printing the diff of the live and merged files of the changed objects, defaults to $FLUX_EXTERNAL_DIFF")
+	diffCmd.AddCommand(diffHrCmd)
+}
+
+func diffHrCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("%s name is required", helmReleaseType.humanKind)}
+	}
+	name := args[0]
+
+	builder, err := build.NewHelmReleaseBuilder(kubeconfigArgs, name,
+		build.WithChartPath(diffHrArgs.chartPath),
+		build.WithValuesFiles(diffHrArgs.valuesFiles),
+		build.WithHelmReleaseTimeout(rootArgs.timeout),
+		build.WithHelmReleaseExternalDiff(externalDiffCommand(diffHrArgs.externalDiff)))
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	output, hasChanged, err := builder.Diff()
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	cmd.Print(output)
+
+	if hasChanged {
+		return &RequestError{StatusCode: 1, Err: fmt.Errorf("identified at least one change, exiting with non-zero exit code")}
+	}
+	return nil
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -32,39 +34,108 @@ var diffKsCmd = &cobra.Command{
 	Aliases: []string{"ks"},
 	Short:   "Diff Kustomization",
 	Long: `The diff command does a build, then it performs a server-side dry-run and prints the diff.
-Exit status: 0 No differences were found. 1 Differences were found. >1 diff failed with an error.`,
+With --from-source the build uses the latest artifact of the Kustomization source instead of a local directory.
+The changed objects are spilled to disk until the diff is printed, and --max-memory aborts the diff
+of very large kustomizations before the process runs out of memory.
+The objects annotated with kustomize.toolkit.fluxcd.io/reconcile: disabled are skipped, and the fields
+matching --ignore-paths are left out of the diff.
+The objects of the Kustomization inventory that are no longer in the build are reported as deleted when
+garbage collection is enabled. With --detect-orphans they're reported as orphaned when it's disabled.
+With --output=json the objects that would be created, changed or deleted are printed as a JSON list,
+with the JSON patch from the cluster state for the changed objects.
+With --external-diff or FLUX_EXTERNAL_DIFF, the command is run with the live and merged YAML files of each
+changed object as the last arguments to print their diff, it should exit with 1 when the files differ.
+Exit status: 0 No differences were found. 1 Differences were found. 2 The diff failed with an error.`,
 	Example: `# Preview local changes as they were applied on the cluster
-flux diff kustomization my-app --path ./path/to/local/manifests`,
+flux diff kustomization my-app --path ./path/to/local/manifests
+
+# Preview the changes of the latest source artifact that are not applied yet
+flux diff kustomization my-app --from-source
+
+# Preview local changes without the fields set by the cluster
+flux diff kustomization my-app --path ./path/to/local/manifests \
+  --ignore-paths="webhooks.*.clientConfig.caBundle" \
+  --ignore-paths="spec.template.metadata.annotations.checksum/*"
+
+# Preview the objects left on the cluster by a Kustomization without garbage collection
+flux diff kustomization my-app --path ./path/to/local/manifests --detect-orphans
+
+# Print the changes with an external diff tool
+flux diff kustomization my-app --path ./path/to/local/manifests --external-diff="dyff between --omit-header"
+
+# Print the changes in JSON format
+flux diff kustomization my-app --path ./path/to/local/manifests -o json`,
 	ValidArgsFunction: resourceNamesCompletionFunc(kustomizev1.GroupVersion.WithKind(kustomizev1.KustomizationKind)),
 	RunE:              diffKsCmdRun,
 }
 
 type diffKsFlags struct {
-	path string
+	path          string
+	fromSource    bool
+	ignorePaths   []string
+	output        string
+	detectOrphans bool
+	maxMemory     string
+	externalDiff  string
 }
 
 var diffKsArgs diffKsFlags
 
 func init() {
 	diffKsCmd.Flags().StringVar(&diffKsArgs.path, "path", "", "Path to a local directory that matches the specified Kustomization.spec.path.)")
+	diffKsCmd.Flags().BoolVar(&diffKsArgs.fromSource, "from-source", false,
+		"build the manifests from the latest artifact of the Kustomization source instead of a local path")
+	diffKsCmd.Flags().StringSliceVar(&diffKsArgs.ignorePaths, "ignore-paths", nil,
+		"globs of the dot separated field paths left out of the diff, a '*' matches a field name or list index and a '**' matches any number of them")
+	diffKsCmd.Flags().BoolVar(&diffKsArgs.detectOrphans, "detect-orphans", false,
+		"report the objects of the Kustomization inventory that are no longer in the build, even when prune is disabled")
+	diffKsCmd.Flags().StringVar(&diffKsArgs.maxMemory, "max-memory", "",
+		"abort the diff when the memory usage exceeds this quantity, e.g. 512Mi")
+	diffKsCmd.Flags().StringVar(&diffKsArgs.externalDiff, "external-diff", "",
+		"command printing the diff of the live and merged files of the changed objects, defaults to $FLUX_EXTERNAL_DIFF")
+	diffKsCmd.Flags().StringVarP(&diffKsArgs.output, "output", "o", "",
+		"the format in which the diff should be printed, can be 'json'")
 	diffCmd.AddCommand(diffKsCmd)
 }
 
 func diffKsCmdRun(cmd *cobra.Command, args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("%s name is required", kustomizationType.humanKind)
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("%s name is required", kustomizationType.humanKind)}
+	}
+
+	switch diffKsArgs.output {
+	case "", "json":
+	default:
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("unsupported output format '%s', must be 'json'", diffKsArgs.output)}
+	}
+	if diffKsArgs.output == "json" && diffKsArgs.externalDiff != "" {
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("the --external-diff and --output=json flags are mutually exclusive")}
 	}
 	name := args[0]
 
-	if diffKsArgs.path == "" {
-		return &RequestError{StatusCode: 2, Err: fmt.Errorf("invalid resource path %q", diffKsArgs.path)}
+	maxMemory, err := parseMemoryLimit(diffKsArgs.maxMemory)
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
 	}
 
-	if fs, err := os.Stat(diffKsArgs.path); err != nil || !fs.IsDir() {
-		return &RequestError{StatusCode: 2, Err: fmt.Errorf("invalid resource path %q", diffKsArgs.path)}
+	if diffKsArgs.fromSource {
+		if diffKsArgs.path != "" {
+			return &RequestError{StatusCode: 2, Err: fmt.Errorf("the --path and --from-source flags are mutually exclusive")}
+		}
+	} else {
+		if diffKsArgs.path == "" {
+			return &RequestError{StatusCode: 2, Err: fmt.Errorf("invalid resource path %q", diffKsArgs.path)}
+		}
+
+		if fs, err := os.Stat(diffKsArgs.path); err != nil || !fs.IsDir() {
+			return &RequestError{StatusCode: 2, Err: fmt.Errorf("invalid resource path %q", diffKsArgs.path)}
+		}
 	}
 
-	builder, err := build.NewBuilder(kubeconfigArgs, name, diffKsArgs.path, build.WithTimeout(rootArgs.timeout))
+	builder, err := build.NewBuilder(kubeconfigArgs, name, diffKsArgs.path,
+		build.WithTimeout(rootArgs.timeout), build.WithFromSource(diffKsArgs.fromSource),
+		build.WithIgnorePaths(diffKsArgs.ignorePaths), build.WithDetectOrphans(diffKsArgs.detectOrphans),
+		build.WithExternalDiff(externalDiffCommand(diffKsArgs.externalDiff)))
 	if err != nil {
 		return &RequestError{StatusCode: 2, Err: err}
 	}
@@ -73,11 +144,16 @@ func diffKsCmdRun(cmd *cobra.Command, args []string) error {
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, os.Interrupt)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	memc := watchMemory(ctx, maxMemory)
+
 	errChan := make(chan error)
 	go func() {
-		output, hasChanged, err := builder.Diff()
+		output, hasChanged, err := diffKustomization(builder)
 		if err != nil {
 			errChan <- &RequestError{StatusCode: 2, Err: err}
+			return
 		}
 
 		cmd.Print(output)
@@ -92,7 +168,15 @@ func diffKsCmdRun(cmd *cobra.Command, args []string) error {
 	select {
 	case <-sigc:
 		fmt.Println("Build cancelled... exiting.")
-		return builder.Cancel()
+		if err := builder.Cancel(); err != nil {
+			return &RequestError{StatusCode: 2, Err: err}
+		}
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("diff cancelled")}
+	case err := <-memc:
+		if errc := builder.Cancel(); errc != nil {
+			return &RequestError{StatusCode: 2, Err: errc}
+		}
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("diff aborted: %w", err)}
 	case err := <-errChan:
 		if err != nil {
 			return err
@@ -102,3 +186,24 @@ func diffKsCmdRun(cmd *cobra.Command, args []string) error {
 	return nil
 
 }
+
+// diffKustomization returns the diff in the format given with --output, and
+// whether any object would be created, changed or deleted.
+func diffKustomization(builder *build.Builder) (string, bool, error) {
+	if diffKsArgs.output != "json" {
+		return builder.Diff()
+	}
+
+	diffs, err := builder.DiffObjects()
+	if err != nil {
+		return "", false, err
+	}
+	if diffs == nil {
+		diffs = []build.ObjectDiff{}
+	}
+	data, err := json.MarshalIndent(diffs, "", "  ")
+	if err != nil {
+		return "", false, err
+	}
+	return string(data) + "\n", len(diffs) > 0, nil
+}
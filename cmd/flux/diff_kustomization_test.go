@@ -61,6 +61,12 @@ func TestDiffKustomization(t *testing.T) {
 			objectFile: "./testdata/diff-kustomization/service.yaml",
 			assert:     assertGoldenFile("./testdata/diff-kustomization/diff-with-drifted-service.golden"),
 		},
+		{
+			name:       "diff with an ignored drifted field",
+			args:       "diff kustomization podinfo --path ./testdata/build-kustomization/podinfo --ignore-paths=spec.ports.*.port",
+			objectFile: "./testdata/diff-kustomization/service.yaml",
+			assert:     assertGoldenFile("./testdata/diff-kustomization/diff-with-ignored-service.golden"),
+		},
 		{
 			name:       "diff with a drifted secret object",
 			args:       "diff kustomization podinfo --path ./testdata/build-kustomization/podinfo",
@@ -0,0 +1,310 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/ssh/knownhosts"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var diffSourceGitCmd = &cobra.Command{
+	Use:   "git [name]",
+	Short: "Diff a GitRepository source with its branch head",
+	Long: `The diff source git command clones the branch of a GitRepository, then lists the commits
+and the paths changed between the revision applied on the cluster and the branch head,
+which will land on the next reconciliation. The credentials of the GitRepository secret are used for the clone.
+Exit status: 0 No commits are pending. 1 Commits are pending. 2 The diff failed with an error.`,
+	Example: `  # List the pending commits of a GitRepository
+  flux diff source git podinfo
+
+  # List the pending commits of the Flux system repository
+  flux diff source git flux-system -n flux-system`,
+	ValidArgsFunction: resourceNamesCompletionFunc(sourcev1.GroupVersion.WithKind(sourcev1.GitRepositoryKind)),
+	RunE:              diffSourceGitCmdRun,
+}
+
+// defaultGitBranch is the branch checked out by source-controller when the
+// GitRepository has no reference.
+const defaultGitBranch = "master"
+
+func init() {
+	diffSourceCmd.AddCommand(diffSourceGitCmd)
+}
+
+// pendingChanges are the commits and the changed paths between two revisions.
+type pendingChanges struct {
+	commits []*object.Commit
+	paths   []string
+}
+
+func diffSourceGitCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("GitRepository name is required")}
+	}
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	var repository sourcev1.GitRepository
+	namespacedName := types.NamespacedName{Namespace: *kubeconfigArgs.Namespace, Name: name}
+	if err := kubeClient.Get(ctx, namespacedName, &repository); err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	branch, err := gitRepositoryBranch(repository)
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+	artifact := repository.GetArtifact()
+	if artifact == nil {
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("GitRepository %s has no artifact", namespacedName)}
+	}
+	applied := artifact.Revision[strings.LastIndex(artifact.Revision, "/")+1:]
+
+	cloneOpts, err := gitRepositoryCloneOptions(ctx, kubeClient, repository, branch)
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	logger.Actionf("cloning branch %s from %s", branch, repository.Spec.URL)
+	repo, err := gogit.CloneContext(ctx, memory.NewStorage(), nil, cloneOpts)
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: fmt.Errorf("failed to clone %s: %w", repository.Spec.URL, err)}
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	changes, err := diffRevisions(repo, plumbing.NewHash(applied), head.Hash())
+	if err != nil {
+		return &RequestError{StatusCode: 2, Err: err}
+	}
+
+	if len(changes.commits) == 0 {
+		logger.Successf("revision %s/%s is up to date", branch, applied)
+		return nil
+	}
+
+	logger.Successf("%d commits pending from %s/%s to %s/%s", len(changes.commits), branch, applied, branch, head.Hash())
+	printPendingChanges(cmd.OutOrStdout(), changes)
+	return &RequestError{StatusCode: 1, Err: fmt.Errorf("identified at least one pending commit, exiting with non-zero exit code")}
+}
+
+// gitRepositoryBranch returns the branch tracked by the GitRepository.
+func gitRepositoryBranch(repository sourcev1.GitRepository) (string, error) {
+	ref := repository.Spec.Reference
+	if ref == nil {
+		return defaultGitBranch, nil
+	}
+	if ref.Tag != "" || ref.SemVer != "" || ref.Commit != "" {
+		return "", fmt.Errorf("GitRepository %s/%s doesn't track a branch, only branches can be compared",
+			repository.Namespace, repository.Name)
+	}
+	if ref.Branch == "" {
+		return defaultGitBranch, nil
+	}
+	return ref.Branch, nil
+}
+
+// gitRepositoryCloneOptions returns the options to clone the branch of the
+// GitRepository, with the credentials of its secret.
+func gitRepositoryCloneOptions(ctx context.Context, kubeClient client.Client, repository sourcev1.GitRepository,
+	branch string) (*gogit.CloneOptions, error) {
+	opts := &gogit.CloneOptions{
+		URL:           repository.Spec.URL,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+		NoCheckout:    true,
+		Tags:          gogit.NoTags,
+	}
+	if repository.Spec.SecretRef == nil {
+		return opts, nil
+	}
+
+	var secret corev1.Secret
+	namespacedName := types.NamespacedName{Namespace: repository.Namespace, Name: repository.Spec.SecretRef.Name}
+	if err := kubeClient.Get(ctx, namespacedName, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get the secret of GitRepository %s/%s: %w", repository.Namespace, repository.Name, err)
+	}
+
+	u, err := url.Parse(repository.Spec.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", repository.Spec.URL, err)
+	}
+	opts.Auth, err = gitAuthFromSecret(u, secret)
+	if err != nil {
+		return nil, err
+	}
+	opts.CABundle = secret.Data["caFile"]
+	return opts, nil
+}
+
+// gitAuthFromSecret returns the credentials for the URL scheme, that are
+// stored in the secret as generated by 'flux create secret git'.
+func gitAuthFromSecret(u *url.URL, secret corev1.Secret) (transport.AuthMethod, error) {
+	switch u.Scheme {
+	case "http", "https":
+		if _, ok := secret.Data["username"]; !ok {
+			return nil, nil
+		}
+		return &http.BasicAuth{
+			Username: string(secret.Data["username"]),
+			Password: string(secret.Data["password"]),
+		}, nil
+	case "ssh":
+		user := u.User.Username()
+		if user == "" {
+			user = "git"
+		}
+		auth, err := ssh.NewPublicKeys(user, secret.Data["identity"], string(secret.Data["password"]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity in secret %s: %w", secret.Name, err)
+		}
+		callback, err := knownhosts.New(secret.Data["known_hosts"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid known_hosts in secret %s: %w", secret.Name, err)
+		}
+		auth.HostKeyCallback = callback
+		return auth, nil
+	default:
+		return nil, fmt.Errorf("scheme %q is not supported", u.Scheme)
+	}
+}
+
+// diffRevisions returns the commits reachable from the head but not from the
+// applied revision, newest first like git log applied..head, and the paths
+// changed between the two trees.
+func diffRevisions(repo *gogit.Repository, applied, head plumbing.Hash) (*pendingChanges, error) {
+	changes := &pendingChanges{}
+	if applied == head {
+		return changes, nil
+	}
+
+	appliedCommit, err := repo.CommitObject(applied)
+	if err != nil {
+		return nil, fmt.Errorf("revision %s not found in the branch history: %w", applied, err)
+	}
+	headCommit, err := repo.CommitObject(head)
+	if err != nil {
+		return nil, err
+	}
+
+	isAncestor, err := appliedCommit.IsAncestor(headCommit)
+	if err != nil {
+		return nil, err
+	}
+	if !isAncestor {
+		return nil, fmt.Errorf("revision %s is not an ancestor of the branch head %s", applied, head)
+	}
+
+	// the commits merged from other branches may be older than the applied
+	// revision, so the history of the applied revision is excluded instead
+	// of stopping the walk when reaching it
+	appliedHistory := map[plumbing.Hash]bool{}
+	appliedIter, err := repo.Log(&gogit.LogOptions{From: applied})
+	if err != nil {
+		return nil, err
+	}
+	if err := appliedIter.ForEach(func(c *object.Commit) error {
+		appliedHistory[c.Hash] = true
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	iter, err := repo.Log(&gogit.LogOptions{From: head, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !appliedHistory[c.Hash] {
+			changes.commits = append(changes.commits, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	appliedTree, err := appliedCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	treeChanges, err := appliedTree.Diff(headTree)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range treeChanges {
+		action, err := change.Action()
+		if err != nil {
+			return nil, err
+		}
+		switch action {
+		case merkletrie.Insert:
+			changes.paths = append(changes.paths, "A "+change.To.Name)
+		case merkletrie.Delete:
+			changes.paths = append(changes.paths, "D "+change.From.Name)
+		default:
+			changes.paths = append(changes.paths, "M "+change.To.Name)
+		}
+	}
+	return changes, nil
+}
+
+func printPendingChanges(w io.Writer, changes *pendingChanges) {
+	fmt.Fprintln(w, "commits:")
+	for _, c := range changes.commits {
+		subject := strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
+		fmt.Fprintf(w, "  %s %s (%s)\n", c.Hash.String()[:7], subject, c.Author.Name)
+	}
+	fmt.Fprintln(w, "changed paths:")
+	for _, path := range changes.paths {
+		fmt.Fprintf(w, "  %s\n", path)
+	}
+}
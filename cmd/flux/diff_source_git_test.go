@@ -0,0 +1,186 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(message string, files map[string]string, removed ...string) plumbing.Hash {
+		for name, content := range files {
+			f, err := wt.Filesystem.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte(content))
+			f.Close()
+			if _, err := wt.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, name := range removed {
+			if _, err := wt.Remove(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, err := wt.Commit(message, &gogit.CommitOptions{
+			Author: &object.Signature{Name: "Flux", Email: "flux@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	applied := commit("Initial commit", map[string]string{"app.yaml": "v1", "old.yaml": "old"})
+	commit("Update app", map[string]string{"app.yaml": "v2"})
+	head := commit("Replace old\n\nwith new", map[string]string{"new.yaml": "new"}, "old.yaml")
+
+	changes, err := diffRevisions(repo, applied, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subjects []string
+	for _, c := range changes.commits {
+		subjects = append(subjects, c.Message)
+	}
+	if diff := cmp.Diff([]string{"Replace old\n\nwith new", "Update app"}, subjects); diff != "" {
+		t.Errorf("unexpected commits (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"M app.yaml", "A new.yaml", "D old.yaml"}, changes.paths); diff != "" {
+		t.Errorf("unexpected paths (-want +got):\n%s", diff)
+	}
+
+	changes, err = diffRevisions(repo, head, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.commits) != 0 || len(changes.paths) != 0 {
+		t.Errorf("expected no changes for the same revision, got %+v", changes)
+	}
+
+	if _, err := diffRevisions(repo, head, applied); err == nil {
+		t.Error("expected an error when the applied revision is not an ancestor of the head")
+	}
+
+	// a branch forked before the applied revision and merged after it
+	if err := wt.Checkout(&gogit.CheckoutOptions{Hash: head}); err != nil {
+		t.Fatal(err)
+	}
+	feature := commit("Add feature", map[string]string{"feature.yaml": "feature"})
+	if err := wt.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Master}); err != nil {
+		t.Fatal(err)
+	}
+	applied = commit("Update app again", map[string]string{"app.yaml": "v3"})
+	head = commit("Merge feature", map[string]string{"feature.yaml": "feature"})
+	mergeCommit, err := repo.CommitObject(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergeCommit.ParentHashes = append(mergeCommit.ParentHashes, feature)
+	obj := repo.Storer.NewEncodedObject()
+	if err := mergeCommit.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	head, err = repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err = diffRevisions(repo, applied, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subjects = nil
+	for _, c := range changes.commits {
+		subjects = append(subjects, c.Message)
+	}
+	sort.Strings(subjects)
+	if diff := cmp.Diff([]string{"Add feature", "Merge feature"}, subjects); diff != "" {
+		t.Errorf("unexpected commits (-want +got):\n%s", diff)
+	}
+}
+
+func TestGitRepositoryBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      *sourcev1.GitRepositoryRef
+		expected string
+		wantErr  bool
+	}{
+		{name: "no reference", expected: "master"},
+		{name: "branch", ref: &sourcev1.GitRepositoryRef{Branch: "main"}, expected: "main"},
+		{name: "tag", ref: &sourcev1.GitRepositoryRef{Tag: "v1.0.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var repository sourcev1.GitRepository
+			repository.Spec.Reference = tt.ref
+			branch, err := gitRepositoryBranch(repository)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("gitRepositoryBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if branch != tt.expected {
+				t.Errorf("gitRepositoryBranch() = %q, want %q", branch, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGitAuthFromSecret(t *testing.T) {
+	u, _ := url.Parse("https://github.com/stefanprodan/podinfo")
+	secret := corev1.Secret{Data: map[string][]byte{"username": []byte("git"), "password": []byte("token")}}
+	auth, err := gitAuthFromSecret(u, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&http.BasicAuth{Username: "git", Password: "token"}, auth); diff != "" {
+		t.Errorf("unexpected auth (-want +got):\n%s", diff)
+	}
+
+	u, _ = url.Parse("ftp://example.com/repo")
+	if _, err := gitAuthFromSecret(u, secret); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+}
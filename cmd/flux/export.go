@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/types"
@@ -36,13 +38,16 @@ var exportCmd = &cobra.Command{
 }
 
 type exportFlags struct {
-	all bool
+	all       bool
+	outputDir string
 }
 
 var exportArgs exportFlags
 
 func init() {
 	exportCmd.PersistentFlags().BoolVar(&exportArgs.all, "all", false, "select all resources")
+	exportCmd.PersistentFlags().StringVar(&exportArgs.outputDir, "output-dir", "",
+		"write each object to <output-dir>/<kind>/<namespace>/<name>.yaml instead of printing them")
 
 	rootCmd.AddCommand(exportCmd)
 }
@@ -86,11 +91,14 @@ func (export exportCommand) run(cmd *cobra.Command, args []string) error {
 		}
 
 		if export.list.len() == 0 {
+			if cmd.Use == "all" {
+				return nil
+			}
 			return fmt.Errorf("no objects found in %s namespace", *kubeconfigArgs.Namespace)
 		}
 
 		for i := 0; i < export.list.len(); i++ {
-			if err = printExport(export.list.exportItem(i)); err != nil {
+			if err = writeExport(export.list.exportItem(i)); err != nil {
 				return err
 			}
 		}
@@ -104,8 +112,46 @@ func (export exportCommand) run(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
-		return printExport(export.object.export())
+		return writeExport(export.object.export())
+	}
+	return nil
+}
+
+// exportedFiles records the paths written to the output directory.
+var exportedFiles []string
+
+// writeExport prints the exported object or writes it to the output
+// directory when one is set.
+func writeExport(export interface{}) error {
+	if exportArgs.outputDir == "" {
+		return printExport(export)
+	}
+
+	data, err := yaml.Marshal(export)
+	if err != nil {
+		return err
+	}
+	var object struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	dir := exportDir(object.Kind, object.Metadata.Namespace)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, object.Metadata.Name+".yaml")
+	if err := os.WriteFile(path, []byte("---\n"+resourceToString(data)), 0o644); err != nil {
+		return err
 	}
+	exportedFiles = append(exportedFiles, path)
+	logger.Successf("%s/%s/%s exported to %s", object.Kind, object.Metadata.Namespace, object.Metadata.Name, path)
 	return nil
 }
 
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	autov1 "github.com/fluxcd/image-automation-controller/api/v1beta1"
+	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+)
+
+var exportAllCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Export all resources in YAML format",
+	Long: `The export all command exports the sources, Kustomizations, HelmReleases, notification
+and image automation objects of a namespace in YAML format.
+The HelmCharts generated by the HelmReleases are not exported.
+With --as-kustomize and --group-by=namespace, the objects of each namespace are written to a
+base/<namespace> kustomization, which is included by an overlays/<namespace> kustomization where
+the namespace specific patches can be added. The top kustomization lists the overlays of all the
+namespaces exported to the output directory.`,
+	Example: `  # Export all resources of the flux-system namespace
+  flux export all --namespace=flux-system > flux-system.yaml
+
+  # Export all resources to a directory that can be applied with kustomize
+  flux export all --as-kustomize --output-dir=./clusters/my-cluster
+
+  # Export the resources of two namespaces to a base and an overlay per namespace
+  flux export all --namespace=flux-system --as-kustomize --group-by=namespace --output-dir=./clusters/my-cluster
+  flux export all --namespace=apps --as-kustomize --group-by=namespace --output-dir=./clusters/my-cluster`,
+	RunE: exportAllCmdRun,
+}
+
+// exportGroupByNamespace groups the exported objects into a base and an
+// overlay kustomization per namespace.
+const exportGroupByNamespace = "namespace"
+
+type exportAllFlags struct {
+	asKustomize bool
+	groupBy     string
+}
+
+var exportAllArgs exportAllFlags
+
+func init() {
+	exportAllCmd.Flags().BoolVar(&exportSourceWithCred, "with-credentials", false, "include credential secrets")
+	exportAllCmd.Flags().BoolVar(&exportAllArgs.asKustomize, "as-kustomize", false,
+		"generate a kustomization.yaml listing the exported objects in the output directory")
+	exportAllCmd.Flags().StringVar(&exportAllArgs.groupBy, "group-by", "",
+		"group the objects of the generated kustomization into a base and an overlay per namespace, the only supported value is 'namespace'")
+
+	exportCmd.AddCommand(exportAllCmd)
+}
+
+func exportAllCmdRun(cmd *cobra.Command, args []string) error {
+	if exportAllArgs.asKustomize && exportArgs.outputDir == "" {
+		return fmt.Errorf("--as-kustomize requires --output-dir")
+	}
+	if exportAllArgs.groupBy != "" {
+		if exportAllArgs.groupBy != exportGroupByNamespace {
+			return fmt.Errorf("unsupported --group-by value '%s', only '%s' is supported", exportAllArgs.groupBy, exportGroupByNamespace)
+		}
+		if !exportAllArgs.asKustomize {
+			return fmt.Errorf("--group-by requires --as-kustomize")
+		}
+	}
+	exportArgs.all = true
+	exportedFiles = nil
+
+	allCmd := []func(cmd *cobra.Command, args []string) error{
+		exportWithSecretCommand{
+			object: gitRepositoryAdapter{&sourcev1.GitRepository{}},
+			list:   gitRepositoryListAdapter{&sourcev1.GitRepositoryList{}},
+		}.run,
+		exportWithSecretCommand{
+			object: helmRepositoryAdapter{&sourcev1.HelmRepository{}},
+			list:   helmRepositoryListAdapter{&sourcev1.HelmRepositoryList{}},
+		}.run,
+		exportWithSecretCommand{
+			object: bucketAdapter{&sourcev1.Bucket{}},
+			list:   bucketListAdapter{&sourcev1.BucketList{}},
+		}.run,
+		exportCommand{
+			object: kustomizationAdapter{&kustomizev1.Kustomization{}},
+			list:   kustomizationListAdapter{&kustomizev1.KustomizationList{}},
+		}.run,
+		exportCommand{
+			object: helmReleaseAdapter{&helmv2.HelmRelease{}},
+			list:   helmReleaseListAdapter{&helmv2.HelmReleaseList{}},
+		}.run,
+		exportCommand{
+			object: alertProviderAdapter{&notificationv1.Provider{}},
+			list:   alertProviderListAdapter{&notificationv1.ProviderList{}},
+		}.run,
+		exportCommand{
+			object: alertAdapter{&notificationv1.Alert{}},
+			list:   alertListAdapter{&notificationv1.AlertList{}},
+		}.run,
+		exportCommand{
+			object: receiverAdapter{&notificationv1.Receiver{}},
+			list:   receiverListAdapter{&notificationv1.ReceiverList{}},
+		}.run,
+		exportCommand{
+			object: imageRepositoryAdapter{&imagev1.ImageRepository{}},
+			list:   imageRepositoryListAdapter{&imagev1.ImageRepositoryList{}},
+		}.run,
+		exportCommand{
+			object: imagePolicyAdapter{&imagev1.ImagePolicy{}},
+			list:   imagePolicyListAdapter{&imagev1.ImagePolicyList{}},
+		}.run,
+		exportCommand{
+			object: imageUpdateAutomationAdapter{&autov1.ImageUpdateAutomation{}},
+			list:   imageUpdateAutomationListAdapter{&autov1.ImageUpdateAutomationList{}},
+		}.run,
+	}
+
+	for _, run := range allCmd {
+		if err := run(cmd, args); err != nil {
+			// the image automation CRDs are optional
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return err
+		}
+	}
+
+	if exportAllArgs.asKustomize {
+		return writeExportKustomization(exportArgs.outputDir, exportedFiles, exportAllArgs.groupBy)
+	}
+	return nil
+}
+
+// exportDir returns the directory the objects of the given kind and namespace
+// are exported to, in the base of their namespace with --group-by=namespace.
+func exportDir(kind, namespace string) string {
+	if exportAllArgs.groupBy == exportGroupByNamespace {
+		return filepath.Join(exportArgs.outputDir, "base", namespace, strings.ToLower(kind))
+	}
+	return filepath.Join(exportArgs.outputDir, strings.ToLower(kind), namespace)
+}
+
+// writeExportKustomization generates a kustomization.yaml in the given
+// directory with the exported files as resources. When grouped by namespace,
+// the files are listed in the kustomization of their base/<namespace>
+// directory, which is the resource of the overlays/<namespace> kustomization,
+// and the top kustomization lists all the overlays of the directory, including
+// the ones of the namespaces exported previously.
+func writeExportKustomization(dir string, files []string, groupBy string) error {
+	if groupBy != exportGroupByNamespace {
+		return writeKustomization(dir, files)
+	}
+
+	baseDir := filepath.Join(dir, "base")
+	namespaceFiles := make(map[string][]string)
+	for _, file := range files {
+		rel, err := filepath.Rel(baseDir, file)
+		if err != nil {
+			return err
+		}
+		namespace := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+		namespaceFiles[namespace] = append(namespaceFiles[namespace], file)
+	}
+
+	for namespace, files := range namespaceFiles {
+		if err := writeKustomization(filepath.Join(baseDir, namespace), files); err != nil {
+			return err
+		}
+		overlayDir := filepath.Join(dir, "overlays", namespace)
+		if err := os.MkdirAll(overlayDir, 0o755); err != nil {
+			return err
+		}
+		if err := writeKustomization(overlayDir, []string{filepath.Join(baseDir, namespace)}); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "overlays"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var overlays []string
+	for _, entry := range entries {
+		overlayDir := filepath.Join(dir, "overlays", entry.Name())
+		if _, err := os.Stat(filepath.Join(overlayDir, "kustomization.yaml")); entry.IsDir() && err == nil {
+			overlays = append(overlays, overlayDir)
+		}
+	}
+	return writeKustomization(dir, overlays)
+}
+
+// writeKustomization writes a kustomization.yaml in the given directory with
+// the given paths as resources, relative to the directory.
+func writeKustomization(dir string, paths []string) error {
+	kus := kustypes.Kustomization{
+		TypeMeta: kustypes.TypeMeta{
+			APIVersion: kustypes.KustomizationVersion,
+			Kind:       kustypes.KustomizationKind,
+		},
+	}
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		kus.Resources = append(kus.Resources, rel)
+	}
+	sort.Strings(kus.Resources)
+
+	data, err := yaml.Marshal(kus)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "kustomization.yaml")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	logger.Successf("kustomization written to %s", path)
+	return nil
+}
@@ -32,7 +32,10 @@ var exportKsCmd = &cobra.Command{
   flux export kustomization --all > kustomizations.yaml
 
   # Export a Kustomization
-  flux export kustomization my-app > kustomization.yaml`,
+  flux export kustomization my-app > kustomization.yaml
+
+  # Export all Kustomization resources to one file per object in ./clusters/my-cluster
+  flux export kustomization --all --output-dir=./clusters/my-cluster`,
 	ValidArgsFunction: resourceNamesCompletionFunc(kustomizev1.GroupVersion.WithKind(kustomizev1.KustomizationKind)),
 	RunE: exportCommand{
 		object: kustomizationAdapter{&kustomizev1.Kustomization{}},
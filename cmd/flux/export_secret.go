@@ -22,7 +22,6 @@ import (
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -71,18 +70,23 @@ func (export exportWithSecretCommand) run(cmd *cobra.Command, args []string) err
 		}
 
 		if export.list.len() == 0 {
+			if cmd.Use == "all" {
+				return nil
+			}
 			return fmt.Errorf("no objects found in %s namespace", *kubeconfigArgs.Namespace)
 		}
 
 		for i := 0; i < export.list.len(); i++ {
-			if err = printExport(export.list.exportItem(i)); err != nil {
+			if err = writeExport(export.list.exportItem(i)); err != nil {
 				return err
 			}
 
 			if exportSourceWithCred {
 				if export.list.secretItem(i) != nil {
 					namespacedName := *export.list.secretItem(i)
-					return printSecretCredentials(ctx, kubeClient, namespacedName)
+					if err := printSecretCredentials(ctx, kubeClient, namespacedName); err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -97,7 +101,7 @@ func (export exportWithSecretCommand) run(cmd *cobra.Command, args []string) err
 			return err
 		}
 
-		if err := printExport(export.object.export()); err != nil {
+		if err := writeExport(export.object.export()); err != nil {
 			return err
 		}
 
@@ -119,17 +123,5 @@ func printSecretCredentials(ctx context.Context, kubeClient client.Client, nsNam
 		return fmt.Errorf("failed to retrieve secret %s, error: %w", nsName.Name, err)
 	}
 
-	exported := corev1.Secret{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: "v1",
-			Kind:       "Secret",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      nsName.Name,
-			Namespace: nsName.Namespace,
-		},
-		Data: cred.Data,
-		Type: cred.Type,
-	}
-	return printExport(exported)
+	return writeExport(exportSecret(&cred))
 }
@@ -0,0 +1,215 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var exportSecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Export the secrets referenced by sources and notifications in YAML format",
+	Long: `The export secrets command exports the secrets referenced by the sources, the alert providers
+and the receivers of a namespace in YAML format.
+The keys of the secrets are kept and their values are replaced with a placeholder, so that the
+configuration can be reviewed and committed without leaking the credentials.
+The actual values are exported only with --unsafe-show-values.`,
+	Example: `  # Export the secrets of the flux-system namespace with their values redacted
+  flux export secrets > secrets.yaml
+
+  # Export the secrets with their actual values
+  flux export secrets --unsafe-show-values > secrets.yaml
+
+  # Export the secrets along with the resources that reference them
+  flux export all --output-dir=./clusters/my-cluster
+  flux export secrets --output-dir=./clusters/my-cluster`,
+	RunE: exportSecretsCmdRun,
+}
+
+// redactedSecretValue is the placeholder of the exported secret values.
+const redactedSecretValue = "REDACTED"
+
+type exportSecretsFlags struct {
+	unsafeShowValues bool
+}
+
+var exportSecretsArgs exportSecretsFlags
+
+func init() {
+	exportSecretsCmd.Flags().BoolVar(&exportSecretsArgs.unsafeShowValues, "unsafe-show-values", false,
+		"export the actual values of the secrets instead of redacting them")
+
+	exportCmd.AddCommand(exportSecretsCmd)
+}
+
+func exportSecretsCmdRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	names, err := referencedSecrets(ctx, kubeClient, *kubeconfigArgs.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no secrets referenced in %s namespace", *kubeconfigArgs.Namespace)
+	}
+
+	for _, name := range names {
+		namespacedName := types.NamespacedName{Namespace: *kubeconfigArgs.Namespace, Name: name}
+		var secret corev1.Secret
+		if err := kubeClient.Get(ctx, namespacedName, &secret); err != nil {
+			return fmt.Errorf("failed to retrieve secret %s, error: %w", name, err)
+		}
+
+		export := exportSecret(&secret)
+		if !exportSecretsArgs.unsafeShowValues {
+			sanitizeSecret(&export, redactedSecretValue)
+		}
+		if err := writeExport(export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencedSecrets returns the sorted names of the secrets referenced by
+// the sources and the notification objects of the namespace. The kinds that
+// aren't installed on the cluster are skipped.
+func referencedSecrets(ctx context.Context, kubeClient client.Client, namespace string) ([]string, error) {
+	set := map[string]bool{}
+	add := func(ref *meta.LocalObjectReference) {
+		if ref != nil && ref.Name != "" {
+			set[ref.Name] = true
+		}
+	}
+
+	list := func(obj client.ObjectList) (bool, error) {
+		if err := kubeClient.List(ctx, obj, client.InNamespace(namespace)); err != nil {
+			if apimeta.IsNoMatchError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	var gitRepositories sourcev1.GitRepositoryList
+	if ok, err := list(&gitRepositories); err != nil {
+		return nil, err
+	} else if ok {
+		for _, item := range gitRepositories.Items {
+			add(item.Spec.SecretRef)
+			if item.Spec.Verification != nil {
+				add(&item.Spec.Verification.SecretRef)
+			}
+		}
+	}
+
+	var helmRepositories sourcev1.HelmRepositoryList
+	if ok, err := list(&helmRepositories); err != nil {
+		return nil, err
+	} else if ok {
+		for _, item := range helmRepositories.Items {
+			add(item.Spec.SecretRef)
+		}
+	}
+
+	var buckets sourcev1.BucketList
+	if ok, err := list(&buckets); err != nil {
+		return nil, err
+	} else if ok {
+		for _, item := range buckets.Items {
+			add(item.Spec.SecretRef)
+		}
+	}
+
+	var providers notificationv1.ProviderList
+	if ok, err := list(&providers); err != nil {
+		return nil, err
+	} else if ok {
+		for _, item := range providers.Items {
+			add(item.Spec.SecretRef)
+			add(item.Spec.CertSecretRef)
+		}
+	}
+
+	var receivers notificationv1.ReceiverList
+	if ok, err := list(&receivers); err != nil {
+		return nil, err
+	} else if ok {
+		for i := range receivers.Items {
+			add(&receivers.Items[i].Spec.SecretRef)
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// exportSecret returns a copy of the secret without its cluster metadata.
+func exportSecret(secret *corev1.Secret) corev1.Secret {
+	return corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secret.Name,
+			Namespace: secret.Namespace,
+		},
+		Data: secret.Data,
+		Type: secret.Type,
+	}
+}
+
+// sanitizeSecret replaces the values of the secret with the placeholder.
+// The values are moved to stringData, so that the placeholder is readable.
+func sanitizeSecret(secret *corev1.Secret, placeholder string) {
+	stringData := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for key := range secret.Data {
+		stringData[key] = placeholder
+	}
+	for key := range secret.StringData {
+		stringData[key] = placeholder
+	}
+	secret.Data = nil
+	secret.StringData = stringData
+}
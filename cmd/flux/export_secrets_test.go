@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestReferencedSecrets(t *testing.T) {
+	objectMeta := func(name, namespace string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace}
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&sourcev1.GitRepository{
+			ObjectMeta: objectMeta("podinfo", "flux-system"),
+			Spec: sourcev1.GitRepositorySpec{
+				SecretRef: &meta.LocalObjectReference{Name: "git-auth"},
+				Verification: &sourcev1.GitRepositoryVerification{
+					SecretRef: meta.LocalObjectReference{Name: "pgp-keys"},
+				},
+			},
+		},
+		&sourcev1.GitRepository{
+			ObjectMeta: objectMeta("public", "flux-system"),
+		},
+		&sourcev1.HelmRepository{
+			ObjectMeta: objectMeta("charts", "flux-system"),
+			Spec:       sourcev1.HelmRepositorySpec{SecretRef: &meta.LocalObjectReference{Name: "git-auth"}},
+		},
+		&sourcev1.Bucket{
+			ObjectMeta: objectMeta("artifacts", "apps"),
+			Spec:       sourcev1.BucketSpec{SecretRef: &meta.LocalObjectReference{Name: "bucket-auth"}},
+		},
+		&notificationv1.Provider{
+			ObjectMeta: objectMeta("slack", "flux-system"),
+			Spec: notificationv1.ProviderSpec{
+				SecretRef:     &meta.LocalObjectReference{Name: "slack-url"},
+				CertSecretRef: &meta.LocalObjectReference{Name: "slack-ca"},
+			},
+		},
+		&notificationv1.Receiver{
+			ObjectMeta: objectMeta("github", "flux-system"),
+			Spec:       notificationv1.ReceiverSpec{SecretRef: meta.LocalObjectReference{Name: "webhook-token"}},
+		},
+	).Build()
+
+	names, err := referencedSecrets(context.Background(), kubeClient, "flux-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"git-auth", "pgp-keys", "slack-ca", "slack-url", "webhook-token"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestSanitizeSecret(t *testing.T) {
+	secret := exportSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-auth", Namespace: "flux-system", ResourceVersion: "1"},
+		Data: map[string][]byte{
+			"username": []byte("git"),
+			"password": []byte("s3cr3t"),
+		},
+		Type: corev1.SecretTypeOpaque,
+	})
+	sanitizeSecret(&secret, redactedSecretValue)
+
+	if secret.Data != nil {
+		t.Errorf("expected the data to be removed, got %v", secret.Data)
+	}
+	expected := map[string]string{
+		"username": redactedSecretValue,
+		"password": redactedSecretValue,
+	}
+	if !reflect.DeepEqual(secret.StringData, expected) {
+		t.Errorf("expected %v, got %v", expected, secret.StringData)
+	}
+	if secret.ResourceVersion != "" || secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("unexpected exported secret %+v", secret)
+	}
+}
@@ -4,7 +4,14 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
 )
 
 func TestExport(t *testing.T) {
@@ -48,6 +55,11 @@ func TestExport(t *testing.T) {
 			"export source helm flux-system",
 			"testdata/export/helm-repo.yaml",
 		},
+		{
+			"source chart",
+			"export source chart flux-system",
+			"testdata/export/helm-chart.yaml",
+		},
 		{
 			"receiver",
 			"export receiver flux-system",
@@ -87,3 +99,115 @@ func TestExport(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteExport(t *testing.T) {
+	exportArgs.outputDir = t.TempDir()
+	defer func() { exportArgs.outputDir = "" }()
+
+	ks := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"},
+		Spec:       kustomizev1.KustomizationSpec{Path: "./apps"},
+	}
+	if err := writeExport(exportKs(ks)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(exportArgs.outputDir, "kustomization", "flux-system", "apps.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"---\napiVersion: kustomize.toolkit.fluxcd.io/v1beta2\n", "kind: Kustomization", "path: ./apps"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("expected %s in:\n%s", s, data)
+		}
+	}
+	if strings.Contains(string(data), "status:") || strings.Contains(string(data), "creationTimestamp") {
+		t.Errorf("expected the status and creation timestamp to be removed:\n%s", data)
+	}
+}
+
+func TestWriteExportKustomization(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		filepath.Join(dir, "kustomization", "flux-system", "apps.yaml"),
+		filepath.Join(dir, "gitrepository", "flux-system", "flux-system.yaml"),
+		filepath.Join(dir, "kustomization", "flux-system", "apps.yaml"),
+	}
+	if err := writeExportKustomization(dir, files, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- gitrepository/flux-system/flux-system.yaml
+- kustomization/flux-system/apps.yaml
+`
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestWriteExportKustomizationGroupByNamespace(t *testing.T) {
+	exportArgs.outputDir = t.TempDir()
+	exportAllArgs.groupBy = exportGroupByNamespace
+	defer func() {
+		exportArgs.outputDir = ""
+		exportAllArgs.groupBy = ""
+	}()
+	dir := exportArgs.outputDir
+
+	// the overlay of a namespace exported previously
+	if err := os.MkdirAll(filepath.Join(dir, "overlays", "apps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "overlays", "apps", "kustomization.yaml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{
+		filepath.Join(exportDir("Kustomization", "flux-system"), "apps.yaml"),
+		filepath.Join(exportDir("GitRepository", "flux-system"), "flux-system.yaml"),
+	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeExportKustomization(dir, files, exportGroupByNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- overlays/apps
+- overlays/flux-system
+`,
+		"base/flux-system/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- gitrepository/flux-system.yaml
+- kustomization/apps.yaml
+`,
+		"overlays/flux-system/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ../../base/flux-system
+`,
+	}
+	for file, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("expected %s:\n%s\ngot:\n%s", file, content, data)
+		}
+	}
+}
@@ -19,16 +19,26 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	watchtools "k8s.io/client-go/tools/watch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"github.com/fluxcd/pkg/apis/meta"
 
@@ -66,6 +76,13 @@ type GetFlags struct {
 	noHeader       bool
 	statusSelector string
 	watch          bool
+	output         string
+	sortBy         string
+	noTruncate     bool
+	omitSuspended  bool
+	onlySuspended  bool
+	contexts       []string
+	allContexts    bool
 }
 
 var getArgs GetFlags
@@ -76,7 +93,19 @@ func init() {
 	getCmd.PersistentFlags().BoolVarP(&getArgs.noHeader, "no-header", "", false, "skip the header when printing the results")
 	getCmd.PersistentFlags().BoolVarP(&getArgs.watch, "watch", "w", false, "After listing/getting the requested object, watch for changes.")
 	getCmd.PersistentFlags().StringVar(&getArgs.statusSelector, "status-selector", "",
-		"specify the status condition name and the desired state to filter the get result, e.g. ready=false")
+		"specify the status condition name and the desired state to filter the get result, e.g. ready=false, ready=unknown, stalled=true or suspended=true")
+	getCmd.PersistentFlags().BoolVar(&getArgs.omitSuspended, "omit-suspended", false, "skip the suspended objects")
+	getCmd.PersistentFlags().BoolVar(&getArgs.onlySuspended, "only-suspended", false, "list only the suspended objects")
+	getCmd.PersistentFlags().StringSliceVar(&getArgs.contexts, "contexts", nil,
+		"list the objects of the given kubeconfig contexts, accepts comma-separated values")
+	getCmd.PersistentFlags().BoolVar(&getArgs.allContexts, "all-contexts", false,
+		"list the objects of all the kubeconfig contexts")
+	getCmd.PersistentFlags().StringVar(&getArgs.sortBy, "sort-by", "",
+		"sort the objects by 'name', 'kind', 'ready' (not ready first) or 'last-reconcile' (least recently reconciled first)")
+	getCmd.PersistentFlags().StringVarP(&getArgs.output, "output", "o", "",
+		"print the objects in the given format instead of a table, can be 'wide', 'json', 'yaml', 'go-template=<template>' or 'custom-columns=<header>:<json-path>,...'")
+	getCmd.PersistentFlags().BoolVar(&getArgs.noTruncate, "no-truncate", false,
+		"print the full commit SHAs in the revisions and messages")
 	rootCmd.AddCommand(getCmd)
 }
 
@@ -87,6 +116,12 @@ type summarisable interface {
 	statusSelectorMatches(i int, conditionType, conditionStatus string) bool
 }
 
+// itemFilter can be implemented by the summarisable lists that hide some
+// of their items depending on command specific flags.
+type itemFilter interface {
+	includeItem(i int) bool
+}
+
 // --- these help with implementations of summarisable
 
 func statusAndMessage(conditions []metav1.Condition) (string, string) {
@@ -96,7 +131,14 @@ func statusAndMessage(conditions []metav1.Condition) (string, string) {
 	return string(metav1.ConditionFalse), "waiting to be reconciled"
 }
 
-func statusMatches(conditionType, conditionStatus string, conditions []metav1.Condition) bool {
+// statusMatches reports whether the object condition of the given type has
+// the given status, a missing condition has the Unknown status. The suspended
+// type matches the spec.suspend field of the object instead of a condition.
+func statusMatches(conditionType, conditionStatus string, conditions []metav1.Condition, suspended bool) bool {
+	if strings.EqualFold(conditionType, "suspended") {
+		return strings.EqualFold(strconv.FormatBool(suspended), conditionStatus)
+	}
+
 	// we don't use apimeta.FindStatusCondition because we'd like to use EqualFold to compare two strings
 	var c *metav1.Condition
 	for i := range conditions {
@@ -107,7 +149,7 @@ func statusMatches(conditionType, conditionStatus string, conditions []metav1.Co
 	if c != nil {
 		return strings.EqualFold(string(c.Status), conditionStatus)
 	}
-	return false
+	return strings.EqualFold(string(metav1.ConditionUnknown), conditionStatus)
 }
 
 func nameColumns(item named, includeNamespace bool, includeKind bool) []string {
@@ -129,6 +171,9 @@ type getCommand struct {
 	apiType
 	list    summarisable
 	funcMap typeMap
+	// prepare is called with the client config of each cluster before
+	// its objects are listed, when set.
+	prepare func(rcg genericclioptions.RESTClientGetter) error
 }
 
 func (get getCommand) run(cmd *cobra.Command, args []string) error {
@@ -140,6 +185,38 @@ func (get getCommand) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	listOpts := getListOptions(args)
+	getAll := cmd.Use == "all"
+
+	if err := validateGetFlags(); err != nil {
+		return err
+	}
+
+	if len(getArgs.contexts) > 0 || getArgs.allContexts {
+		return get.runContexts(cmd, getAll, listOpts)
+	}
+
+	if get.prepare != nil {
+		if err := get.prepare(kubeconfigArgs); err != nil {
+			return err
+		}
+	}
+
+	if getArgs.watch {
+		return get.watch(context.Background(), kubeClient, cmd, args, listOpts)
+	}
+
+	err = kubeClient.List(ctx, get.list.asClientList(), listOpts...)
+	if err != nil {
+		return err
+	}
+
+	return get.print(cmd.OutOrStdout(), getAll)
+}
+
+// getListOptions returns the options used to list the objects, based on
+// the namespace flags and the optional name argument.
+func getListOptions(args []string) []client.ListOption {
 	var listOpts []client.ListOption
 	if !getArgs.allNamespaces {
 		listOpts = append(listOpts, client.InNamespace(*kubeconfigArgs.Namespace))
@@ -148,16 +225,51 @@ func (get getCommand) run(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		listOpts = append(listOpts, client.MatchingFields{"metadata.name": args[0]})
 	}
+	return listOpts
+}
 
-	getAll := cmd.Use == "all"
+// validateGetFlags checks the combination of the get flags.
+func validateGetFlags() error {
+	if err := validateOutputFormat(getArgs.output); err != nil {
+		return err
+	}
 
-	if getArgs.watch {
-		return get.watch(ctx, kubeClient, cmd, args, listOpts)
+	switch getArgs.sortBy {
+	case "", "name", "kind", "ready", "last-reconcile":
+	default:
+		return fmt.Errorf("unsupported sort key '%s', must be one of: name, kind, ready, last-reconcile", getArgs.sortBy)
 	}
 
-	err = kubeClient.List(ctx, get.list.asClientList(), listOpts...)
-	if err != nil {
-		return err
+	if getArgs.omitSuspended && getArgs.onlySuspended {
+		return fmt.Errorf("--omit-suspended and --only-suspended are mutually exclusive")
+	}
+
+	structuredOutput := isStructuredOutput()
+	if getArgs.watch && structuredOutput {
+		return fmt.Errorf("--output=%s can't be used with --watch", getArgs.output)
+	}
+
+	if (len(getArgs.contexts) > 0 || getArgs.allContexts) && (getArgs.watch || structuredOutput) {
+		return fmt.Errorf("--contexts and --all-contexts can't be used with --watch or --output=%s", getArgs.output)
+	}
+	return nil
+}
+
+// isStructuredOutput returns true when the objects are printed in a
+// machine-readable format instead of a table.
+func isStructuredOutput() bool {
+	return getArgs.output != "" && getArgs.output != wideOutput
+}
+
+// print writes the listed objects to w, as a table or in the format given
+// with --output.
+func (get getCommand) print(w io.Writer, getAll bool) error {
+	if isStructuredOutput() {
+		objects, err := get.objectsToPrint()
+		if err != nil {
+			return err
+		}
+		return printObjects(w, objects, getArgs.output)
 	}
 
 	if get.list.len() == 0 {
@@ -169,7 +281,7 @@ func (get getCommand) run(cmd *cobra.Command, args []string) error {
 
 	var header []string
 	if !getArgs.noHeader {
-		header = get.list.headers(getArgs.allNamespaces)
+		header = tableHeaders(get.list)
 	}
 
 	rows, err := getRowsToPrint(getAll, get.list)
@@ -177,16 +289,62 @@ func (get getCommand) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	utils.PrintTable(cmd.OutOrStdout(), header, rows)
+	utils.PrintTable(w, header, rows)
 
 	if getAll {
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 
 	return nil
 }
 
 func getRowsToPrint(getAll bool, list summarisable) ([][]string, error) {
+	items, err := getItemsToPrint(list)
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	if getArgs.output == wideOutput {
+		if objects, err = apimeta.ExtractList(list.asClientList()); err != nil {
+			return nil, err
+		}
+	}
+	var rows [][]string
+	for _, i := range items {
+		row := list.summariseItem(i, getArgs.allNamespaces, getAll)
+		if objects != nil {
+			columns, err := wideColumns(objects[i])
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, columns...)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func tableHeaders(list summarisable) []string {
+	headers := list.headers(getArgs.allNamespaces)
+	if getArgs.output == wideOutput {
+		headers = append(headers, wideHeaders...)
+	}
+	return headers
+}
+
+// objectsToPrint returns the listed objects matching the status selector
+// and the command specific filters, as printed with --output.
+func (get getCommand) objectsToPrint() ([]runtime.Object, error) {
+	items, err := getItemsToPrint(get.list)
+	if err != nil {
+		return nil, err
+	}
+	return listObjects(get.list, items)
+}
+
+// getItemsToPrint returns the indexes of the list items matching the status
+// selector and the command specific filters.
+func getItemsToPrint(list summarisable) ([]int, error) {
 	noFilter := true
 	var conditionType, conditionStatus string
 	if getArgs.statusSelector != "" {
@@ -198,36 +356,162 @@ func getRowsToPrint(getAll bool, list summarisable) ([][]string, error) {
 		conditionStatus = parts[1]
 		noFilter = false
 	}
-	var rows [][]string
+	filter, hasFilter := list.(itemFilter)
+	var items []int
 	for i := 0; i < list.len(); i++ {
+		if hasFilter && !filter.includeItem(i) {
+			continue
+		}
+		if getArgs.omitSuspended || getArgs.onlySuspended {
+			if list.statusSelectorMatches(i, "suspended", "true") != getArgs.onlySuspended {
+				continue
+			}
+		}
 		if noFilter || list.statusSelectorMatches(i, conditionType, conditionStatus) {
-			row := list.summariseItem(i, getArgs.allNamespaces, getAll)
-			rows = append(rows, row)
+			items = append(items, i)
 		}
 	}
-	return rows, nil
+	if getArgs.sortBy != "" {
+		if err := sortItems(list, items, getArgs.sortBy); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// sortItems orders the list item indexes by the given key, the items with
+// equal keys are ordered by namespace and name.
+func sortItems(list listAdapter, items []int, sortBy string) error {
+	objects, err := apimeta.ExtractList(list.asClientList())
+	if err != nil {
+		return err
+	}
+
+	scheme := utils.NewScheme()
+	type sortKey struct {
+		kind, namespacedName string
+		ready                int
+		lastReconcile        time.Time
+	}
+	keys := make(map[int]sortKey, len(items))
+	for _, i := range items {
+		obj, err := apimeta.Accessor(objects[i])
+		if err != nil {
+			return err
+		}
+		gvk, err := apiutil.GVKForObject(objects[i], scheme)
+		if err != nil {
+			return err
+		}
+		key := sortKey{
+			kind:           gvk.Kind,
+			namespacedName: obj.GetNamespace() + "/" + obj.GetName(),
+			ready:          1,
+		}
+		if o, ok := objects[i].(interface {
+			GetStatusConditions() *[]metav1.Condition
+		}); ok {
+			if c := apimeta.FindStatusCondition(*o.GetStatusConditions(), meta.ReadyCondition); c != nil {
+				switch c.Status {
+				case metav1.ConditionFalse:
+					key.ready = 0
+				case metav1.ConditionTrue:
+					key.ready = 2
+				}
+			}
+		}
+		if sortBy == "last-reconcile" {
+			key.lastReconcile, err = lastReconcileTime(objects[i])
+			if err != nil {
+				return err
+			}
+		}
+		keys[i] = key
+	}
+
+	sort.SliceStable(items, func(a, b int) bool {
+		ka, kb := keys[items[a]], keys[items[b]]
+		switch sortBy {
+		case "kind":
+			if ka.kind != kb.kind {
+				return ka.kind < kb.kind
+			}
+		case "ready":
+			if ka.ready != kb.ready {
+				return ka.ready < kb.ready
+			}
+		case "last-reconcile":
+			if !ka.lastReconcile.Equal(kb.lastReconcile) {
+				return ka.lastReconcile.Before(kb.lastReconcile)
+			}
+		}
+		return ka.namespacedName < kb.namespacedName
+	})
+	return nil
+}
+
+// lastReconcileTime returns the time of the last reconcile request handled
+// by the controller, or the last update of the artifact for the sources.
+// The Ready condition isn't used as its transition time only changes when
+// the status flips. The zero time is returned when neither is known.
+func lastReconcileTime(obj runtime.Object) (time.Time, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if v, _, _ := unstructured.NestedString(content, "status", "lastHandledReconcileAt"); v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+	}
+	if v, _, _ := unstructured.NestedString(content, "status", "artifact", "lastUpdateTime"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, nil
 }
 
-//
-// watch starts a client-side watch of one or more resources.
+// watch starts a client-side watch of one or more resources. The watch is not
+// bound by the command timeout, it runs until interrupted.
 func (get *getCommand) watch(ctx context.Context, kubeClient client.WithWatch, cmd *cobra.Command, args []string, listOpts []client.ListOption) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
 	w, err := kubeClient.Watch(ctx, get.list.asClientList(), listOpts...)
 	if err != nil {
 		return err
 	}
 
-	_, err = watchUntil(ctx, w, get)
-	if err != nil {
+	_, err = watchUntil(ctx, w, get, cmd.OutOrStdout())
+	if err != nil && ctx.Err() == nil {
 		return err
 	}
 
 	return nil
 }
 
-func watchUntil(ctx context.Context, w watch.Interface, get *getCommand) (bool, error) {
+// watchUntil prints the row of an object each time it changes, events which
+// leave the printed columns as they were are skipped.
+func watchUntil(ctx context.Context, w watch.Interface, get *getCommand, out io.Writer) (bool, error) {
 	firstIteration := true
+	printed := make(map[string][]string)
 	_, error := watchtools.UntilWithoutRetry(ctx, w, func(e watch.Event) (bool, error) {
+		if e.Type == watch.Error {
+			return false, apierrors.FromObject(e.Object)
+		}
+
 		objToPrint := e.Object
+		obj, err := apimeta.Accessor(objToPrint)
+		if err != nil {
+			return false, err
+		}
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		if e.Type == watch.Deleted {
+			delete(printed, key)
+			return false, nil
+		}
+
 		sink, err := get.funcMap.execute(get.apiType.kind, objToPrint)
 		if err != nil {
 			return false, err
@@ -235,17 +519,22 @@ func watchUntil(ctx context.Context, w watch.Interface, get *getCommand) (bool,
 
 		var header []string
 		if !getArgs.noHeader {
-			header = sink.headers(getArgs.allNamespaces)
+			header = tableHeaders(sink)
 		}
 		rows, err := getRowsToPrint(false, sink)
 		if err != nil {
 			return false, err
 		}
+		if len(rows) == 0 || reflect.DeepEqual(printed[key], rows[0]) {
+			return false, nil
+		}
+		printed[key] = rows[0]
+
 		if firstIteration {
-			utils.PrintTable(os.Stdout, header, rows)
+			utils.PrintTable(out, header, rows)
 			firstIteration = false
 		} else {
-			utils.PrintTable(os.Stdout, []string{}, rows)
+			utils.PrintTable(out, []string{}, rows)
 		}
 
 		return false, nil
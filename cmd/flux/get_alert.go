@@ -90,5 +90,5 @@ func (s alertListAdapter) headers(includeNamespace bool) []string {
 
 func (s alertListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := s.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
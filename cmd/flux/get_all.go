@@ -17,13 +17,19 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
 	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
 var getAllCmd = &cobra.Command{
@@ -34,20 +40,21 @@ var getAllCmd = &cobra.Command{
   flux get all --namespace=flux-system
 
   # List all resources in all namespaces
-  flux get all --all-namespaces`,
+  flux get all --all-namespaces
+
+  # List all resources with the ones failing to reconcile at the top
+  flux get all --all-namespaces --sort-by=ready
+
+  # List all resources of the staging and production clusters
+  flux get all --contexts=staging,production`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		err := validateWatchOption(cmd, "all")
 		if err != nil {
 			return err
 		}
 
-		err = getSourceAllCmd.RunE(cmd, args)
-		if err != nil {
-			logError(err)
-		}
-
-		// all get command
-		var allCmd = []getCommand{
+		commands := sourceGetCommands()
+		commands = append(commands, []getCommand{
 			{
 				apiType: helmReleaseType,
 				list:    &helmReleaseListAdapter{&helmv2.HelmReleaseList{}},
@@ -68,21 +75,90 @@ var getAllCmd = &cobra.Command{
 				apiType: alertType,
 				list:    &alertListAdapter{&notificationv1.AlertList{}},
 			},
-		}
+		}...)
+		commands = append(commands, imageGetCommands()...)
 
-		err = getImageAllCmd.RunE(cmd, args)
-		if err != nil {
-			logError(err)
-		}
+		return runGetAll(cmd, args, commands)
+	},
+}
+
+// getAllConcurrency is the maximum number of kinds listed at the same time.
+const getAllConcurrency = 4
 
-		for _, c := range allCmd {
+// runGetAll lists the objects of the given commands concurrently with a
+// single client, and prints the table of each kind in the order of the commands.
+// With --output, the objects of all kinds are printed in a single List.
+// The kinds that aren't installed on the cluster are skipped.
+func runGetAll(cmd *cobra.Command, args []string, commands []getCommand) error {
+	if err := validateGetFlags(); err != nil {
+		return err
+	}
+
+	if len(getArgs.contexts) > 0 || getArgs.allContexts {
+		for _, c := range commands {
 			if err := c.run(cmd, args); err != nil {
 				logError(err)
 			}
 		}
-
 		return nil
-	},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	// the client is shared so that the API discovery is done only once
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+	listOpts := getListOptions(args)
+
+	// each kind is printed in its own buffer, then the buffers are
+	// written in the order of the commands for a stable output
+	structuredOutput := isStructuredOutput()
+	outputs := make([]bytes.Buffer, len(commands))
+	objects := make([][]runtime.Object, len(commands))
+	errs := make([]error, len(commands))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, getAllConcurrency)
+	for i, c := range commands {
+		wg.Add(1)
+		go func(i int, c getCommand) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := kubeClient.List(ctx, c.list.asClientList(), listOpts...); err != nil {
+				errs[i] = err
+				return
+			}
+			if structuredOutput {
+				objects[i], errs[i] = c.objectsToPrint()
+				return
+			}
+			errs[i] = c.print(&outputs[i], true)
+		}(i, c)
+	}
+	wg.Wait()
+
+	var all []runtime.Object
+	for i := range commands {
+		if errs[i] != nil {
+			logError(errs[i])
+			continue
+		}
+		if structuredOutput {
+			all = append(all, objects[i]...)
+			continue
+		}
+		if _, err := outputs[i].WriteTo(cmd.OutOrStdout()); err != nil {
+			return err
+		}
+	}
+
+	if structuredOutput {
+		return printObjects(cmd.OutOrStdout(), all, getArgs.output)
+	}
+	return nil
 }
 
 func logError(err error) {
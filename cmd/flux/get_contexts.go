@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/cobra"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+// runContexts lists the objects of several kubeconfig contexts concurrently,
+// then prints them in a single table with a cluster column. It fails when
+// the objects can't be listed in any of the contexts.
+func (get getCommand) runContexts(cmd *cobra.Command, getAll bool, listOpts []client.ListOption) error {
+	rawConfig, err := kubeconfigArgs.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return err
+	}
+	contexts, err := resolveContexts(rawConfig, getArgs.contexts, getArgs.allContexts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	lists := make([]client.ObjectList, len(contexts))
+	errs := make([]error, len(contexts))
+	var wg sync.WaitGroup
+	for i, name := range contexts {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			lists[i], errs[i] = listContextObjects(ctx, contextConfigFlags(name), get.list.asClientList(), listOpts)
+		}(i, name)
+	}
+	wg.Wait()
+
+	var rows [][]string
+	var lastErr error
+	succeeded := 0
+	for i, name := range contexts {
+		if errs[i] != nil {
+			logger.Failuref("%s: %s", name, errs[i])
+			lastErr = errs[i]
+			continue
+		}
+		if get.prepare != nil {
+			if err := get.prepare(contextConfigFlags(name)); err != nil {
+				logger.Failuref("%s: %s", name, err)
+				lastErr = err
+				continue
+			}
+		}
+		items, err := apimeta.ExtractList(lists[i])
+		if err != nil {
+			return err
+		}
+		if err := apimeta.SetList(get.list.asClientList(), items); err != nil {
+			return err
+		}
+		contextRows, err := getRowsToPrint(getAll, get.list)
+		if err != nil {
+			return err
+		}
+		for _, row := range contextRows {
+			rows = append(rows, append([]string{name}, row...))
+		}
+		succeeded++
+	}
+
+	if succeeded == 0 && lastErr != nil {
+		return fmt.Errorf("failed to get %s objects in all contexts: %w", get.kind, lastErr)
+	}
+
+	if len(rows) == 0 {
+		if !getAll {
+			logger.Failuref("no %s objects found in %s namespace", get.kind, *kubeconfigArgs.Namespace)
+		}
+		return nil
+	}
+
+	var header []string
+	if !getArgs.noHeader {
+		header = append([]string{"Cluster"}, tableHeaders(get.list)...)
+	}
+	utils.PrintTable(cmd.OutOrStdout(), header, rows)
+
+	if getAll {
+		fmt.Fprintln(cmd.OutOrStdout())
+	}
+	return nil
+}
+
+// resolveContexts returns the names of the requested kubeconfig contexts,
+// or of all the contexts sorted by name.
+func resolveContexts(config clientcmdapi.Config, names []string, all bool) ([]string, error) {
+	if all {
+		var contexts []string
+		for name := range config.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+		return contexts, nil
+	}
+	for _, name := range names {
+		if _, ok := config.Contexts[name]; !ok {
+			return nil, fmt.Errorf("context '%s' not found in kubeconfig", name)
+		}
+	}
+	return names, nil
+}
+
+// contextConfigFlags returns a copy of the client config flags with the
+// given kubeconfig context, so that the other flags like the namespace or
+// the TLS settings apply to every context.
+func contextConfigFlags(contextName string) *genericclioptions.ConfigFlags {
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.CacheDir = kubeconfigArgs.CacheDir
+	flags.KubeConfig = kubeconfigArgs.KubeConfig
+	flags.ClusterName = kubeconfigArgs.ClusterName
+	flags.AuthInfoName = kubeconfigArgs.AuthInfoName
+	flags.Context = &contextName
+	flags.Namespace = kubeconfigArgs.Namespace
+	flags.APIServer = kubeconfigArgs.APIServer
+	flags.TLSServerName = kubeconfigArgs.TLSServerName
+	flags.Insecure = kubeconfigArgs.Insecure
+	flags.CertFile = kubeconfigArgs.CertFile
+	flags.KeyFile = kubeconfigArgs.KeyFile
+	flags.CAFile = kubeconfigArgs.CAFile
+	flags.BearerToken = kubeconfigArgs.BearerToken
+	flags.Impersonate = kubeconfigArgs.Impersonate
+	flags.ImpersonateUID = kubeconfigArgs.ImpersonateUID
+	flags.ImpersonateGroup = kubeconfigArgs.ImpersonateGroup
+	flags.Username = kubeconfigArgs.Username
+	flags.Password = kubeconfigArgs.Password
+	flags.Timeout = kubeconfigArgs.Timeout
+	flags.WrapConfigFn = kubeconfigArgs.WrapConfigFn
+	return flags
+}
+
+// listContextObjects lists the objects of the given kind with the client
+// config of a kubeconfig context.
+func listContextObjects(ctx context.Context, rcg genericclioptions.RESTClientGetter, list client.ObjectList, listOpts []client.ListOption) (client.ObjectList, error) {
+	kubeClient, err := utils.KubeClient(rcg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := list.DeepCopyObject().(client.ObjectList)
+	if err := kubeClient.List(ctx, result, listOpts...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
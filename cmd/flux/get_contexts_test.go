@@ -0,0 +1,74 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestResolveContexts(t *testing.T) {
+	config := clientcmdapi.Config{
+		Contexts: map[string]*clientcmdapi.Context{
+			"staging":    {},
+			"production": {},
+			"dev":        {},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		contexts  []string
+		all       bool
+		expect    []string
+		expectErr bool
+	}{
+		{"all", nil, true, []string{"dev", "production", "staging"}, false},
+		{"selected", []string{"staging", "dev"}, false, []string{"staging", "dev"}, false},
+		{"unknown", []string{"staging", "qa"}, false, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveContexts(config, tc.contexts, tc.all)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestContextConfigFlags(t *testing.T) {
+	flags := contextConfigFlags("staging")
+	if *flags.Context != "staging" {
+		t.Errorf("expected context staging, got %s", *flags.Context)
+	}
+	if flags.Namespace != kubeconfigArgs.Namespace || flags.Insecure != kubeconfigArgs.Insecure ||
+		flags.KubeConfig != kubeconfigArgs.KubeConfig {
+		t.Error("expected the other client config flags to be copied")
+	}
+	if kubeconfigArgs.Context == flags.Context {
+		t.Error("expected the context flag of the CLI to be left unchanged")
+	}
+}
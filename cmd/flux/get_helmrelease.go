@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -24,6 +25,10 @@ import (
 	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
 var getHelmReleaseCmd = &cobra.Command{
@@ -32,7 +37,10 @@ var getHelmReleaseCmd = &cobra.Command{
 	Short:   "Get HelmRelease statuses",
 	Long:    "The get helmreleases command prints the statuses of the resources.",
 	Example: `  # List all Helm releases and their status
-  flux get helmreleases`,
+  flux get helmreleases
+
+  # List the Helm releases whose chart has a newer version in its Helm repository
+  flux get helmreleases --all-namespaces --only-outdated`,
 	ValidArgsFunction: resourceNamesCompletionFunc(helmv2.GroupVersion.WithKind(helmv2.HelmReleaseKind)),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		get := getCommand{
@@ -58,6 +66,17 @@ var getHelmReleaseCmd = &cobra.Command{
 			return err
 		}
 
+		if getHelmReleaseArgs.onlyOutdated {
+			getHelmReleaseArgs.showDrift = true
+		}
+		if getHelmReleaseArgs.showDrift {
+			get.prepare = func(rcg genericclioptions.RESTClientGetter) error {
+				var err error
+				availableCharts, err = listAvailableChartVersions(rcg)
+				return err
+			}
+		}
+
 		if err := get.run(cmd, args); err != nil {
 			return err
 		}
@@ -66,20 +85,91 @@ var getHelmReleaseCmd = &cobra.Command{
 	},
 }
 
+type getHelmReleaseFlags struct {
+	showDrift    bool
+	onlyOutdated bool
+}
+
+var getHelmReleaseArgs getHelmReleaseFlags
+
+// availableCharts holds the latest chart version available for the
+// HelmReleases, indexed by '<namespace>/<name>'.
+var availableCharts map[string]string
+
 func init() {
+	getHelmReleaseCmd.Flags().BoolVar(&getHelmReleaseArgs.showDrift, "show-drift", false,
+		"print the latest chart version available in the HelmRepository, and whether the release is outdated")
+	getHelmReleaseCmd.Flags().BoolVar(&getHelmReleaseArgs.onlyOutdated, "only-outdated", false,
+		"only print the releases whose chart has a newer version available, implies --show-drift")
 	getCmd.AddCommand(getHelmReleaseCmd)
 }
 
+// listAvailableChartVersions returns the latest chart version available for
+// the HelmReleases of the cluster with the given client config.
+func listAvailableChartVersions(rcg genericclioptions.RESTClientGetter) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(rcg)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := utils.KubeConfig(rcg)
+	if err != nil {
+		return nil, err
+	}
+
+	var listOpts []client.ListOption
+	if !getArgs.allNamespaces {
+		listOpts = append(listOpts, client.InNamespace(*kubeconfigArgs.Namespace))
+	}
+	var releases helmv2.HelmReleaseList
+	if err := kubeClient.List(ctx, &releases, listOpts...); err != nil {
+		return nil, err
+	}
+	return latestChartVersions(ctx, kubeClient, restConfig, releases.Items), nil
+}
+
+// helmReleaseDrift returns the latest chart version available for the
+// release, and whether it is newer than the applied one.
+func helmReleaseDrift(hr *helmv2.HelmRelease, available map[string]string) (string, bool) {
+	latest, ok := available[client.ObjectKeyFromObject(hr).String()]
+	if !ok {
+		return "", false
+	}
+	return latest, newerVersion(hr.Status.LastAppliedRevision, latest)
+}
+
 func (a helmReleaseListAdapter) summariseItem(i int, includeNamespace bool, includeKind bool) []string {
 	item := a.Items[i]
 	revision := item.Status.LastAppliedRevision
 	status, msg := statusAndMessage(item.Status.Conditions)
-	return append(nameColumns(&item, includeNamespace, includeKind),
+	row := append(nameColumns(&item, includeNamespace, includeKind),
 		status, msg, revision, strings.Title(strconv.FormatBool(item.Spec.Suspend)))
+	if getHelmReleaseArgs.showDrift {
+		latest, outdated := helmReleaseDrift(&item, availableCharts)
+		if latest == "" {
+			latest = "-"
+		}
+		row = append(row, latest, strings.Title(strconv.FormatBool(outdated)))
+	}
+	return row
+}
+
+func (a helmReleaseListAdapter) includeItem(i int) bool {
+	if !getHelmReleaseArgs.onlyOutdated {
+		return true
+	}
+	_, outdated := helmReleaseDrift(&a.Items[i], availableCharts)
+	return outdated
 }
 
 func (a helmReleaseListAdapter) headers(includeNamespace bool) []string {
 	headers := []string{"Name", "Ready", "Message", "Revision", "Suspended"}
+	if getHelmReleaseArgs.showDrift {
+		headers = append(headers, "Available", "Outdated")
+	}
 	if includeNamespace {
 		headers = append([]string{"Namespace"}, headers...)
 	}
@@ -88,5 +178,5 @@ func (a helmReleaseListAdapter) headers(includeNamespace bool) []string {
 
 func (a helmReleaseListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := a.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
@@ -0,0 +1,60 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+)
+
+func TestHelmReleaseDrift(t *testing.T) {
+	available := map[string]string{
+		"apps/podinfo": "6.0.3",
+		"apps/redis":   "16.4.0",
+	}
+	hr := func(name, revision string) *helmv2.HelmRelease {
+		return &helmv2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+			Status:     helmv2.HelmReleaseStatus{LastAppliedRevision: revision},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		release        *helmv2.HelmRelease
+		expectLatest   string
+		expectOutdated bool
+	}{
+		{"outdated", hr("podinfo", "6.0.0"), "6.0.3", true},
+		{"up to date", hr("redis", "16.4.0"), "16.4.0", false},
+		{"unresolved", hr("nginx", "9.0.0"), "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			latest, outdated := helmReleaseDrift(tc.release, available)
+			if latest != tc.expectLatest || outdated != tc.expectOutdated {
+				t.Errorf("expected (%s, %v), got (%s, %v)", tc.expectLatest, tc.expectOutdated, latest, outdated)
+			}
+		})
+	}
+}
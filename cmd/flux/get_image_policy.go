@@ -17,12 +17,23 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
 var getImagePolicyCmd = &cobra.Command{
@@ -33,7 +44,10 @@ var getImagePolicyCmd = &cobra.Command{
   flux get image policy
 
  # List image policies from all namespaces
-  flux get image policy --all-namespaces`,
+  flux get image policy --all-namespaces
+
+  # List the image policies whose latest image is not yet running in the cluster
+  flux get image policy --all-namespaces --only-outdated`,
 	ValidArgsFunction: resourceNamesCompletionFunc(imagev1.GroupVersion.WithKind(imagev1.ImagePolicyKind)),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		get := getCommand{
@@ -59,6 +73,17 @@ var getImagePolicyCmd = &cobra.Command{
 			return err
 		}
 
+		if getImagePolicyArgs.onlyOutdated {
+			getImagePolicyArgs.showDrift = true
+		}
+		if getImagePolicyArgs.showDrift {
+			get.prepare = func(rcg genericclioptions.RESTClientGetter) error {
+				var err error
+				deployedImages, err = listDeployedImages(rcg)
+				return err
+			}
+		}
+
 		if err := get.run(cmd, args); err != nil {
 			return err
 		}
@@ -67,18 +92,136 @@ var getImagePolicyCmd = &cobra.Command{
 	},
 }
 
+type getImagePolicyFlags struct {
+	showDrift    bool
+	onlyOutdated bool
+}
+
+var getImagePolicyArgs getImagePolicyFlags
+
+// deployedImages holds the tags of the images run by the workloads
+// in the cluster, indexed by repository.
+var deployedImages map[string][]string
+
 func init() {
+	getImagePolicyCmd.Flags().BoolVar(&getImagePolicyArgs.showDrift, "show-drift", false,
+		"print the tags of the policy image run by the workloads in the cluster, and whether they are outdated")
+	getImagePolicyCmd.Flags().BoolVar(&getImagePolicyArgs.onlyOutdated, "only-outdated", false,
+		"only print the policies whose latest image is not run by all the workloads, implies --show-drift")
 	getImageCmd.AddCommand(getImagePolicyCmd)
 }
 
+// listDeployedImages returns the tags of the container images of the
+// workloads in all namespaces of the given cluster, indexed by repository.
+func listDeployedImages(rcg genericclioptions.RESTClientGetter) (map[string][]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(rcg)
+	if err != nil {
+		return nil, err
+	}
+
+	var podSpecs []corev1.PodSpec
+	var deployments appsv1.DeploymentList
+	if err := kubeClient.List(ctx, &deployments); err != nil {
+		return nil, err
+	}
+	for _, o := range deployments.Items {
+		podSpecs = append(podSpecs, o.Spec.Template.Spec)
+	}
+	var statefulSets appsv1.StatefulSetList
+	if err := kubeClient.List(ctx, &statefulSets); err != nil {
+		return nil, err
+	}
+	for _, o := range statefulSets.Items {
+		podSpecs = append(podSpecs, o.Spec.Template.Spec)
+	}
+	var daemonSets appsv1.DaemonSetList
+	if err := kubeClient.List(ctx, &daemonSets); err != nil {
+		return nil, err
+	}
+	for _, o := range daemonSets.Items {
+		podSpecs = append(podSpecs, o.Spec.Template.Spec)
+	}
+	var cronJobs batchv1.CronJobList
+	if err := kubeClient.List(ctx, &cronJobs); err != nil && !apimeta.IsNoMatchError(err) {
+		return nil, err
+	}
+	for _, o := range cronJobs.Items {
+		podSpecs = append(podSpecs, o.Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	images := make(map[string][]string)
+	for _, spec := range podSpecs {
+		containers := append([]corev1.Container{}, spec.InitContainers...)
+		for _, c := range append(containers, spec.Containers...) {
+			repo, tag, ok := splitImageTag(c.Image)
+			if !ok || utils.ContainsItemString(images[repo], tag) {
+				continue
+			}
+			images[repo] = append(images[repo], tag)
+		}
+	}
+	for repo := range images {
+		sort.Strings(images[repo])
+	}
+	return images, nil
+}
+
+// splitImageTag returns the normalised repository and the tag of an image.
+func splitImageTag(image string) (string, string, bool) {
+	ref, err := name.NewTag(image)
+	if err != nil {
+		return "", "", false
+	}
+	return ref.Context().Name(), ref.TagStr(), true
+}
+
+// imagePolicyDrift returns the tags of the policy repository run in the
+// cluster, and whether any of them differs from the latest image.
+func imagePolicyDrift(latestImage string, deployed map[string][]string) ([]string, bool) {
+	repo, latest, ok := splitImageTag(latestImage)
+	if !ok {
+		return nil, false
+	}
+	tags := deployed[repo]
+	for _, tag := range tags {
+		if tag != latest {
+			return tags, true
+		}
+	}
+	return tags, false
+}
+
 func (s imagePolicyListAdapter) summariseItem(i int, includeNamespace bool, includeKind bool) []string {
 	item := s.Items[i]
 	status, msg := statusAndMessage(item.Status.Conditions)
-	return append(nameColumns(&item, includeNamespace, includeKind), status, msg, item.Status.LatestImage)
+	row := append(nameColumns(&item, includeNamespace, includeKind), status, msg, item.Status.LatestImage)
+	if getImagePolicyArgs.showDrift {
+		tags, outdated := imagePolicyDrift(item.Status.LatestImage, deployedImages)
+		current := "-"
+		if len(tags) > 0 {
+			current = strings.Join(tags, ", ")
+		}
+		row = append(row, current, strings.Title(fmt.Sprintf("%t", outdated)))
+	}
+	return row
+}
+
+func (s imagePolicyListAdapter) includeItem(i int) bool {
+	if !getImagePolicyArgs.onlyOutdated {
+		return true
+	}
+	_, outdated := imagePolicyDrift(s.Items[i].Status.LatestImage, deployedImages)
+	return outdated
 }
 
 func (s imagePolicyListAdapter) headers(includeNamespace bool) []string {
 	headers := []string{"Name", "Ready", "Message", "Latest image"}
+	if getImagePolicyArgs.showDrift {
+		headers = append(headers, "Current tags", "Outdated")
+	}
 	if includeNamespace {
 		return append(namespaceHeader, headers...)
 	}
@@ -87,5 +230,5 @@ func (s imagePolicyListAdapter) headers(includeNamespace bool) []string {
 
 func (s imagePolicyListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := s.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, false)
 }
@@ -0,0 +1,55 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImagePolicyDrift(t *testing.T) {
+	deployed := map[string][]string{
+		"ghcr.io/stefanprodan/podinfo":  {"6.0.0", "6.0.3"},
+		"index.docker.io/library/nginx": {"1.21.6"},
+	}
+	cases := []struct {
+		name           string
+		latestImage    string
+		expectTags     []string
+		expectOutdated bool
+	}{
+		{"outdated", "ghcr.io/stefanprodan/podinfo:6.0.3", []string{"6.0.0", "6.0.3"}, true},
+		{"up to date", "nginx:1.21.6", []string{"1.21.6"}, false},
+		{"not deployed", "ghcr.io/fluxcd/flagger:1.19.0", nil, false},
+		{"no latest image", "", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, outdated := imagePolicyDrift(tc.latestImage, deployed)
+			if diff := cmp.Diff(tc.expectTags, tags); diff != "" {
+				t.Errorf("unexpected tags (-want +got):\n%s", diff)
+			}
+			if outdated != tc.expectOutdated {
+				t.Errorf("expected outdated %v, got %v", tc.expectOutdated, outdated)
+			}
+		})
+	}
+}
@@ -18,11 +18,13 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
@@ -36,7 +38,10 @@ var getImageRepositoryCmd = &cobra.Command{
   flux get image repository
 
  # List image repositories from all namespaces
-  flux get image repository --all-namespaces`,
+  flux get image repository --all-namespaces
+
+  # List the image repositories failing to scan, with the likely cause
+  flux get image repository --all-namespaces --show-errors`,
 	ValidArgsFunction: resourceNamesCompletionFunc(imagev1.GroupVersion.WithKind(imagev1.ImageRepositoryKind)),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		get := getCommand{
@@ -70,23 +75,91 @@ var getImageRepositoryCmd = &cobra.Command{
 	},
 }
 
+type getImageRepositoryFlags struct {
+	showErrors bool
+}
+
+var getImageRepositoryArgs getImageRepositoryFlags
+
 func init() {
+	getImageRepositoryCmd.Flags().BoolVar(&getImageRepositoryArgs.showErrors, "show-errors", false,
+		"only print the image repositories that are not ready, with the likely cause of the scan failure")
 	getImageCmd.AddCommand(getImageRepositoryCmd)
 }
 
+// scanErrorCauses maps fragments of the registry errors to their likely cause.
+var scanErrorCauses = []struct {
+	fragments []string
+	cause     string
+}{
+	{[]string{`secrets "`}, "missing secret"},
+	{[]string{"TOOMANYREQUESTS", "rate limit"}, "registry rate limit"},
+	{[]string{"UNAUTHORIZED", "DENIED", "authentication required", "no basic auth credentials"}, "registry authentication"},
+	{[]string{"x509", "certificate"}, "TLS certificate"},
+	{[]string{"NAME_UNKNOWN", "MANIFEST_UNKNOWN"}, "repository not found"},
+	{[]string{"no such host", "i/o timeout", "connection refused", "deadline exceeded"}, "registry unreachable"},
+}
+
+// scanErrorStatus matches the HTTP status code of the registry errors, as in
+// "unexpected status code 429 Too Many Requests" or "HTTP 401".
+var scanErrorStatus = regexp.MustCompile(`(?i)\b(?:status(?: code)?:?|http(?:/[0-9.]+)?) ([0-9]{3})\b`)
+
+// scanErrorStatusCauses maps the HTTP status codes to their likely cause.
+var scanErrorStatusCauses = map[string]string{
+	"401": "registry authentication",
+	"403": "registry authentication",
+	"404": "repository not found",
+	"429": "registry rate limit",
+}
+
+// scanErrorCause returns the likely cause of an image repository scan
+// failure, given the message of the Ready condition.
+func scanErrorCause(msg string) string {
+	lower := strings.ToLower(msg)
+	for _, c := range scanErrorCauses {
+		for _, f := range c.fragments {
+			if strings.Contains(lower, strings.ToLower(f)) {
+				return c.cause
+			}
+		}
+	}
+	if m := scanErrorStatus.FindStringSubmatch(msg); m != nil {
+		if cause, ok := scanErrorStatusCauses[m[1]]; ok {
+			return cause
+		}
+	}
+	return "unknown"
+}
+
 func (s imageRepositoryListAdapter) summariseItem(i int, includeNamespace bool, includeKind bool) []string {
 	item := s.Items[i]
 	status, msg := statusAndMessage(item.Status.Conditions)
-	var lastScan string
+	var lastScan, tags string
 	if item.Status.LastScanResult != nil {
 		lastScan = item.Status.LastScanResult.ScanTime.Time.Format(time.RFC3339)
+		tags = strconv.Itoa(item.Status.LastScanResult.TagCount)
 	}
-	return append(nameColumns(&item, includeNamespace, includeKind),
-		status, msg, lastScan, strings.Title(strconv.FormatBool(item.Spec.Suspend)))
+	row := append(nameColumns(&item, includeNamespace, includeKind),
+		status, msg, lastScan, tags, strings.Title(strconv.FormatBool(item.Spec.Suspend)))
+	if getImageRepositoryArgs.showErrors {
+		row = append(row, scanErrorCause(msg))
+	}
+	return row
+}
+
+func (s imageRepositoryListAdapter) includeItem(i int) bool {
+	if !getImageRepositoryArgs.showErrors {
+		return true
+	}
+	status, _ := statusAndMessage(s.Items[i].Status.Conditions)
+	return status != string(metav1.ConditionTrue)
 }
 
 func (s imageRepositoryListAdapter) headers(includeNamespace bool) []string {
-	headers := []string{"Name", "Ready", "Message", "Last scan", "Suspended"}
+	headers := []string{"Name", "Ready", "Message", "Last scan", "Tags", "Suspended"}
+	if getImageRepositoryArgs.showErrors {
+		headers = append(headers, "Cause")
+	}
 	if includeNamespace {
 		return append(namespaceHeader, headers...)
 	}
@@ -95,5 +168,5 @@ func (s imageRepositoryListAdapter) headers(includeNamespace bool) []string {
 
 func (s imageRepositoryListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := s.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
@@ -0,0 +1,47 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestScanErrorCause(t *testing.T) {
+	cases := []struct {
+		msg    string
+		expect string
+	}{
+		{"GET https://index.docker.io/v2/library/alpine/tags/list: TOOMANYREQUESTS: You have reached your pull rate limit.", "registry rate limit"},
+		{"GET https://ghcr.io/v2/org/app/tags/list: UNAUTHORIZED: authentication required", "registry authentication"},
+		{`secrets "regcred" not found`, "missing secret"},
+		{"Get \"https://registry.local/v2/\": x509: certificate signed by unknown authority", "TLS certificate"},
+		{"dial tcp: lookup registry.local: no such host", "registry unreachable"},
+		{"GET https://registry.local/v2/app/tags/list: unexpected status code 429 Too Many Requests", "registry rate limit"},
+		{"HTTP 403 from registry.local", "registry authentication"},
+		{"pulling from registry.local:4291/app: connection reset by peer", "unknown"},
+		{"listing tags of registry.local/app-401: server closed the connection", "unknown"},
+		{"something else", "unknown"},
+	}
+	for _, tc := range cases {
+		if got := scanErrorCause(tc.msg); got != tc.expect {
+			t.Errorf("scanErrorCause(%q) expected %s, got %s", tc.msg, tc.expect, got)
+		}
+	}
+}
@@ -94,5 +94,5 @@ func (s imageUpdateAutomationListAdapter) headers(includeNamespace bool) []strin
 
 func (s imageUpdateAutomationListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := s.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
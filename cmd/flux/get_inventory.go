@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var getInventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Get the objects managed by Flux resources",
+	Long:  "The get inventory sub-commands print the objects recorded in the inventory of Flux resources.",
+}
+
+var getInventoryKsCmd = &cobra.Command{
+	Use:     "kustomization [name]",
+	Aliases: []string{"ks", "kustomizations"},
+	Short:   "Get the objects managed by a Kustomization",
+	Long: `The get inventory kustomization command prints the objects recorded in the inventory
+of a Kustomization with their status in the cluster.`,
+	Example: `  # List the objects managed by the root Kustomization
+  flux get inventory kustomization flux-system
+
+  # List the objects in JSON format
+  flux get inventory kustomization flux-system -o json`,
+	RunE:              getInventoryKsCmdRun,
+	ValidArgsFunction: resourceNamesCompletionFunc(kustomizev1.GroupVersion.WithKind(kustomizev1.KustomizationKind)),
+}
+
+func init() {
+	getInventoryCmd.AddCommand(getInventoryKsCmd)
+	getCmd.AddCommand(getInventoryCmd)
+}
+
+// inventoryEntry is an object of an inventory with its status in the cluster.
+type inventoryEntry struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+func getInventoryKsCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kustomization name is required")
+	}
+	name := args[0]
+
+	switch getArgs.output {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("unsupported output format '%s', must be one of: json, yaml", getArgs.output)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	k := &kustomizev1.Kustomization{}
+	err = kubeClient.Get(ctx, client.ObjectKey{
+		Namespace: *kubeconfigArgs.Namespace,
+		Name:      name,
+	}, k)
+	if err != nil {
+		return err
+	}
+
+	if k.Status.Inventory == nil || len(k.Status.Inventory.Entries) == 0 {
+		logger.Failuref("no objects found in the inventory of Kustomization %s/%s", k.Namespace, k.Name)
+		return nil
+	}
+
+	// The objects of a Kustomization targeting a remote cluster can't be read.
+	remote := k.Spec.KubeConfig != nil
+	entries, err := getInventoryEntries(ctx, kubeClient, k.Status.Inventory, remote)
+	if err != nil {
+		return err
+	}
+
+	switch getArgs.output {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	case "yaml":
+		data, err := yaml.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), string(data))
+	default:
+		var header []string
+		if !getArgs.noHeader {
+			header = []string{"Kind", "API version", "Namespace", "Name", "Status", "Message"}
+		}
+		var rows [][]string
+		for _, e := range entries {
+			rows = append(rows, []string{e.Kind, e.APIVersion, e.Namespace, e.Name, e.Status, e.Message})
+		}
+		utils.PrintTable(cmd.OutOrStdout(), header, rows)
+	}
+	return nil
+}
+
+// getInventoryEntries returns the objects of the inventory with the status
+// computed by kstatus, or NotFound if they are missing from the cluster.
+func getInventoryEntries(ctx context.Context, kubeClient client.Client, inventory *kustomizev1.ResourceInventory, skipStatus bool) ([]inventoryEntry, error) {
+	var entries []inventoryEntry
+	for _, ref := range inventory.Entries {
+		objMetadata, err := object.ParseObjMetadata(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		gvk := objMetadata.GroupKind.WithVersion(ref.Version)
+		entry := inventoryEntry{
+			Kind:       gvk.Kind,
+			APIVersion: gvk.GroupVersion().String(),
+			Namespace:  objMetadata.Namespace,
+			Name:       objMetadata.Name,
+			Status:     status.UnknownStatus.String(),
+		}
+
+		if !skipStatus {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(gvk)
+			err := kubeClient.Get(ctx, client.ObjectKey{Namespace: objMetadata.Namespace, Name: objMetadata.Name}, obj)
+			switch {
+			case apierrors.IsNotFound(err):
+				entry.Status = status.NotFoundStatus.String()
+			case err != nil:
+				entry.Message = err.Error()
+			default:
+				if res, err := status.Compute(obj); err != nil {
+					entry.Message = err.Error()
+				} else {
+					entry.Status = res.Status.String()
+					entry.Message = res.Message
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestGetInventoryEntries(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(configMap).Build()
+
+	inventory := &kustomizev1.ResourceInventory{
+		Entries: []kustomizev1.ResourceRef{
+			{ID: "apps_podinfo__ConfigMap", Version: "v1"},
+			{ID: "apps_frontend_apps_Deployment", Version: "v1"},
+		},
+	}
+
+	cases := []struct {
+		name       string
+		skipStatus bool
+		expect     []string
+	}{
+		{"status", false, []string{"Current", "NotFound"}},
+		{"remote cluster", true, []string{"Unknown", "Unknown"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := getInventoryEntries(context.TODO(), kubeClient, inventory, tc.skipStatus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 {
+				t.Fatalf("expected 2 entries, got %v", entries)
+			}
+			if e := entries[0]; e.Kind != "ConfigMap" || e.APIVersion != "v1" || e.Namespace != "apps" || e.Name != "podinfo" {
+				t.Errorf("unexpected entry %+v", e)
+			}
+			if e := entries[1]; e.Kind != "Deployment" || e.APIVersion != "apps/v1" || e.Namespace != "apps" || e.Name != "frontend" {
+				t.Errorf("unexpected entry %+v", e)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Status)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
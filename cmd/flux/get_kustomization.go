@@ -98,10 +98,13 @@ func (a kustomizationListAdapter) headers(includeNamespace bool) []string {
 
 func (a kustomizationListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := a.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
 
 func shortenCommitSha(msg string) string {
+	if getArgs.noTruncate {
+		return msg
+	}
 	r := regexp.MustCompile("/([a-f0-9]{40})$")
 	sha := r.FindString(msg)
 	if sha != "" {
@@ -0,0 +1,318 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+const (
+	wideOutput                = "wide"
+	goTemplateOutputPrefix    = "go-template="
+	customColumnsOutputPrefix = "custom-columns="
+)
+
+// validateOutputFormat returns an error if the output format is not supported
+// or if its template or columns can't be parsed.
+func validateOutputFormat(format string) error {
+	switch {
+	case format == "", format == wideOutput, format == "json", format == "yaml":
+		return nil
+	case strings.HasPrefix(format, goTemplateOutputPrefix):
+		_, err := parseGoTemplateOutput(strings.TrimPrefix(format, goTemplateOutputPrefix))
+		return err
+	case strings.HasPrefix(format, customColumnsOutputPrefix):
+		_, err := parseCustomColumnsOutput(strings.TrimPrefix(format, customColumnsOutputPrefix))
+		return err
+	default:
+		return fmt.Errorf("unsupported output format '%s', must be one of: wide, json, yaml, go-template=<template>, custom-columns=<spec>", format)
+	}
+}
+
+// listObjects returns the given list items as stored in the cluster, with
+// their kind set and without their managed fields.
+func listObjects(list summarisable, items []int) ([]runtime.Object, error) {
+	objects, err := apimeta.ExtractList(list.asClientList())
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := utils.NewScheme()
+	var result []runtime.Object
+	for _, i := range items {
+		obj, ok := objects[i].(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unexpected list item type %T", objects[i])
+		}
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return nil, err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		obj.SetManagedFields(nil)
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+// printObjects writes the given objects as a single v1 List in the
+// requested format.
+func printObjects(w io.Writer, objects []runtime.Object, format string) error {
+	result := struct {
+		APIVersion string           `json:"apiVersion"`
+		Kind       string           `json:"kind"`
+		Items      []runtime.Object `json:"items"`
+	}{
+		APIVersion: "v1",
+		Kind:       "List",
+		Items:      append([]runtime.Object{}, objects...),
+	}
+
+	switch {
+	case format == "json":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case format == "yaml":
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		return err
+	}
+
+	// The templates and the JSONPath expressions are evaluated against the
+	// JSON representation of the objects, like kubectl does.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(format, goTemplateOutputPrefix):
+		tmpl, err := parseGoTemplateOutput(strings.TrimPrefix(format, goTemplateOutputPrefix))
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(w, content); err != nil {
+			return fmt.Errorf("error executing template: %w", err)
+		}
+		return nil
+	case strings.HasPrefix(format, customColumnsOutputPrefix):
+		columns, err := parseCustomColumnsOutput(strings.TrimPrefix(format, customColumnsOutputPrefix))
+		if err != nil {
+			return err
+		}
+		return printCustomColumns(w, columns, content["items"].([]interface{}))
+	default:
+		return fmt.Errorf("unsupported output format '%s'", format)
+	}
+}
+
+func parseGoTemplateOutput(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("go-template format specified but no template given")
+	}
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+type customColumn struct {
+	header string
+	path   *jsonpath.JSONPath
+}
+
+// parseCustomColumnsOutput parses a comma-separated list of <header>:<json-path>
+// columns, the JSONPath expressions can be written without the braces and the
+// leading dot, e.g. NAME:.metadata.name or NAME:metadata.name.
+func parseCustomColumnsOutput(spec string) ([]customColumn, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
+	}
+	var columns []customColumn
+	for _, part := range strings.Split(spec, ",") {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("unexpected custom-columns spec '%s', expected <header>:<json-path>", part)
+		}
+		expr := kv[1]
+		if !strings.HasPrefix(expr, "{") {
+			expr = "{." + strings.TrimPrefix(expr, ".") + "}"
+		}
+		path := jsonpath.New(kv[0]).AllowMissingKeys(true)
+		if err := path.Parse(expr); err != nil {
+			return nil, fmt.Errorf("invalid custom-columns expression '%s': %w", kv[1], err)
+		}
+		columns = append(columns, customColumn{header: kv[0], path: path})
+	}
+	return columns, nil
+}
+
+func printCustomColumns(w io.Writer, columns []customColumn, items []interface{}) error {
+	var header []string
+	if !getArgs.noHeader {
+		for _, c := range columns {
+			header = append(header, c.header)
+		}
+	}
+	var rows [][]string
+	for _, item := range items {
+		var row []string
+		for _, c := range columns {
+			results, err := c.path.FindResults(item)
+			if err != nil {
+				return err
+			}
+			var values []string
+			for _, result := range results {
+				for _, v := range result {
+					values = append(values, fmt.Sprintf("%v", v.Interface()))
+				}
+			}
+			if len(values) == 0 {
+				values = []string{"<none>"}
+			}
+			row = append(row, strings.Join(values, ","))
+		}
+		rows = append(rows, row)
+	}
+	utils.PrintTable(w, header, rows)
+	return nil
+}
+
+var wideHeaders = []string{"Source", "Link", "Last handled reconcile"}
+
+// wideColumns returns the source, the web link of the resolved revision and
+// the last handled reconcile request of the given object.
+func wideColumns(obj runtime.Object) ([]string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	str := func(fields ...string) string {
+		v, _, _ := unstructured.NestedString(content, fields...)
+		return v
+	}
+	ref := func(kind string, fields ...string) string {
+		name := str(append(fields, "name")...)
+		if name == "" {
+			return ""
+		}
+		if k := str(append(fields, "kind")...); k != "" {
+			kind = k
+		}
+		if ns := str(append(fields, "namespace")...); ns != "" {
+			return fmt.Sprintf("%s/%s/%s", kind, ns, name)
+		}
+		return fmt.Sprintf("%s/%s", kind, name)
+	}
+
+	var source, link string
+	switch {
+	case str("spec", "url") != "":
+		source = str("spec", "url")
+		link = commitLink(source, str("status", "artifact", "revision"))
+	case str("spec", "bucketName") != "":
+		source = str("spec", "endpoint") + "/" + str("spec", "bucketName")
+	case str("spec", "image") != "":
+		source = str("spec", "image")
+	case str("spec", "sourceRef", "name") != "":
+		source = ref("", "spec", "sourceRef")
+	case str("spec", "chart", "spec", "sourceRef", "name") != "":
+		source = ref("", "spec", "chart", "spec", "sourceRef")
+	case str("spec", "imageRepositoryRef", "name") != "":
+		source = ref("ImageRepository", "spec", "imageRepositoryRef")
+		link = imageLink(str("status", "latestImage"))
+	case str("spec", "providerRef", "name") != "":
+		source = ref("Provider", "spec", "providerRef")
+	}
+
+	return []string{source, link, str("status", "lastHandledReconcileAt")}, nil
+}
+
+var commitLinkFormats = map[string]string{
+	"github.com":    "https://github.com/%s/commit/%s",
+	"gitlab.com":    "https://gitlab.com/%s/-/commit/%s",
+	"bitbucket.org": "https://bitbucket.org/%s/commits/%s",
+}
+
+// commitLink returns the web page of the commit of a '<branch>/<sha>' revision
+// for the repositories hosted on GitHub, GitLab and Bitbucket.
+func commitLink(repoURL, revision string) string {
+	i := strings.LastIndex(revision, "/")
+	if i < 0 || len(revision)-i-1 != 40 {
+		return ""
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	format, ok := commitLinkFormats[u.Hostname()]
+	if !ok {
+		return ""
+	}
+	repo := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	return fmt.Sprintf(format, repo, revision[i+1:])
+}
+
+// imageLink returns the web page of the tags of the given image for the
+// repositories hosted on Docker Hub and Quay.
+func imageLink(image string) string {
+	tag, err := name.NewTag(image)
+	if err != nil {
+		return ""
+	}
+	ref := tag.Context()
+	switch ref.RegistryStr() {
+	case name.DefaultRegistry:
+		path := strings.TrimPrefix(ref.RepositoryStr(), "library/")
+		if path != ref.RepositoryStr() {
+			return fmt.Sprintf("https://hub.docker.com/_/%s?tab=tags", path)
+		}
+		return fmt.Sprintf("https://hub.docker.com/r/%s/tags", path)
+	case "quay.io":
+		return fmt.Sprintf("https://quay.io/repository/%s?tab=tags", ref.RepositoryStr())
+	}
+	return ""
+}
@@ -0,0 +1,217 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+)
+
+func TestValidateOutputFormat(t *testing.T) {
+	cases := []struct {
+		format    string
+		expectErr bool
+	}{
+		{"", false},
+		{"json", false},
+		{"yaml", false},
+		{"go-template={{range .items}}{{.metadata.name}}{{end}}", false},
+		{"go-template=", true},
+		{"go-template={{range .items}}", true},
+		{"custom-columns=NAME:.metadata.name,REV:status.lastAppliedRevision", false},
+		{"custom-columns=NAME", true},
+		{"custom-columns=NAME:{.metadata.name", true},
+		{"wide", false},
+		{"table", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			err := validateOutputFormat(tc.format)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestPrintObjects(t *testing.T) {
+	list := &kustomizationListAdapter{&kustomizev1.KustomizationList{
+		Items: []kustomizev1.Kustomization{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"},
+				Status:     kustomizev1.KustomizationStatus{LastAppliedRevision: "main/1234"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "infra", Namespace: "flux-system"},
+				Status:     kustomizev1.KustomizationStatus{LastAppliedRevision: "main/5678"},
+			},
+		},
+	}}
+
+	cases := []struct {
+		format string
+		expect []string
+	}{
+		{"yaml", []string{"---\napiVersion: v1\n", "kind: List", "apiVersion: kustomize.toolkit.fluxcd.io/v1beta2", "name: infra"}},
+		{"json", []string{`"kind": "List"`, `"kind": "Kustomization"`, `"name": "infra"`}},
+		{"go-template={{range .items}}{{.metadata.name}}={{.status.lastAppliedRevision}}{{end}}", []string{"infra=main/5678"}},
+		{"custom-columns=NAME:.metadata.name,REV:status.lastAppliedRevision,SUSPENDED:.spec.suspend", []string{"NAME", "REV", "infra", "main/5678", "<none>"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			objects, err := listObjects(list, []int{1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := printObjects(&out, objects, tc.format); err != nil {
+				t.Fatal(err)
+			}
+			got := out.String()
+			for _, s := range tc.expect {
+				if !strings.Contains(got, s) {
+					t.Errorf("expected %s in:\n%s", s, got)
+				}
+			}
+			if strings.Contains(got, "main/1234") {
+				t.Errorf("expected the filtered out item to be skipped:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestPrintObjectsOfSeveralKinds(t *testing.T) {
+	kustomizations := &kustomizationListAdapter{&kustomizev1.KustomizationList{
+		Items: []kustomizev1.Kustomization{
+			{ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"}},
+		},
+	}}
+	repositories := &gitRepositoryListAdapter{&sourcev1.GitRepositoryList{
+		Items: []sourcev1.GitRepository{
+			{ObjectMeta: metav1.ObjectMeta{Name: "flux-system", Namespace: "flux-system"}},
+		},
+	}}
+
+	var objects []runtime.Object
+	for _, list := range []summarisable{repositories, kustomizations} {
+		items, err := listObjects(list, []int{0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, items...)
+	}
+
+	var out bytes.Buffer
+	if err := printObjects(&out, objects, "json"); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if n := strings.Count(got, `"kind": "List"`); n != 1 {
+		t.Errorf("expected a single List, got %d:\n%s", n, got)
+	}
+	for _, s := range []string{`"kind": "GitRepository"`, `"kind": "Kustomization"`} {
+		if !strings.Contains(got, s) {
+			t.Errorf("expected %s in:\n%s", s, got)
+		}
+	}
+}
+
+func TestWideColumns(t *testing.T) {
+	sha := "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"
+	cases := []struct {
+		name   string
+		obj    runtime.Object
+		expect []string
+	}{
+		{
+			name: "git repository",
+			obj: &sourcev1.GitRepository{
+				Spec: sourcev1.GitRepositorySpec{URL: "ssh://git@github.com/fluxcd/flux2.git"},
+				Status: sourcev1.GitRepositoryStatus{
+					Artifact:               &sourcev1.Artifact{Revision: "main/" + sha},
+					ReconcileRequestStatus: meta.ReconcileRequestStatus{LastHandledReconcileAt: "2022-01-01T00:00:00Z"},
+				},
+			},
+			expect: []string{"ssh://git@github.com/fluxcd/flux2.git", "https://github.com/fluxcd/flux2/commit/" + sha, "2022-01-01T00:00:00Z"},
+		},
+		{
+			name: "self-hosted git repository",
+			obj: &sourcev1.GitRepository{
+				Spec:   sourcev1.GitRepositorySpec{URL: "https://git.example.com/apps.git"},
+				Status: sourcev1.GitRepositoryStatus{Artifact: &sourcev1.Artifact{Revision: "main/" + sha}},
+			},
+			expect: []string{"https://git.example.com/apps.git", "", ""},
+		},
+		{
+			name: "kustomization",
+			obj: &kustomizev1.Kustomization{
+				Spec: kustomizev1.KustomizationSpec{
+					SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "flux-system"},
+				},
+			},
+			expect: []string{"GitRepository/flux-system", "", ""},
+		},
+		{
+			name: "image policy",
+			obj: &imagev1.ImagePolicy{
+				Spec: imagev1.ImagePolicySpec{
+					ImageRepositoryRef: meta.NamespacedObjectReference{Name: "podinfo", Namespace: "apps"},
+				},
+				Status: imagev1.ImagePolicyStatus{LatestImage: "quay.io/stefanprodan/podinfo:6.0.0"},
+			},
+			expect: []string{"ImageRepository/apps/podinfo", "https://quay.io/repository/stefanprodan/podinfo?tab=tags", ""},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := wideColumns(tc.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestImageLink(t *testing.T) {
+	cases := map[string]string{
+		"nginx:1.21":                     "https://hub.docker.com/_/nginx?tab=tags",
+		"stefanprodan/podinfo:6.0.0":     "https://hub.docker.com/r/stefanprodan/podinfo/tags",
+		"ghcr.io/stefanprodan/podinfo:6": "",
+		"not an image":                   "",
+	}
+	for image, expect := range cases {
+		if got := imageLink(image); got != expect {
+			t.Errorf("%s: expected %q, got %q", image, expect, got)
+		}
+	}
+}
@@ -17,23 +17,37 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
+// receiverWebhookHostAnnotation can be set on the webhook-receiver service
+// to the external address the receivers are exposed on.
+const receiverWebhookHostAnnotation = "notification.toolkit.fluxcd.io/webhook-host"
+
 var getReceiverCmd = &cobra.Command{
 	Use:     "receivers",
 	Aliases: []string{"receiver"},
 	Short:   "Get Receiver statuses",
 	Long:    "The get receiver command prints the statuses of the resources.",
 	Example: `  # List all Receiver and their status
-  flux get receivers`,
+  flux get receivers
+
+  # Print the webhook URLs to configure on the senders
+  flux get receivers --show-url --webhook-host=https://flux-webhook.example.com`,
 	ValidArgsFunction: resourceNamesCompletionFunc(notificationv1.GroupVersion.WithKind(notificationv1.ReceiverKind)),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		get := getCommand{
@@ -59,6 +73,18 @@ var getReceiverCmd = &cobra.Command{
 			return err
 		}
 
+		if getReceiverArgs.showURL {
+			get.prepare = func(rcg genericclioptions.RESTClientGetter) error {
+				receiverHost = getReceiverArgs.webhookHost
+				if receiverHost != "" {
+					return nil
+				}
+				var err error
+				receiverHost, err = receiverWebhookHost(rcg, getReceiverArgs.fluxNamespace)
+				return err
+			}
+		}
+
 		if err := get.run(cmd, args); err != nil {
 			return err
 		}
@@ -67,18 +93,77 @@ var getReceiverCmd = &cobra.Command{
 	},
 }
 
+type getReceiverFlags struct {
+	showURL       bool
+	webhookHost   string
+	fluxNamespace string
+}
+
+var getReceiverArgs getReceiverFlags
+
+// receiverHost holds the external address of the webhook receiver of the
+// cluster whose receivers are printed.
+var receiverHost string
+
 func init() {
+	getReceiverCmd.Flags().BoolVar(&getReceiverArgs.showURL, "show-url", false,
+		"print the webhook URL of the receivers")
+	getReceiverCmd.Flags().StringVar(&getReceiverArgs.webhookHost, "webhook-host", "",
+		fmt.Sprintf("external address of the webhook receiver, defaults to the %s annotation of the webhook-receiver service", receiverWebhookHostAnnotation))
+	getReceiverCmd.Flags().StringVar(&getReceiverArgs.fluxNamespace, "flux-namespace", rootArgs.defaults.Namespace,
+		"the namespace where the Flux components are running")
 	getCmd.AddCommand(getReceiverCmd)
 }
 
+// receiverWebhookHost returns the external address recorded on the
+// webhook-receiver service of the given cluster, or an empty string if there
+// is none.
+func receiverWebhookHost(rcg genericclioptions.RESTClientGetter, namespace string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(rcg)
+	if err != nil {
+		return "", err
+	}
+
+	var svc corev1.Service
+	if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "webhook-receiver"}, &svc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return svc.Annotations[receiverWebhookHostAnnotation], nil
+}
+
+// receiverWebhookURL joins the webhook path from the Receiver status with
+// the external host, https is assumed when the host has no scheme.
+func receiverWebhookURL(host, path string) string {
+	if path == "" || host == "" {
+		return path
+	}
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return strings.TrimSuffix(host, "/") + path
+}
+
 func (s receiverListAdapter) summariseItem(i int, includeNamespace bool, includeKind bool) []string {
 	item := s.Items[i]
 	status, msg := statusAndMessage(item.Status.Conditions)
-	return append(nameColumns(&item, includeNamespace, includeKind), status, msg, strings.Title(strconv.FormatBool(item.Spec.Suspend)))
+	row := append(nameColumns(&item, includeNamespace, includeKind), status, msg, strings.Title(strconv.FormatBool(item.Spec.Suspend)))
+	if getReceiverArgs.showURL {
+		row = append(row, receiverWebhookURL(receiverHost, item.Status.URL))
+	}
+	return row
 }
 
 func (s receiverListAdapter) headers(includeNamespace bool) []string {
 	headers := []string{"Name", "Ready", "Message", "Suspended"}
+	if getReceiverArgs.showURL {
+		headers = append(headers, "URL")
+	}
 	if includeNamespace {
 		return append(namespaceHeader, headers...)
 	}
@@ -87,5 +172,5 @@ func (s receiverListAdapter) headers(includeNamespace bool) []string {
 
 func (s receiverListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := s.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
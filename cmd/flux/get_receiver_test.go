@@ -0,0 +1,45 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestReceiverWebhookURL(t *testing.T) {
+	cases := []struct {
+		name   string
+		host   string
+		path   string
+		expect string
+	}{
+		{"host with scheme", "http://flux-webhook.example.com/", "/hook/abc", "http://flux-webhook.example.com/hook/abc"},
+		{"host without scheme", "flux-webhook.example.com", "/hook/abc", "https://flux-webhook.example.com/hook/abc"},
+		{"no host", "", "/hook/abc", "/hook/abc"},
+		{"not ready", "flux-webhook.example.com", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := receiverWebhookURL(tc.host, tc.path); got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
@@ -94,5 +94,5 @@ func (a bucketListAdapter) headers(includeNamespace bool) []string {
 
 func (a bucketListAdapter) statusSelectorMatches(i int, conditionType, conditionStatus string) bool {
 	item := a.Items[i]
-	return statusMatches(conditionType, conditionStatus, item.Status.Conditions)
+	return statusMatches(conditionType, conditionStatus, item.Status.Conditions, item.Spec.Suspend)
 }
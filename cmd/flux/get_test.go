@@ -0,0 +1,230 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/meta"
+)
+
+func TestWatchUntilPrintsChanges(t *testing.T) {
+	get := &getCommand{
+		apiType: kustomizationType,
+		funcMap: make(typeMap),
+	}
+	err := get.funcMap.registerCommand(get.apiType.kind, func(obj runtime.Object) (summarisable, error) {
+		o := obj.(*kustomizev1.Kustomization)
+		return kustomizationListAdapter{&kustomizev1.KustomizationList{Items: []kustomizev1.Kustomization{*o}}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := func(status metav1.ConditionStatus, message string) *kustomizev1.Kustomization {
+		return &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"},
+			Status: kustomizev1.KustomizationStatus{
+				Conditions: []metav1.Condition{{Type: meta.ReadyCondition, Status: status, Message: message}},
+			},
+		}
+	}
+
+	w := watch.NewFake()
+	go func() {
+		w.Add(ks(metav1.ConditionUnknown, "reconciliation in progress"))
+		w.Modify(ks(metav1.ConditionUnknown, "reconciliation in progress"))
+		w.Modify(ks(metav1.ConditionTrue, "Applied revision: main/1234"))
+		w.Delete(ks(metav1.ConditionTrue, "Applied revision: main/1234"))
+		w.Stop()
+	}()
+
+	var out bytes.Buffer
+	_, err = watchUntil(context.TODO(), w, get, &out)
+	if err != watchtools.ErrWatchClosed {
+		t.Fatalf("expected the watch to be closed, got %v", err)
+	}
+
+	got := out.String()
+	if n := strings.Count(got, "reconciliation in progress"); n != 1 {
+		t.Errorf("expected the unchanged row to be printed once, got %d times:\n%s", n, got)
+	}
+	if !strings.Contains(got, "Applied revision: main/1234") {
+		t.Errorf("expected the updated row to be printed:\n%s", got)
+	}
+	if n := strings.Count(got, "NAME"); n != 1 {
+		t.Errorf("expected a single header, got %d:\n%s", n, got)
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	now := time.Now()
+	ks := func(name string, status metav1.ConditionStatus, age time.Duration) kustomizev1.Kustomization {
+		return kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flux-system"},
+			Status: kustomizev1.KustomizationStatus{
+				ReconcileRequestStatus: meta.ReconcileRequestStatus{
+					LastHandledReconcileAt: now.Add(-age).Format(time.RFC3339Nano),
+				},
+				Conditions: []metav1.Condition{{
+					Type:               meta.ReadyCondition,
+					Status:             status,
+					LastTransitionTime: metav1.NewTime(now.Add(age)),
+				}},
+			},
+		}
+	}
+	list := &kustomizationListAdapter{&kustomizev1.KustomizationList{
+		Items: []kustomizev1.Kustomization{
+			ks("apps", metav1.ConditionTrue, time.Minute),
+			ks("infra", metav1.ConditionFalse, time.Second),
+			ks("crds", metav1.ConditionUnknown, time.Hour),
+			{ObjectMeta: metav1.ObjectMeta{Name: "tenants", Namespace: "flux-system"}},
+		},
+	}}
+
+	cases := []struct {
+		sortBy string
+		expect []int
+	}{
+		{"name", []int{0, 2, 1, 3}},
+		{"kind", []int{0, 2, 1, 3}},
+		{"ready", []int{1, 2, 3, 0}},
+		{"last-reconcile", []int{3, 2, 0, 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.sortBy, func(t *testing.T) {
+			items := []int{0, 1, 2, 3}
+			if err := sortItems(list, items, tc.sortBy); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, tc.expect) {
+				t.Errorf("expected %v, got %v", tc.expect, items)
+			}
+		})
+	}
+}
+
+func TestStatusMatches(t *testing.T) {
+	conditions := []metav1.Condition{
+		{Type: meta.ReadyCondition, Status: metav1.ConditionFalse},
+		{Type: "Stalled", Status: metav1.ConditionTrue},
+	}
+
+	cases := []struct {
+		selector   string
+		conditions []metav1.Condition
+		suspended  bool
+		expect     bool
+	}{
+		{"ready=false", conditions, false, true},
+		{"Ready=False", conditions, false, true},
+		{"ready=true", conditions, false, false},
+		{"stalled=true", conditions, false, true},
+		{"ready=unknown", nil, false, true},
+		{"ready=false", nil, false, false},
+		{"suspended=true", conditions, true, true},
+		{"suspended=true", conditions, false, false},
+		{"suspended=false", nil, false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.selector, func(t *testing.T) {
+			parts := strings.SplitN(tc.selector, "=", 2)
+			if got := statusMatches(parts[0], parts[1], tc.conditions, tc.suspended); got != tc.expect {
+				t.Errorf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestGetItemsToPrintSuspended(t *testing.T) {
+	list := &kustomizationListAdapter{&kustomizev1.KustomizationList{
+		Items: []kustomizev1.Kustomization{
+			{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "infra"}, Spec: kustomizev1.KustomizationSpec{Suspend: true}},
+		},
+	}}
+
+	cases := []struct {
+		name          string
+		omitSuspended bool
+		onlySuspended bool
+		expect        []int
+	}{
+		{"all", false, false, []int{0, 1}},
+		{"omit suspended", true, false, []int{0}},
+		{"only suspended", false, true, []int{1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			getArgs.omitSuspended, getArgs.onlySuspended = tc.omitSuspended, tc.onlySuspended
+			defer func() { getArgs.omitSuspended, getArgs.onlySuspended = false, false }()
+
+			items, err := getItemsToPrint(list)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, tc.expect) {
+				t.Errorf("expected %v, got %v", tc.expect, items)
+			}
+		})
+	}
+}
+
+func TestGetCommandPrintAll(t *testing.T) {
+	get := getCommand{
+		apiType: kustomizationType,
+		list:    &kustomizationListAdapter{&kustomizev1.KustomizationList{}},
+	}
+
+	var out bytes.Buffer
+	if err := get.print(&out, true); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output for an empty list, got %q", out.String())
+	}
+
+	get.list = &kustomizationListAdapter{&kustomizev1.KustomizationList{
+		Items: []kustomizev1.Kustomization{
+			{ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"}},
+		},
+	}}
+	if err := get.print(&out, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "/apps") {
+		t.Errorf("expected the object in the table, got %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "\n\n") {
+		t.Errorf("expected the table to be followed by an empty line, got %q", out.String())
+	}
+}
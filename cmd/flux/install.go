@@ -21,9 +21,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fluxcd/flux2/internal/flags"
 	"github.com/fluxcd/flux2/internal/utils"
@@ -45,6 +48,12 @@ If a previous version is installed, then an in-place upgrade will be performed.`
   # Install Flux onto tainted Kubernetes nodes
   flux install --toleration-keys=node.kubernetes.io/dedicated-to-flux
 
+  # Install Flux with a pod disruption budget for each controller
+  flux install --with-pdb --pdb-max-unavailable=1
+
+  # Install Flux and restrict the controllers egress traffic to GitHub
+  flux install --egress-allow=github.com:443,github.com:22
+
   # Dry-run install
   flux install --export | kubectl apply --dry-run=client -f- 
 
@@ -70,6 +79,9 @@ type installFlags struct {
 	tokenAuth          bool
 	clusterDomain      string
 	tolerationKeys     []string
+	egressAllow        []string
+	withPDB            bool
+	pdbMaxUnavailable  int
 }
 
 var installArgs = NewInstallFlags()
@@ -80,12 +92,14 @@ func init() {
 	installCmd.Flags().BoolVarP(&installArgs.dryRun, "dry-run", "", false,
 		"only print the object that would be applied")
 	installCmd.Flags().StringVarP(&installArgs.version, "version", "v", "",
-		"toolkit version, when specified the manifests are downloaded from https://github.com/fluxcd/flux2/releases")
+		"toolkit version, when specified the manifests are downloaded from https://github.com/fluxcd/flux2/releases, "+
+			"the manifests of the CLI version are embedded in the binary")
 	installCmd.Flags().StringSliceVar(&installArgs.defaultComponents, "components", rootArgs.defaults.Components,
 		"list of components, accepts comma-separated values")
 	installCmd.Flags().StringSliceVar(&installArgs.extraComponents, "components-extra", nil,
 		"list of components in addition to those supplied or defaulted, accepts comma-separated values")
-	installCmd.Flags().StringVar(&installArgs.manifestsPath, "manifests", "", "path to the manifest directory")
+	installCmd.Flags().StringVar(&installArgs.manifestsPath, "manifests", "",
+		"path to the manifest directory, overrides the embedded manifests")
 	installCmd.Flags().StringVar(&installArgs.registry, "registry", rootArgs.defaults.Registry,
 		"container registry where the toolkit images are published")
 	installCmd.Flags().StringVar(&installArgs.imagePullSecret, "image-pull-secret", "",
@@ -99,6 +113,15 @@ func init() {
 	installCmd.Flags().StringVar(&installArgs.clusterDomain, "cluster-domain", rootArgs.defaults.ClusterDomain, "internal cluster domain")
 	installCmd.Flags().StringSliceVar(&installArgs.tolerationKeys, "toleration-keys", nil,
 		"list of toleration keys used to schedule the components pods onto nodes with matching taints")
+	installCmd.Flags().StringSliceVar(&installArgs.egressAllow, "egress-allow", nil,
+		"list of destinations in the format '<host|cidr>:<port>' the controllers are allowed to connect to, "+
+			"when specified the network policies deny egress traffic to any other destination; "+
+			"host names are resolved once to the IP addresses they point to, the network policies must be "+
+			"regenerated when these addresses change")
+	installCmd.Flags().BoolVar(&installArgs.withPDB, "with-pdb", rootArgs.defaults.PodDisruptionBudget,
+		"generate a pod disruption budget for each controller")
+	installCmd.Flags().IntVar(&installArgs.pdbMaxUnavailable, "pdb-max-unavailable", rootArgs.defaults.PDBMaxUnavailable,
+		"maximum number of unavailable pods of each controller set in the pod disruption budgets, must be at least 1 so that the nodes can be drained")
 	installCmd.Flags().MarkHidden("manifests")
 	installCmd.Flags().MarkDeprecated("arch", "multi-arch container image is now available for AMD64, ARMv7 and ARM64")
 	installCmd.Flags().MarkDeprecated("dry-run", "use 'flux install --export | kubectl apply --dry-run=client -f-'")
@@ -127,6 +150,19 @@ func installCmdRun(cmd *cobra.Command, args []string) error {
 		installArgs.version = ver
 	}
 
+	if len(installArgs.egressAllow) > 0 && !installArgs.networkPolicy {
+		return fmt.Errorf("--egress-allow requires --network-policy to be enabled")
+	}
+
+	if installArgs.withPDB && installArgs.pdbMaxUnavailable < 1 {
+		return fmt.Errorf("--pdb-max-unavailable must be at least 1, otherwise the single replica of each controller can't be evicted")
+	}
+
+	egressRules, err := makeEgressRules(ctx, installArgs.egressAllow)
+	if err != nil {
+		return err
+	}
+
 	if !installArgs.export {
 		logger.Generatef("generating manifests")
 	}
@@ -138,7 +174,7 @@ func installCmdRun(cmd *cobra.Command, args []string) error {
 	defer os.RemoveAll(tmpDir)
 
 	manifestsBase := ""
-	if isEmbeddedVersion(installArgs.version) {
+	if isEmbeddedVersion(installArgs.version) && installArgs.manifestsPath == "" {
 		if err := writeEmbeddedManifests(tmpDir); err != nil {
 			return err
 		}
@@ -160,6 +196,9 @@ func installCmdRun(cmd *cobra.Command, args []string) error {
 		Timeout:                rootArgs.timeout,
 		ClusterDomain:          installArgs.clusterDomain,
 		TolerationKeys:         installArgs.tolerationKeys,
+		EgressRules:            egressRules,
+		PodDisruptionBudget:    installArgs.withPDB,
+		PDBMaxUnavailable:      installArgs.pdbMaxUnavailable,
 	}
 
 	if installArgs.manifestsPath == "" {
@@ -217,3 +256,55 @@ func installCmdRun(cmd *cobra.Command, args []string) error {
 	logger.Successf("install finished")
 	return nil
 }
+
+// makeEgressRules converts the given destinations to network policy egress rules.
+// The Kubernetes API server endpoints are added to the rules when the cluster
+// can be reached, as the controllers can't function without access to the API.
+func makeEgressRules(ctx context.Context, destinations []string) ([]install.EgressRule, error) {
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+
+	var egressRules []flags.EgressRule
+	for _, destination := range destinations {
+		var rule flags.EgressRule
+		if err := rule.Set(destination); err != nil {
+			return nil, err
+		}
+		egressRules = append(egressRules, rule)
+	}
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err == nil {
+		var endpoints corev1.Endpoints
+		err = kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "kubernetes"}, &endpoints)
+		for _, subset := range endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				for _, port := range subset.Ports {
+					egressRules = append(egressRules, flags.EgressRule{Host: address.IP, Port: int(port.Port)})
+				}
+			}
+		}
+	}
+	if err != nil {
+		logger.Warningf("Kubernetes API server endpoints can't be determined, " +
+			"make sure the --egress-allow list contains the API server address")
+	}
+
+	var rules []install.EgressRule
+	for _, rule := range egressRules {
+		cidrs, err := rule.CIDRs()
+		if err != nil {
+			return nil, err
+		}
+		if rule.IsHostname() {
+			logger.Actionf("%s resolved to %s, regenerate the network policies when its addresses change",
+				rule.Host, strings.Join(cidrs, ", "))
+		}
+		for _, cidr := range cidrs {
+			rules = append(rules, install.EgressRule{CIDR: cidr, Port: rule.Port})
+		}
+	}
+
+	return rules, nil
+}
@@ -121,6 +121,8 @@ var kubeconfigArgs = genericclioptions.NewConfigFlags(false)
 func init() {
 	rootCmd.PersistentFlags().DurationVar(&rootArgs.timeout, "timeout", 5*time.Minute, "timeout for this operation")
 	rootCmd.PersistentFlags().BoolVar(&rootArgs.verbose, "verbose", false, "print generated objects")
+	rootCmd.PersistentFlags().DurationVar(&rootArgs.pollInterval, "poll-interval", rootArgs.pollInterval,
+		"how often the cluster is polled for the latest state of the resources that can't be watched")
 
 	configureDefaultNamespace()
 	kubeconfigArgs.APIServer = nil // prevent AddFlags from configuring --server flag
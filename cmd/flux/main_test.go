@@ -371,6 +371,7 @@ func resetCmdArgs() {
 	createArgs = createFlags{}
 	getArgs = GetFlags{}
 	secretGitArgs = NewSecretGitFlags()
+	sourceBucketArgs = NewSourceBucketFlags()
 }
 
 func isChangeError(err error) bool {
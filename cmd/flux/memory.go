@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// memoryCheckInterval is how often the heap is compared with the memory limit.
+var memoryCheckInterval = 250 * time.Millisecond
+
+// parseMemoryLimit returns the number of bytes of a quantity such as 512Mi,
+// zero meaning no limit.
+func parseMemoryLimit(limit string) (uint64, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(limit)
+	if err != nil || q.Sign() < 0 {
+		return 0, fmt.Errorf("invalid memory limit '%s', must be a quantity such as 512Mi", limit)
+	}
+	return uint64(q.Value()), nil
+}
+
+// watchMemory returns a channel that receives an error when the heap of the
+// process exceeds limit bytes. The channel never receives when there's no limit.
+func watchMemory(ctx context.Context, limit uint64) <-chan error {
+	errc := make(chan error, 1)
+	if limit == 0 {
+		return errc
+	}
+
+	go func() {
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > limit {
+				errc <- fmt.Errorf("memory usage of %s exceeded the limit of %s",
+					resource.NewQuantity(int64(stats.HeapAlloc), resource.BinarySI),
+					resource.NewQuantity(int64(limit), resource.BinarySI))
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return errc
+}
@@ -0,0 +1,71 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseMemoryLimit(t *testing.T) {
+	tests := []struct {
+		limit    string
+		expected uint64
+		wantErr  bool
+	}{
+		{limit: "", expected: 0},
+		{limit: "512Mi", expected: 512 * 1024 * 1024},
+		{limit: "1G", expected: 1000 * 1000 * 1000},
+		{limit: "-1Gi", wantErr: true},
+		{limit: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			got, err := parseMemoryLimit(tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMemoryLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("parseMemoryLimit() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWatchMemory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	select {
+	case err := <-watchMemory(ctx, 1):
+		if err == nil {
+			t.Error("expected an error when the limit is exceeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected the limit of 1 byte to be exceeded")
+	}
+
+	select {
+	case err := <-watchMemory(ctx, 0):
+		t.Errorf("unexpected error without limit: %v", err)
+	case <-time.After(2 * memoryCheckInterval):
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/flux2/internal/utils"
+	"github.com/fluxcd/flux2/pkg/manifestgen"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the Flux custom resources to the latest storage version",
+	Long: `The migrate command rewrites the Flux custom resources stored in a deprecated API version
+to the storage version of their CRD, then removes the deprecated versions from the CRD status.
+Migrating is required before upgrading to a Flux version that no longer serves the deprecated APIs.`,
+	Example: `  # Print the custom resources that need to be migrated
+  flux migrate --dry-run
+
+  # Migrate the custom resources to the latest storage version
+  flux migrate`,
+	RunE: migrateCmdRun,
+}
+
+type migrateFlags struct {
+	dryRun bool
+}
+
+var migrateArgs migrateFlags
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateArgs.dryRun, "dry-run", false,
+		"only print the objects that would be migrated")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrateCmdRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	result, err := migrateCustomResources(ctx, kubeClient, migrateArgs.dryRun)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case result.crds == 0:
+		logger.Successf("no custom resources need to be migrated")
+	case migrateArgs.dryRun:
+		logger.Successf("%d custom resources of %d CustomResourceDefinitions would be migrated", result.migrated, result.crds)
+	default:
+		logger.Successf("migrated %d custom resources (%d already stored in the latest version) and the stored versions of %d CustomResourceDefinitions",
+			result.migrated, result.unchanged, result.crds)
+	}
+	return nil
+}
+
+type migrateResult struct {
+	crds      int
+	migrated  int
+	unchanged int
+}
+
+// migrateCustomResources rewrites the objects of the Flux CRDs that have
+// deprecated stored versions, then sets the stored versions of those CRDs to
+// their storage version.
+func migrateCustomResources(ctx context.Context, kubeClient client.Client, dryRun bool) (migrateResult, error) {
+	var result migrateResult
+
+	selector := client.MatchingLabels{manifestgen.PartOfLabelKey: manifestgen.PartOfLabelValue}
+	var list apiextensionsv1.CustomResourceDefinitionList
+	if err := kubeClient.List(ctx, &list, selector); err != nil {
+		return result, fmt.Errorf("listing custom resource definitions failed: %w", err)
+	}
+
+	opts, dryRunStr := getUpdateOptions(dryRun)
+	for _, crd := range list.Items {
+		versions := deprecatedStoredVersions(crd)
+		if len(versions) == 0 {
+			continue
+		}
+
+		storage := storageVersion(crd)
+		logger.Actionf("migrating %s from %v to %s", crd.Name, versions, storage)
+
+		objects, err := listCustomResources(ctx, kubeClient, crd)
+		if err != nil {
+			return result, fmt.Errorf("listing %s failed: %w", crd.Spec.Names.Kind, err)
+		}
+
+		for _, obj := range objects {
+			// An update with the object read at the storage version makes
+			// the API server persist it again in that version. The objects
+			// already stored in that version are left as is, so their
+			// resource version doesn't change.
+			var resourceVersion string
+			err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+				if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(&obj), &obj); err != nil {
+					return err
+				}
+				resourceVersion = obj.GetResourceVersion()
+				return kubeClient.Update(ctx, &obj, opts)
+			})
+			if err != nil {
+				return result, fmt.Errorf("%s/%s/%s migration failed: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+			}
+			if !dryRun && obj.GetResourceVersion() == resourceVersion {
+				result.unchanged++
+				continue
+			}
+			logger.Successf("%s/%s/%s migrated to %s/%s %s", obj.GetKind(), obj.GetNamespace(), obj.GetName(),
+				crd.Spec.Group, storage, dryRunStr)
+			result.migrated++
+		}
+		result.crds++
+
+		if dryRun {
+			continue
+		}
+
+		err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(&crd), &crd); err != nil {
+				return err
+			}
+			crd.Status.StoredVersions = []string{storage}
+			return kubeClient.Status().Update(ctx, &crd)
+		})
+		if err != nil {
+			return result, fmt.Errorf("updating %s stored versions failed: %w", crd.Name, err)
+		}
+		logger.Successf("CustomResourceDefinition/%s stored versions set to %s", crd.Name, storage)
+	}
+	return result, nil
+}
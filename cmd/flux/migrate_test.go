@@ -0,0 +1,109 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+// migrateTestClient simulates the API server behaviour the migration relies
+// on: updates of objects already stored in the latest version are no-ops, and
+// an object modified concurrently returns a conflict.
+type migrateTestClient struct {
+	client.Client
+	stored    map[string]bool
+	conflicts map[string]int
+}
+
+func (c *migrateTestClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	key := obj.GetNamespace() + "/" + obj.GetName()
+	if c.conflicts[key] > 0 {
+		c.conflicts[key]--
+		return apierrors.NewConflict(schema.GroupResource{Resource: "widgets"}, obj.GetName(), nil)
+	}
+	if c.stored[key] {
+		return nil
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestMigrateCustomResources(t *testing.T) {
+	tests := []struct {
+		name           string
+		dryRun         bool
+		expected       migrateResult
+		storedVersions []string
+	}{
+		{
+			name:           "migrate",
+			expected:       migrateResult{crds: 1, migrated: 2, unchanged: 1},
+			storedVersions: []string{"v1beta2"},
+		},
+		{
+			name:           "dry run",
+			dryRun:         true,
+			expected:       migrateResult{crds: 1, migrated: 3},
+			storedVersions: []string{"v1beta1", "v1beta2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := &migrateTestClient{
+				Client: fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+					newTestCRD("Widget", "v1beta1", "v1beta2"),
+					newTestCRD("Gadget", "v1beta2"),
+					newTestCustomResource("Widget", "apps", "backend"),
+					newTestCustomResource("Widget", "apps", "frontend"),
+					newTestCustomResource("Widget", "flux-system", "podinfo"),
+					newTestCustomResource("Gadget", "apps", "frontend"),
+				).Build(),
+				stored:    map[string]bool{"flux-system/podinfo": true},
+				conflicts: map[string]int{"apps/backend": 2},
+			}
+
+			result, err := migrateCustomResources(context.TODO(), kubeClient, tt.dryRun)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, result, cmp.AllowUnexported(migrateResult{})); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+
+			var crd apiextensionsv1.CustomResourceDefinition
+			if err := kubeClient.Get(context.TODO(), client.ObjectKey{Name: "widgets.example.toolkit.fluxcd.io"}, &crd); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.storedVersions, crd.Status.StoredVersions); diff != "" {
+				t.Errorf("unexpected stored versions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
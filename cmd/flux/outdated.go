@@ -0,0 +1,287 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/build"
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List the images and Helm charts with newer versions available",
+	Long: `The outdated command compares what is deployed with the newer versions known to Flux:
+the latest image of each ImagePolicy is compared with the tags run by the workloads in the cluster,
+and the chart version of each HelmRelease with the latest stable version in its HelmRepository index.
+The index is read from the artifact stored by source-controller, through the API server proxy.`,
+	Example: `  # List the outdated images and charts in all namespaces
+  flux outdated --all-namespaces
+
+  # Print the report as JSON
+  flux outdated -A -o json`,
+	RunE: outdatedCmdRun,
+}
+
+type outdatedFlags struct {
+	allNamespaces bool
+	output        string
+}
+
+var outdatedArgs outdatedFlags
+
+func init() {
+	outdatedCmd.Flags().BoolVarP(&outdatedArgs.allNamespaces, "all-namespaces", "A", false,
+		"list the outdated resources across all namespaces")
+	outdatedCmd.Flags().StringVarP(&outdatedArgs.output, "output", "o", "table",
+		"the format in which the report should be printed, can be 'table' or 'json'")
+	rootCmd.AddCommand(outdatedCmd)
+}
+
+type outdatedEntry struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+}
+
+func outdatedCmdRun(cmd *cobra.Command, args []string) error {
+	if outdatedArgs.output != "table" && outdatedArgs.output != "json" {
+		return fmt.Errorf("--output must be table or json, not %s", outdatedArgs.output)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	restConfig, err := utils.KubeConfig(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	var listOpts []client.ListOption
+	if !outdatedArgs.allNamespaces {
+		listOpts = append(listOpts, client.InNamespace(*kubeconfigArgs.Namespace))
+	}
+
+	entries, err := outdatedImages(ctx, kubeClient, listOpts)
+	if err != nil {
+		return err
+	}
+	charts, err := outdatedCharts(ctx, kubeClient, restConfig, listOpts)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, charts...)
+
+	if outdatedArgs.output == "json" {
+		if entries == nil {
+			entries = []outdatedEntry{}
+		}
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(b))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		logger.Successf("everything is up to date")
+		return nil
+	}
+
+	var rows [][]string
+	for _, e := range entries {
+		rows = append(rows, []string{e.Kind, e.Namespace, e.Name, e.Current, e.Latest})
+	}
+	utils.PrintTable(cmd.OutOrStdout(), []string{"Kind", "Namespace", "Name", "Current", "Latest"}, rows)
+	return nil
+}
+
+// outdatedImages returns the ImagePolicies whose latest image tag is not
+// the one run by the workloads of the cluster.
+func outdatedImages(ctx context.Context, kubeClient client.Client, listOpts []client.ListOption) ([]outdatedEntry, error) {
+	var policies imagev1.ImagePolicyList
+	if err := kubeClient.List(ctx, &policies, listOpts...); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	deployed, err := listDeployedImages(kubeconfigArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []outdatedEntry
+	for _, p := range policies.Items {
+		tags, outdated := imagePolicyDrift(p.Status.LatestImage, deployed)
+		if !outdated {
+			continue
+		}
+		_, latest, _ := splitImageTag(p.Status.LatestImage)
+		for _, tag := range tags {
+			if tag == latest {
+				continue
+			}
+			entries = append(entries, outdatedEntry{
+				Kind:      imagev1.ImagePolicyKind,
+				Namespace: p.Namespace,
+				Name:      p.Name,
+				Current:   tag,
+				Latest:    latest,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// outdatedCharts returns the HelmReleases whose applied chart version is lower
+// than the latest stable version in the index of their HelmRepository.
+func outdatedCharts(ctx context.Context, kubeClient client.Client, restConfig *rest.Config, listOpts []client.ListOption) ([]outdatedEntry, error) {
+	var releases helmv2.HelmReleaseList
+	if err := kubeClient.List(ctx, &releases, listOpts...); err != nil {
+		return nil, err
+	}
+
+	latestVersions := latestChartVersions(ctx, kubeClient, restConfig, releases.Items)
+	var entries []outdatedEntry
+	for _, hr := range releases.Items {
+		latest, ok := latestVersions[client.ObjectKeyFromObject(&hr).String()]
+		if ok && newerVersion(hr.Status.LastAppliedRevision, latest) {
+			entries = append(entries, outdatedEntry{
+				Kind:      helmv2.HelmReleaseKind,
+				Namespace: hr.Namespace,
+				Name:      hr.Name,
+				Current:   hr.Status.LastAppliedRevision,
+				Latest:    latest,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// latestChartVersions returns the latest stable version of the chart of each
+// HelmRelease indexed by '<namespace>/<name>'. The indexes are read from the
+// HelmRepository artifacts, so that source-controller handles the repository
+// authentication. The releases whose chart comes from another kind of source
+// are skipped.
+func latestChartVersions(ctx context.Context, kubeClient client.Client, restConfig *rest.Config, releases []helmv2.HelmRelease) map[string]string {
+	indexes := make(map[string][]byte)
+	versions := make(map[string]string)
+	for _, hr := range releases {
+		sourceRef := hr.Spec.Chart.Spec.SourceRef
+		if sourceRef.Kind != sourcev1.HelmRepositoryKind || hr.Status.LastAppliedRevision == "" {
+			continue
+		}
+		namespace := sourceRef.Namespace
+		if namespace == "" {
+			namespace = hr.Namespace
+		}
+
+		var repo sourcev1.HelmRepository
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: sourceRef.Name}, &repo); err != nil {
+			logger.Warningf("HelmRelease %s/%s: %s", hr.Namespace, hr.Name, err.Error())
+			continue
+		}
+
+		repoKey := client.ObjectKeyFromObject(&repo).String()
+		index, ok := indexes[repoKey]
+		if !ok {
+			if repo.Status.Artifact == nil {
+				logger.Warningf("HelmRepository %s: no artifact found", repoKey)
+				continue
+			}
+			var err error
+			index, err = build.FetchArtifact(ctx, restConfig, *repo.Status.Artifact)
+			if err != nil {
+				logger.Warningf("HelmRepository %s: fetching the index failed: %s", repoKey, err.Error())
+				continue
+			}
+			indexes[repoKey] = index
+		}
+
+		latest, err := latestChartVersion(index, hr.Spec.Chart.Spec.Chart)
+		if err != nil {
+			logger.Warningf("HelmRelease %s/%s: %s", hr.Namespace, hr.Name, err.Error())
+			continue
+		}
+		versions[client.ObjectKeyFromObject(&hr).String()] = latest
+	}
+	return versions
+}
+
+// latestChartVersion returns the highest stable version of the chart
+// listed in the Helm repository index.
+func latestChartVersion(index []byte, chart string) (string, error) {
+	var idx struct {
+		Entries map[string][]struct {
+			Version string `json:"version"`
+		} `json:"entries"`
+	}
+	if err := yaml.Unmarshal(index, &idx); err != nil {
+		return "", fmt.Errorf("parsing the index failed: %w", err)
+	}
+
+	var latest *semver.Version
+	for _, e := range idx.Entries[chart] {
+		v, err := semver.NewVersion(e.Version)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("chart %s not found in the index", chart)
+	}
+	return latest.Original(), nil
+}
+
+// newerVersion reports whether latest is a higher semver version than current.
+func newerVersion(current, latest string) bool {
+	c, err := semver.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	l, err := semver.NewVersion(latest)
+	if err != nil {
+		return false
+	}
+	return l.GreaterThan(c)
+}
@@ -0,0 +1,70 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLatestChartVersion(t *testing.T) {
+	index, err := os.ReadFile("testdata/outdated/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		chart     string
+		expect    string
+		expectErr bool
+	}{
+		{"podinfo", "6.1.0", false},
+		{"redis", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.chart, func(t *testing.T) {
+			got, err := latestChartVersion(index, tc.chart)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	cases := []struct {
+		current string
+		latest  string
+		expect  bool
+	}{
+		{"6.0.0", "6.1.0", true},
+		{"6.1.0", "6.1.0", false},
+		{"6.2.0", "6.1.0", false},
+		{"main@sha1:abc", "6.1.0", false},
+	}
+	for _, tc := range cases {
+		if got := newerVersion(tc.current, tc.latest); got != tc.expect {
+			t.Errorf("newerVersion(%s, %s) expected %v, got %v", tc.current, tc.latest, tc.expect, got)
+		}
+	}
+}
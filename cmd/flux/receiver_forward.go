@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var receiverCmd = &cobra.Command{
+	Use:   "receiver",
+	Short: "Operate on Receiver resources",
+	Long:  "The receiver sub-commands operate on the webhook Receiver resources.",
+}
+
+var receiverForwardCmd = &cobra.Command{
+	Use:   "forward [name]",
+	Short: "Forward a local port to the webhook receiver",
+	Long: `The receiver forward command port-forwards a local port to the webhook receiver of the
+notification-controller, then prints the local URL of the Receiver and a sample request,
+so that webhook integrations can be tested without exposing the receiver.`,
+	Example: `  # Forward localhost:8080 to the webhook receiver and print the URL of the github Receiver
+  flux receiver forward github --port 8080`,
+	ValidArgsFunction: resourceNamesCompletionFunc(notificationv1.GroupVersion.WithKind(notificationv1.ReceiverKind)),
+	RunE:              receiverForwardCmdRun,
+}
+
+type receiverForwardFlags struct {
+	port          int
+	fluxNamespace string
+}
+
+var receiverForwardArgs receiverForwardFlags
+
+func init() {
+	receiverForwardCmd.Flags().IntVar(&receiverForwardArgs.port, "port", 8080, "the local port to listen on")
+	receiverForwardCmd.Flags().StringVar(&receiverForwardArgs.fluxNamespace, "flux-namespace", rootArgs.defaults.Namespace,
+		"the namespace where the Flux components are running")
+	receiverCmd.AddCommand(receiverForwardCmd)
+	rootCmd.AddCommand(receiverCmd)
+}
+
+func receiverForwardCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("Receiver name is required")
+	}
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	var receiver notificationv1.Receiver
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: *kubeconfigArgs.Namespace, Name: name}, &receiver); err != nil {
+		return err
+	}
+	if receiver.Status.URL == "" {
+		return fmt.Errorf("Receiver %s has no webhook URL, check that it is ready", name)
+	}
+
+	cfg, err := utils.KubeConfig(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	pods, err := clientset.CoreV1().Pods(receiverForwardArgs.fluxNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=notification-controller",
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no running notification-controller pods found in %s namespace", receiverForwardArgs.fluxNamespace)
+	}
+	pod := pods.Items[0]
+
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stopCh := make(chan struct{}, 1)
+	readyCh := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(stopCh)
+	}()
+
+	ports := []string{fmt.Sprintf("%d:%d", receiverForwardArgs.port, receiverWebhookPort(pod))}
+	forwarder, err := portforward.New(dialer, ports, stopCh, readyCh, nil, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-readyCh
+		hookURL := fmt.Sprintf("http://localhost:%d%s", receiverForwardArgs.port, receiver.Status.URL)
+		logger.Successf("forwarding %s to %s/%s", hookURL, pod.Namespace, pod.Name)
+		logger.Actionf("send a sample request with:\n%s", receiverSampleRequest(receiver.Spec.Type, hookURL))
+		logger.Waitingf("press Ctrl+C to stop forwarding")
+	}()
+
+	return forwarder.ForwardPorts()
+}
+
+// receiverWebhookPort returns the container port the notification-controller
+// webhook receiver listens on.
+func receiverWebhookPort(pod corev1.Pod) int32 {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == "http-webhook" {
+				return p.ContainerPort
+			}
+		}
+	}
+	return 9292
+}
+
+// receiverSampleRequest returns a curl command posting a payload that
+// passes the validation of the given receiver type, TOKEN being the
+// value stored in the Receiver secret. The gcr receiver authenticates the
+// requests with an identity token issued by Google, its sample request is
+// rejected unless a valid token is set in ID_TOKEN.
+func receiverSampleRequest(receiverType, hookURL string) string {
+	var b strings.Builder
+	switch receiverType {
+	case notificationv1.GitHubReceiver:
+		b.WriteString("PAYLOAD='{\"zen\":\"flux\"}'\n")
+		b.WriteString("SIG=$(printf '%s' \"$PAYLOAD\" | openssl dgst -sha256 -hmac \"$TOKEN\" | cut -d' ' -f2)\n")
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H 'X-GitHub-Event: ping' -H \"X-Hub-Signature-256: sha256=$SIG\" -d \"$PAYLOAD\" %s", hookURL)
+	case notificationv1.GitLabReceiver:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H 'X-Gitlab-Event: Push Hook' -H \"X-Gitlab-Token: $TOKEN\" -d '{}' %s", hookURL)
+	case notificationv1.GenericHMACReceiver:
+		b.WriteString("PAYLOAD='{}'\n")
+		b.WriteString("SIG=$(printf '%s' \"$PAYLOAD\" | openssl dgst -sha256 -hmac \"$TOKEN\" | cut -d' ' -f2)\n")
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H \"X-Signature: sha256=$SIG\" -d \"$PAYLOAD\" %s", hookURL)
+	case notificationv1.BitbucketReceiver:
+		b.WriteString("PAYLOAD='{}'\n")
+		b.WriteString("SIG=$(printf '%s' \"$PAYLOAD\" | openssl dgst -sha256 -hmac \"$TOKEN\" | cut -d' ' -f2)\n")
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H 'X-Event-Key: repo:refs_changed' -H \"X-Hub-Signature: sha256=$SIG\" -d \"$PAYLOAD\" %s", hookURL)
+	case notificationv1.HarborReceiver:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H \"Authorization: $TOKEN\" -d '{}' %s", hookURL)
+	case notificationv1.NexusReceiver:
+		b.WriteString("PAYLOAD='{\"action\":\"CREATED\",\"repositoryName\":\"flux\"}'\n")
+		b.WriteString("SIG=$(printf '%s' \"$PAYLOAD\" | openssl dgst -sha1 -hmac \"$TOKEN\" | cut -d' ' -f2)\n")
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H \"X-Nexus-Webhook-Signature: $SIG\" -d \"$PAYLOAD\" %s", hookURL)
+	case notificationv1.DockerHubReceiver:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -d '{\"push_data\":{\"tag\":\"latest\"},\"repository\":{\"repo_url\":\"https://hub.docker.com/r/flux/app\"}}' %s", hookURL)
+	case notificationv1.QuayReceiver:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -d '{\"docker_url\":\"quay.io/flux/app\",\"updated_tags\":[\"latest\"]}' %s", hookURL)
+	case notificationv1.ACRReceiver:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -d '{\"action\":\"push\",\"target\":{\"repository\":\"flux/app\",\"tag\":\"latest\"}}' %s", hookURL)
+	case notificationv1.GCRReceiver:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -H \"Authorization: Bearer $ID_TOKEN\" -d '{\"message\":{\"data\":\"e30=\"}}' %s", hookURL)
+	default:
+		fmt.Fprintf(&b, "curl -X POST -H 'Content-Type: application/json' -d '{}' %s", hookURL)
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestReceiverSampleRequest(t *testing.T) {
+	hookURL := "http://localhost:8080/hook/abc"
+	cases := []struct {
+		receiverType string
+		expect       string
+	}{
+		{notificationv1.GitHubReceiver, "X-Hub-Signature-256: sha256=$SIG"},
+		{notificationv1.GitLabReceiver, "X-Gitlab-Token: $TOKEN"},
+		{notificationv1.HarborReceiver, "Authorization: $TOKEN"},
+		{notificationv1.BitbucketReceiver, "X-Hub-Signature: sha256=$SIG"},
+		{notificationv1.NexusReceiver, "X-Nexus-Webhook-Signature: $SIG"},
+		{notificationv1.QuayReceiver, `"updated_tags":["latest"]`},
+		{notificationv1.DockerHubReceiver, `"push_data":{"tag":"latest"}`},
+		{notificationv1.GCRReceiver, "Authorization: Bearer $ID_TOKEN"},
+		{notificationv1.GenericReceiver, "curl -X POST"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.receiverType, func(t *testing.T) {
+			got := receiverSampleRequest(tc.receiverType, hookURL)
+			if !strings.Contains(got, tc.expect) || !strings.HasSuffix(got, hookURL) {
+				t.Errorf("unexpected sample request:\n%s", got)
+			}
+		})
+	}
+}
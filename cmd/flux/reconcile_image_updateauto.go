@@ -17,13 +17,26 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/spf13/cobra"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	autov1 "github.com/fluxcd/image-automation-controller/api/v1beta1"
+	imagev1 "github.com/fluxcd/image-reflector-controller/api/v1beta1"
 	meta "github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/flux2/internal/utils"
 )
 
 var reconcileImageUpdateCmd = &cobra.Command{
@@ -31,18 +44,175 @@ var reconcileImageUpdateCmd = &cobra.Command{
 	Short: "Reconcile an ImageUpdateAutomation",
 	Long:  `The reconcile image update command triggers a reconciliation of an ImageUpdateAutomation resource and waits for it to finish.`,
 	Example: `  # Trigger an automation run for an existing image update automation
-  flux reconcile image update latest-images`,
+  flux reconcile image update latest-images
+
+  # Print the changes the automation would commit to a local checkout of its repository
+  flux reconcile image update latest-images --dry-run --repo-path=./fleet-infra`,
 	ValidArgsFunction: resourceNamesCompletionFunc(autov1.GroupVersion.WithKind(autov1.ImageUpdateAutomationKind)),
-	RunE: reconcileCommand{
-		apiType: imageUpdateAutomationType,
-		object:  imageUpdateAutomationAdapter{&autov1.ImageUpdateAutomation{}},
-	}.run,
+	RunE:              reconcileImageUpdateCmdRun,
 }
 
+type reconcileImageUpdateFlags struct {
+	dryRun   bool
+	repoPath string
+}
+
+var reconcileImageUpdateArgs reconcileImageUpdateFlags
+
 func init() {
+	reconcileImageUpdateCmd.Flags().BoolVar(&reconcileImageUpdateArgs.dryRun, "dry-run", false,
+		"print the changes the automation would commit to the local checkout given with --repo-path, without triggering a reconciliation")
+	reconcileImageUpdateCmd.Flags().StringVar(&reconcileImageUpdateArgs.repoPath, "repo-path", ".",
+		"path to a local checkout of the Git repository of the automation, used with --dry-run")
 	reconcileImageCmd.AddCommand(reconcileImageUpdateCmd)
 }
 
+func reconcileImageUpdateCmdRun(cmd *cobra.Command, args []string) error {
+	if !reconcileImageUpdateArgs.dryRun {
+		return reconcileCommand{
+			apiType: imageUpdateAutomationType,
+			object:  imageUpdateAutomationAdapter{&autov1.ImageUpdateAutomation{}},
+		}.run(cmd, args)
+	}
+
+	if len(args) < 1 {
+		return fmt.Errorf("%s name is required", imageUpdateAutomationType.kind)
+	}
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	var auto autov1.ImageUpdateAutomation
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: *kubeconfigArgs.Namespace, Name: name}, &auto); err != nil {
+		return err
+	}
+	if auto.Spec.Update != nil && auto.Spec.Update.Strategy != autov1.UpdateStrategySetters {
+		return fmt.Errorf("update strategy %s is not supported", auto.Spec.Update.Strategy)
+	}
+
+	var policies imagev1.ImagePolicyList
+	if err := kubeClient.List(ctx, &policies, client.InNamespace(auto.Namespace)); err != nil {
+		return err
+	}
+	latestImages := make(map[string]string)
+	for _, p := range policies.Items {
+		if p.Status.LatestImage != "" {
+			latestImages[fmt.Sprintf("%s:%s", p.Namespace, p.Name)] = p.Status.LatestImage
+		}
+	}
+
+	root := reconcileImageUpdateArgs.repoPath
+	updatePath := root
+	if auto.Spec.Update != nil && auto.Spec.Update.Path != "" {
+		updatePath = filepath.Join(root, auto.Spec.Update.Path)
+	}
+
+	changed := 0
+	err = filepath.Walk(updatePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		updated := setImagePolicyMarkers(string(b), latestImages)
+		if updated == string(b) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(b)),
+			B:        difflib.SplitLines(updated),
+			FromFile: filepath.ToSlash(filepath.Join("a", rel)),
+			ToFile:   filepath.ToSlash(filepath.Join("b", rel)),
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		cmd.Print(diff)
+		changed++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed == 0 {
+		logger.Successf("no changes to commit")
+		return nil
+	}
+	logger.Successf("%d files would be updated", changed)
+	return nil
+}
+
+// imagePolicyMarker matches the YAML values followed by an image policy
+// setter comment, e.g. `image: ghcr.io/org/app:1.0.0 # {"$imagepolicy": "ns:app"}`.
+var imagePolicyMarker = regexp.MustCompile(`^(.*?[:-]\s+)("?)([^\s"#]+)("?)(\s+#\s*\{"\$imagepolicy":\s*"([^"]+)"\}.*)$`)
+
+// setImagePolicyMarkers replaces the values marked with image policy setters
+// with the latest image of the policies, keyed by <namespace>:<name>. The
+// :tag and :name marker suffixes select the tag or the repository only.
+func setImagePolicyMarkers(content string, latestImages map[string]string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		m := imagePolicyMarker.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		ref := m[6]
+		field := ""
+		if parts := strings.Split(ref, ":"); len(parts) == 3 {
+			ref = parts[0] + ":" + parts[1]
+			field = parts[2]
+		}
+		latest, ok := latestImages[ref]
+		if !ok {
+			continue
+		}
+
+		value := latest
+		if field != "" {
+			tag, err := name.NewTag(latest)
+			if err != nil {
+				continue
+			}
+			switch field {
+			case "tag":
+				value = tag.TagStr()
+			case "name":
+				value = strings.TrimSuffix(latest, ":"+tag.TagStr())
+			default:
+				continue
+			}
+		}
+		lines[i] = m[1] + m[2] + value + m[4] + m[5]
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (obj imageUpdateAutomationAdapter) suspended() bool {
 	return obj.ImageUpdateAutomation.Spec.Suspend
 }
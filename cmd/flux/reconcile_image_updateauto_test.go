@@ -0,0 +1,64 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetImagePolicyMarkers(t *testing.T) {
+	latestImages := map[string]string{
+		"flux-system:podinfo": "ghcr.io/stefanprodan/podinfo:6.0.3",
+	}
+	cases := []struct {
+		file   string
+		expect []string
+		keep   []string
+	}{
+		{
+			file:   "testdata/image_update/setters/deployment.yaml",
+			expect: []string{`image: ghcr.io/stefanprodan/podinfo:6.0.3 # {"$imagepolicy": "flux-system:podinfo"}`},
+			keep:   []string{`image: "ghcr.io/example/sidecar:1.0.0" # {"$imagepolicy": "flux-system:sidecar"}`},
+		},
+		{
+			file: "testdata/image_update/setters/helmrelease.yaml",
+			expect: []string{
+				`repository: ghcr.io/stefanprodan/podinfo # {"$imagepolicy": "flux-system:podinfo:name"}`,
+				`tag: 6.0.3 # {"$imagepolicy": "flux-system:podinfo:tag"}`,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.file, func(t *testing.T) {
+			b, err := os.ReadFile(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := setImagePolicyMarkers(string(b), latestImages)
+			for _, line := range append(tc.expect, tc.keep...) {
+				if !strings.Contains(got, line) {
+					t.Errorf("expected line %s in:\n%s", line, got)
+				}
+			}
+		})
+	}
+}
@@ -22,7 +22,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fluxcd/flux2/internal/utils"
@@ -113,7 +112,7 @@ func (resume resumeCommand) run(cmd *cobra.Command, args []string) error {
 		}
 
 		logger.Waitingf("waiting for %s reconciliation", resume.kind)
-		if err := wait.PollImmediate(rootArgs.pollInterval, rootArgs.timeout,
+		if err := waitForObject(ctx, kubeClient, namespacedName, resume.list.resumeItem(i).asClientObject(),
 			isReady(ctx, kubeClient, namespacedName, resume.list.resumeItem(i))); err != nil {
 			logger.Failuref(err.Error())
 			continue
@@ -0,0 +1,174 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/flux2/internal/tree"
+	"github.com/fluxcd/flux2/internal/utils"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+)
+
+var treeHrCmd = &cobra.Command{
+	Use:     "helmrelease [name]",
+	Aliases: []string{"hr"},
+	Short:   "Print the resource inventory of a HelmRelease",
+	Long: `The tree command prints the resource list of the last release of a HelmRelease, read from the Helm storage.
+The Kustomizations and HelmReleases created by the release are expanded with the resources they reconcile.`,
+	Example: `  # Print the resources managed by a HelmRelease
+  flux tree helmrelease podinfo
+
+  # Print the Flux resources managed by a HelmRelease
+  flux tree helmrelease podinfo --compact`,
+	RunE:              treeHrCmdRun,
+	ValidArgsFunction: resourceNamesCompletionFunc(helmv2.GroupVersion.WithKind(helmv2.HelmReleaseKind)),
+}
+
+type TreeHrFlags struct {
+	compact bool
+	output  string
+}
+
+var treeHrArgs TreeHrFlags
+
+func init() {
+	treeHrCmd.Flags().BoolVar(&treeHrArgs.compact, "compact", false, "list Flux resources only.")
+	treeHrCmd.Flags().StringVarP(&treeHrArgs.output, "output", "o", "",
+		"the format in which the tree should be printed. can be 'json' or 'yaml'")
+	treeCmd.AddCommand(treeHrCmd)
+}
+
+func treeHrCmdRun(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("helmrelease name is required")
+	}
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	hr := &helmv2.HelmRelease{}
+	err = kubeClient.Get(ctx, client.ObjectKey{
+		Namespace: *kubeconfigArgs.Namespace,
+		Name:      name,
+	}, hr)
+	if err != nil {
+		return err
+	}
+
+	hrTree := tree.New(object.ObjMetadata{
+		Namespace: hr.Namespace,
+		Name:      hr.Name,
+		GroupKind: schema.GroupKind{Group: helmv2.GroupVersion.Group, Kind: helmv2.HelmReleaseKind},
+	})
+
+	err = treeHelmRelease(ctx, hrTree, hr, kubeClient, treeHrArgs.compact)
+	if err != nil {
+		return err
+	}
+
+	switch treeHrArgs.output {
+	case "json":
+		data, err := json.MarshalIndent(hrTree, "", "  ")
+		if err != nil {
+			return err
+		}
+		rootCmd.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(hrTree)
+		if err != nil {
+			return err
+		}
+		rootCmd.Println(string(data))
+	default:
+		rootCmd.Println(hrTree.Print())
+	}
+
+	return nil
+}
+
+func treeHelmRelease(ctx context.Context, tree tree.ObjMetadataTree, item *helmv2.HelmRelease, kubeClient client.Client, compact bool) error {
+	objects, err := helmReleaseInventory(ctx, item, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	for _, objMetadata := range objects {
+		// Helm installs the namespaced objects without namespace in the release namespace
+		if objMetadata.Namespace == "" && isNamespaced(kubeClient.RESTMapper(), objMetadata.GroupKind) {
+			objMetadata.Namespace = item.GetReleaseNamespace()
+		}
+
+		if compact && !strings.Contains(objMetadata.GroupKind.Group, compactGroup) {
+			continue
+		}
+
+		node := tree.Add(objMetadata)
+
+		objectKey := client.ObjectKey{
+			Namespace: objMetadata.Namespace,
+			Name:      objMetadata.Name,
+		}
+		switch objMetadata.GroupKind {
+		case schema.GroupKind{Group: helmv2.GroupVersion.Group, Kind: helmv2.HelmReleaseKind}:
+			child := &helmv2.HelmRelease{}
+			if err := kubeClient.Get(ctx, objectKey, child); err != nil {
+				return fmt.Errorf("failed to find object: %w", err)
+			}
+			if err := treeHelmRelease(ctx, node, child, kubeClient, compact); err != nil {
+				return err
+			}
+		case schema.GroupKind{Group: kustomizev1.GroupVersion.Group, Kind: kustomizev1.KustomizationKind}:
+			child := &kustomizev1.Kustomization{}
+			if err := kubeClient.Get(ctx, objectKey, child); err != nil {
+				return fmt.Errorf("failed to find object: %w", err)
+			}
+			if err := treeKustomization(ctx, node, child, kubeClient, compact); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isNamespaced returns whether the kind is namespaced, the kinds unknown to
+// the cluster are reported as cluster-scoped.
+func isNamespaced(mapper meta.RESTMapper, gk schema.GroupKind) bool {
+	mapping, err := mapper.RESTMapping(gk)
+	if err != nil {
+		return false
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
@@ -17,23 +17,17 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"strings"
 
+	"github.com/fluxcd/flux2/internal/build"
 	"github.com/fluxcd/flux2/internal/tree"
 	"github.com/fluxcd/flux2/internal/utils"
 	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
-	"github.com/fluxcd/pkg/ssa"
 	"github.com/spf13/cobra"
-	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -61,6 +55,9 @@ type TreeKsFlags struct {
 
 var treeKsArgs TreeKsFlags
 
+// compactGroup is the API group suffix of the resources listed with --compact.
+const compactGroup = "toolkit.fluxcd.io"
+
 func init() {
 	treeKsCmd.Flags().BoolVar(&treeKsArgs.compact, "compact", false, "list Flux resources only.")
 	treeKsCmd.Flags().StringVarP(&treeKsArgs.output, "output", "o", "",
@@ -127,8 +124,6 @@ func treeKustomization(ctx context.Context, tree tree.ObjMetadataTree, item *kus
 		return nil
 	}
 
-	compactGroup := "toolkit.fluxcd.io"
-
 	for _, entry := range item.Status.Inventory.Entries {
 		objMetadata, err := object.ParseObjMetadata(entry.ID)
 		if err != nil {
@@ -150,20 +145,16 @@ func treeKustomization(ctx context.Context, tree tree.ObjMetadataTree, item *kus
 
 		if objMetadata.GroupKind.Group == helmv2.GroupVersion.Group &&
 			objMetadata.GroupKind.Kind == helmv2.HelmReleaseKind {
-			objects, err := getHelmReleaseInventory(
-				ctx, client.ObjectKey{
-					Namespace: objMetadata.Namespace,
-					Name:      objMetadata.Name,
-				}, kubeClient)
+			hr := &helmv2.HelmRelease{}
+			err = kubeClient.Get(ctx, client.ObjectKey{
+				Namespace: objMetadata.Namespace,
+				Name:      objMetadata.Name,
+			}, hr)
 			if err != nil {
 				return err
 			}
-
-			for _, obj := range objects {
-				if compact && !strings.Contains(obj.GroupKind.Group, compactGroup) {
-					continue
-				}
-				ks.Add(obj)
+			if err := treeHelmRelease(ctx, ks, hr, kubeClient, compact); err != nil {
+				return err
 			}
 		}
 
@@ -189,88 +180,12 @@ func treeKustomization(ctx context.Context, tree tree.ObjMetadataTree, item *kus
 	return nil
 }
 
-type hrStorage struct {
-	Name     string `json:"name,omitempty"`
-	Manifest string `json:"manifest,omitempty"`
-}
-
-func getHelmReleaseInventory(ctx context.Context, objectKey client.ObjectKey, kubeClient client.Client) ([]object.ObjMetadata, error) {
-	hr := &helmv2.HelmRelease{}
-	if err := kubeClient.Get(ctx, objectKey, hr); err != nil {
-		return nil, err
-	}
-
-	// skip release if it targets a remote clusters
-	if hr.Spec.KubeConfig != nil {
-		return nil, nil
-	}
-
-	storageNamespace := hr.GetNamespace()
-	if hr.Spec.StorageNamespace != "" {
-		storageNamespace = hr.Spec.StorageNamespace
-	}
-
-	storageName := hr.GetName()
-	if hr.Spec.ReleaseName != "" {
-		storageName = hr.Spec.ReleaseName
-	} else if hr.Spec.TargetNamespace != "" {
-		storageName = strings.Join([]string{hr.Spec.TargetNamespace, hr.Name}, "-")
-	}
-
-	storageVersion := hr.Status.LastReleaseRevision
-	// skip release if it failed to install
-	if storageVersion < 1 {
-		return nil, nil
-	}
-
-	storageKey := client.ObjectKey{
-		Namespace: storageNamespace,
-		Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%v", storageName, storageVersion),
-	}
-
-	storageSecret := &corev1.Secret{}
-	if err := kubeClient.Get(ctx, storageKey, storageSecret); err != nil {
-		// skip release if it has no storage
-		if apierrors.IsNotFound(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to find the Helm storage object for HelmRelease '%s': %w", objectKey.String(), err)
-	}
-
-	releaseData, releaseFound := storageSecret.Data["release"]
-	if !releaseFound {
-		return nil, fmt.Errorf("failed to decode the Helm storage object for HelmRelease '%s'", objectKey.String())
-	}
-
-	// adapted from https://github.com/helm/helm/blob/02685e94bd3862afcb44f6cd7716dbeb69743567/pkg/storage/driver/util.go
-	var b64 = base64.StdEncoding
-	b, err := b64.DecodeString(string(releaseData))
+// helmReleaseInventory returns the objects of the last release of the
+// HelmRelease, read from the Helm storage.
+func helmReleaseInventory(ctx context.Context, hr *helmv2.HelmRelease, kubeClient client.Client) ([]object.ObjMetadata, error) {
+	objects, err := build.LastReleaseObjects(ctx, kubeClient, hr)
 	if err != nil {
 		return nil, err
 	}
-	var magicGzip = []byte{0x1f, 0x8b, 0x08}
-	if bytes.Equal(b[0:3], magicGzip) {
-		r, err := gzip.NewReader(bytes.NewReader(b))
-		if err != nil {
-			return nil, err
-		}
-		defer r.Close()
-		b2, err := io.ReadAll(r)
-		if err != nil {
-			return nil, err
-		}
-		b = b2
-	}
-
-	var rls hrStorage
-	if err := json.Unmarshal(b, &rls); err != nil {
-		return nil, fmt.Errorf("failed to decode the Helm storage object for HelmRelease '%s': %w", objectKey.String(), err)
-	}
-
-	objects, err := ssa.ReadObjects(strings.NewReader(rls.Manifest))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read the Helm storage object for HelmRelease '%s': %w", objectKey.String(), err)
-	}
-
 	return object.UnstructuredSetToObjMetadataSet(objects), nil
 }
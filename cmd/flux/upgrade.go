@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+	"github.com/fluxcd/flux2/pkg/manifestgen"
+	"github.com/fluxcd/flux2/pkg/manifestgen/install"
+	"github.com/fluxcd/flux2/pkg/status"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the Flux components installed without bootstrap",
+	Long: `The upgrade command performs an in-place upgrade of the Flux components installed with 'flux install'.
+It compares the components running on the cluster with the manifests of the target version,
+prints the upgrade plan, and applies the new manifests with server-side apply.
+The custom resource definitions are upgraded before the controllers.`,
+	Example: `  # Print the upgrade plan to the latest version
+  flux upgrade --version=latest --dry-run
+
+  # Upgrade the components to the version of the CLI
+  flux upgrade
+
+  # Upgrade to a specific version without asking for confirmation
+  flux upgrade --version=v0.26.0 --silent`,
+	RunE: upgradeCmdRun,
+}
+
+type upgradeFlags struct {
+	version           string
+	defaultComponents []string
+	extraComponents   []string
+	registry          string
+	imagePullSecret   string
+	dryRun            bool
+	silent            bool
+}
+
+var upgradeArgs upgradeFlags
+
+func init() {
+	upgradeCmd.Flags().StringVarP(&upgradeArgs.version, "version", "v", "",
+		"toolkit version, when specified the manifests are downloaded from https://github.com/fluxcd/flux2/releases")
+	upgradeCmd.Flags().StringSliceVar(&upgradeArgs.defaultComponents, "components", rootArgs.defaults.Components,
+		"list of components, accepts comma-separated values")
+	upgradeCmd.Flags().StringSliceVar(&upgradeArgs.extraComponents, "components-extra", nil,
+		"list of components in addition to those supplied or defaulted, accepts comma-separated values")
+	upgradeCmd.Flags().StringVar(&upgradeArgs.registry, "registry", rootArgs.defaults.Registry,
+		"container registry where the toolkit images are published")
+	upgradeCmd.Flags().StringVar(&upgradeArgs.imagePullSecret, "image-pull-secret", "",
+		"Kubernetes secret name used for pulling the toolkit images from a private registry")
+	upgradeCmd.Flags().BoolVar(&upgradeArgs.dryRun, "dry-run", false,
+		"only print the upgrade plan")
+	upgradeCmd.Flags().BoolVarP(&upgradeArgs.silent, "silent", "s", false,
+		"upgrade components without asking for confirmation")
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+func upgradeCmdRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rootArgs.timeout)
+	defer cancel()
+
+	components := append(upgradeArgs.defaultComponents, upgradeArgs.extraComponents...)
+	if err := utils.ValidateComponents(components); err != nil {
+		return err
+	}
+
+	ver, err := getVersion(upgradeArgs.version)
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := utils.KubeClient(kubeconfigArgs)
+	if err != nil {
+		return err
+	}
+
+	current, err := installedVersion(ctx, kubeClient, *kubeconfigArgs.Namespace)
+	if err != nil {
+		return err
+	}
+
+	var gitRepository sourcev1.GitRepository
+	err = kubeClient.Get(ctx, client.ObjectKey{Namespace: *kubeconfigArgs.Namespace, Name: *kubeconfigArgs.Namespace}, &gitRepository)
+	if err == nil {
+		return fmt.Errorf("Flux was bootstrapped from %s, run 'flux bootstrap' to upgrade the components", gitRepository.Spec.URL)
+	}
+
+	logger.Actionf("upgrading Flux from %s to %s", current, ver)
+	logger.Generatef("generating manifests")
+
+	tmpDir, err := os.MkdirTemp("", *kubeconfigArgs.Namespace)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifestsBase := ""
+	if isEmbeddedVersion(ver) {
+		if err := writeEmbeddedManifests(tmpDir); err != nil {
+			return err
+		}
+		manifestsBase = tmpDir
+	}
+
+	opts := install.MakeDefaultOptions()
+	opts.Version = ver
+	opts.Namespace = *kubeconfigArgs.Namespace
+	opts.Components = components
+	opts.Registry = upgradeArgs.registry
+	opts.ImagePullSecret = upgradeArgs.imagePullSecret
+	opts.ManifestFile = fmt.Sprintf("%s.yaml", *kubeconfigArgs.Namespace)
+	opts.Timeout = rootArgs.timeout
+
+	manifest, err := install.Generate(opts, manifestsBase)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+
+	if _, err := manifest.WriteFile(tmpDir); err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+	manifestPath := filepath.Join(tmpDir, manifest.Path)
+
+	logger.Actionf("computing upgrade plan")
+	plan, err := utils.Diff(ctx, kubeconfigArgs, manifestPath)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+
+	if len(plan.Entries) == 0 {
+		logger.Successf("components are up to date")
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, plan.String())
+
+	if upgradeArgs.dryRun {
+		logger.Successf("upgrade dry-run finished")
+		return nil
+	}
+
+	if !upgradeArgs.silent {
+		prompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Are you sure you want to upgrade Flux to %s", ver),
+			IsConfirm: true,
+		}
+		if _, err := prompt.Run(); err != nil {
+			return fmt.Errorf("aborting")
+		}
+	}
+
+	logger.Actionf("upgrading components in %s namespace", *kubeconfigArgs.Namespace)
+	applyOutput, err := utils.Apply(ctx, kubeconfigArgs, manifestPath)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, applyOutput)
+
+	kubeConfig, err := utils.KubeConfig(kubeconfigArgs)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+	statusChecker, err := status.NewStatusChecker(kubeConfig, 5*time.Second, rootArgs.timeout, logger)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+	componentRefs, err := buildComponentObjectRefs(components...)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+	logger.Waitingf("waiting for the rollout to complete")
+	if err := statusChecker.Assess(componentRefs...); err != nil {
+		return fmt.Errorf("upgrade failed")
+	}
+
+	logger.Successf("upgrade finished")
+	return nil
+}
+
+// installedVersion returns the Flux version recorded by the install
+// manifests on the given namespace.
+func installedVersion(ctx context.Context, kubeClient client.Client, namespace string) (string, error) {
+	var ns corev1.Namespace
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: namespace}, &ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("Flux is not installed in the %s namespace", namespace)
+		}
+		return "", err
+	}
+
+	if ns.Labels[manifestgen.PartOfLabelKey] != manifestgen.PartOfLabelValue {
+		return "", fmt.Errorf("Flux is not installed in the %s namespace", namespace)
+	}
+
+	if v, ok := ns.Labels[manifestgen.VersionLabelKey]; ok {
+		return v, nil
+	}
+	return "unknown", nil
+}
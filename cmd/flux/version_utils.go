@@ -29,7 +29,7 @@ func getVersion(input string) (string, error) {
 	}
 
 	if isEmbeddedVersion(input) {
-		return input, nil
+		return rootArgs.defaults.Version, nil
 	}
 
 	var err error
@@ -53,6 +53,9 @@ func getVersion(input string) (string, error) {
 	return input, nil
 }
 
+// isEmbeddedVersion returns true if the given version matches the version
+// of the manifests embedded in the binary, in which case the manifests
+// don't need to be downloaded.
 func isEmbeddedVersion(input string) bool {
-	return input == rootArgs.defaults.Version
+	return input == rootArgs.defaults.Version || "v"+input == rootArgs.defaults.Version
 }
@@ -54,3 +54,21 @@ func TestVersionCmd(t *testing.T) {
 		cmd.runTestCmd(t)
 	}
 }
+
+func TestIsEmbeddedVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "v0.0.0-dev.0", expected: true},
+		{input: "0.0.0-dev.0", expected: true},
+		{input: "v0.1.0", expected: false},
+		{input: "latest", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := isEmbeddedVersion(tt.input); got != tt.expected {
+			t.Errorf("isEmbeddedVersion(%s) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
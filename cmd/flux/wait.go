@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+// waitResyncInterval is the period at which the condition is checked again
+// while waiting on a watch, in case an event was missed.
+const waitResyncInterval = 30 * time.Second
+
+// waitForObject waits until the condition is met or the timeout expires. The
+// condition is checked each time the object changes, using a watch. When the
+// object can't be watched, the condition is checked at the poll interval.
+func waitForObject(ctx context.Context, kubeClient client.Client, namespacedName types.NamespacedName,
+	object client.Object, condition wait.ConditionFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, rootArgs.timeout)
+	defer cancel()
+
+	w, err := watchObject(ctx, kubeClient, namespacedName, object)
+	if err != nil {
+		return wait.PollImmediateUntil(rootArgs.pollInterval, condition, ctx.Done())
+	}
+	defer w.Stop()
+
+	resync := time.NewTicker(waitResyncInterval)
+	defer resync.Stop()
+	for {
+		if done, err := condition(); err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return wait.ErrWaitTimeout
+		case _, ok := <-w.ResultChan():
+			if !ok {
+				// the watch was closed by the API server
+				return wait.PollImmediateUntil(rootArgs.pollInterval, condition, ctx.Done())
+			}
+		case <-resync.C:
+		}
+	}
+}
+
+// watchObject starts a watch of the object with the given name.
+func watchObject(ctx context.Context, kubeClient client.Client, namespacedName types.NamespacedName,
+	object client.Object) (watch.Interface, error) {
+	watchClient, ok := kubeClient.(client.WithWatch)
+	if !ok {
+		return nil, fmt.Errorf("the client doesn't support watches")
+	}
+
+	scheme := utils.NewScheme()
+	gvk, err := apiutil.GVKForObject(object, scheme)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := scheme.New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err != nil {
+		return nil, err
+	}
+	list, ok := obj.(client.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("unexpected list type %T", obj)
+	}
+
+	return watchClient.Watch(ctx, list, client.InNamespace(namespacedName.Namespace),
+		client.MatchingFields{"metadata.name": namespacedName.Name})
+}
@@ -0,0 +1,93 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestWaitForObject(t *testing.T) {
+	ctx := context.Background()
+	namespacedName := types.NamespacedName{Namespace: "default", Name: "test"}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespacedName.Namespace, Name: namespacedName.Name},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(cm).Build()
+	isUpdated := func() (bool, error) {
+		var obj corev1.ConfigMap
+		if err := kubeClient.Get(ctx, namespacedName, &obj); err != nil {
+			return false, err
+		}
+		return obj.Data["updated"] == "true", nil
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cm.Data = map[string]string{"updated": "true"}
+		if err := kubeClient.Update(ctx, cm); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// the poll interval is long enough for the wait to only return on the watch event
+	defer func(interval time.Duration) { rootArgs.pollInterval = interval }(rootArgs.pollInterval)
+	rootArgs.pollInterval = time.Minute
+	start := time.Now()
+	if err := waitForObject(ctx, kubeClient, namespacedName, &corev1.ConfigMap{}, isUpdated); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("wait returned after %s", elapsed)
+	}
+}
+
+func TestWaitForObjectPolling(t *testing.T) {
+	ctx := context.Background()
+	namespacedName := types.NamespacedName{Namespace: "default", Name: "test"}
+
+	// a client without watch support makes the wait fall back to polling
+	kubeClient := struct{ client.Client }{fake.NewClientBuilder().WithScheme(utils.NewScheme()).Build()}
+
+	defer func(interval, timeout time.Duration) { rootArgs.pollInterval, rootArgs.timeout = interval, timeout }(rootArgs.pollInterval, rootArgs.timeout)
+	rootArgs.pollInterval = 10 * time.Millisecond
+	rootArgs.timeout = 5 * time.Second
+	attempts := 0
+	condition := func() (bool, error) {
+		attempts++
+		return attempts == 3, nil
+	}
+	if err := waitForObject(ctx, kubeClient, namespacedName, &corev1.ConfigMap{}, condition); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
@@ -22,17 +22,22 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/fluxcd/flux2/internal/utils"
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
 	"github.com/fluxcd/pkg/kustomize"
+	"github.com/mattn/go-shellwords"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/resmap"
 	"sigs.k8s.io/kustomize/api/resource"
@@ -58,6 +63,7 @@ var defaultTimeout = 80 * time.Second
 type Builder struct {
 	client        client.WithWatch
 	restMapper    meta.RESTMapper
+	restConfig    *rest.Config
 	name          string
 	namespace     string
 	resourcesPath string
@@ -66,6 +72,12 @@ type Builder struct {
 	action        kustomize.Action
 	kustomization *kustomizev1.Kustomization
 	timeout       time.Duration
+	ignorePaths   []*regexp.Regexp
+	detectOrphans bool
+	fromSource    bool
+	sourceDir     string
+	externalDiff  []string
+	substitutions map[string]string
 }
 
 type BuilderOptionFunc func(b *Builder) error
@@ -77,6 +89,70 @@ func WithTimeout(timeout time.Duration) BuilderOptionFunc {
 	}
 }
 
+// WithIgnorePaths makes the diff ignore the fields matching the path globs.
+func WithIgnorePaths(paths []string) BuilderOptionFunc {
+	return func(b *Builder) error {
+		ignorePaths, err := compileIgnorePaths(paths)
+		if err != nil {
+			return err
+		}
+		b.ignorePaths = ignorePaths
+		return nil
+	}
+}
+
+// WithDetectOrphans makes the diff report the objects of the Kustomization
+// inventory that are no longer in the build, even when garbage collection is
+// disabled.
+func WithDetectOrphans(detectOrphans bool) BuilderOptionFunc {
+	return func(b *Builder) error {
+		b.detectOrphans = detectOrphans
+		return nil
+	}
+}
+
+// WithFromSource makes the build use the latest artifact of the source
+// referenced by the Kustomization instead of a local directory.
+func WithFromSource(fromSource bool) BuilderOptionFunc {
+	return func(b *Builder) error {
+		b.fromSource = fromSource
+		return nil
+	}
+}
+
+// WithExternalDiff makes the diff of the changed objects printed by the given
+// command instead of dyff.
+func WithExternalDiff(command string) BuilderOptionFunc {
+	return func(b *Builder) error {
+		args, err := parseExternalDiff(command)
+		if err != nil {
+			return err
+		}
+		b.externalDiff = args
+		return nil
+	}
+}
+
+// parseExternalDiff splits the external diff command into its arguments
+// following the shell quoting rules, without expanding the variables.
+func parseExternalDiff(command string) ([]string, error) {
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid external diff command '%s': %w", command, err)
+	}
+	return args, nil
+}
+
+// WithSubstitutions makes the post-build substitutions use the given variables
+// instead of the ConfigMaps and Secrets of spec.postBuild.substituteFrom, the
+// variables override the ones of spec.postBuild.substitute.
+func WithSubstitutions(vars map[string]string) BuilderOptionFunc {
+	return func(b *Builder) error {
+		b.substitutions = vars
+		return nil
+	}
+}
+
 // NewBuilder returns a new Builder
 // to dp : create functional options
 func NewBuilder(rcg *genericclioptions.ConfigFlags, name, resources string, opts ...BuilderOptionFunc) (*Builder, error) {
@@ -90,9 +166,15 @@ func NewBuilder(rcg *genericclioptions.ConfigFlags, name, resources string, opts
 		return nil, err
 	}
 
+	restConfig, err := rcg.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	b := &Builder{
 		client:        kubeClient,
 		restMapper:    restMapper,
+		restConfig:    restConfig,
 		name:          name,
 		namespace:     *rcg.Namespace,
 		resourcesPath: resources,
@@ -131,17 +213,55 @@ func (b *Builder) getKustomization(ctx context.Context) (*kustomizev1.Kustomizat
 // It expects a kustomization.yaml file in the resourcesPath, and it will
 // generate a kustomization.yaml file if it doesn't exist
 func (b *Builder) Build() ([]byte, error) {
-	m, err := b.build()
-	if err != nil {
+	var buf bytes.Buffer
+	if err := b.BuildTo(context.Background(), &buf); err != nil {
 		return nil, err
 	}
+	return buf.Bytes(), nil
+}
+
+// BuildTo builds the yaml manifests like Build, and writes them to w one
+// resource at a time, so that the multi-doc YAML is never held in memory.
+// It stops before writing the next resource when ctx is cancelled, in which
+// case the output is truncated.
+func (b *Builder) BuildTo(ctx context.Context, w io.Writer) error {
+	return b.walk(ctx, func(i int, data []byte) error {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(data)
+		return err
+	})
+}
 
-	resources, err := m.AsYaml()
+// walk builds the kustomization and calls fn with the YAML of each resource in
+// order, until ctx is cancelled. The resources are released as they're visited.
+func (b *Builder) walk(ctx context.Context, fn func(i int, data []byte) error) error {
+	m, err := b.build()
 	if err != nil {
-		return nil, fmt.Errorf("kustomize build failed: %w", err)
+		return err
 	}
 
-	return resources, nil
+	resources := m.Resources()
+	m.Clear()
+	for i, res := range resources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := res.AsYAML()
+		if err != nil {
+			return fmt.Errorf("kustomize build failed: %w", err)
+		}
+		resources[i] = nil
+
+		if err := fn(i, data); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (b *Builder) build() (m resmap.ResMap, err error) {
@@ -157,6 +277,21 @@ func (b *Builder) build() (m resmap.ResMap, err error) {
 	// store the kustomization object
 	b.kustomization = k
 
+	// download the source artifact and build the kustomization path in it
+	if b.fromSource {
+		dir, path, er := b.fetchSource(ctx, k)
+		if er != nil {
+			err = er
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		b.mu.Lock()
+		b.resourcesPath = path
+		b.sourceDir = dir
+		b.mu.Unlock()
+	}
+
 	// generate kustomization.yaml if needed
 	action, er := b.generate(*k, b.resourcesPath)
 	if er != nil {
@@ -175,7 +310,7 @@ func (b *Builder) build() (m resmap.ResMap, err error) {
 	}()
 
 	// build the kustomization
-	m, err = b.do(ctx, *k, b.resourcesPath)
+	m, err = b.do(ctx, withSubstitutions(*k, b.substitutions), b.resourcesPath)
 	if err != nil {
 		return
 	}
@@ -248,6 +383,27 @@ func (b *Builder) do(ctx context.Context, kustomization kustomizev1.Kustomizatio
 	return m, nil
 }
 
+// withSubstitutions returns a copy of the kustomization that substitutes the
+// given variables, without reading the ConfigMaps and Secrets from the cluster.
+func withSubstitutions(kustomization kustomizev1.Kustomization, vars map[string]string) kustomizev1.Kustomization {
+	if len(vars) == 0 {
+		return kustomization
+	}
+
+	k := kustomization.DeepCopy()
+	if k.Spec.PostBuild == nil {
+		k.Spec.PostBuild = &kustomizev1.PostBuild{}
+	}
+	k.Spec.PostBuild.SubstituteFrom = nil
+	if k.Spec.PostBuild.Substitute == nil {
+		k.Spec.PostBuild.Substitute = make(map[string]string, len(vars))
+	}
+	for key, value := range vars {
+		k.Spec.PostBuild.Substitute[key] = value
+	}
+	return *k
+}
+
 func (b *Builder) setOwnerLabels(res *resource.Resource) error {
 	labels := res.GetLabels()
 
@@ -404,5 +560,9 @@ func (b *Builder) Cancel() error {
 		return err
 	}
 
+	if b.sourceDir != "" {
+		return os.RemoveAll(b.sourceDir)
+	}
+
 	return nil
 }
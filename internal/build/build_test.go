@@ -19,6 +19,7 @@ package build
 import (
 	"testing"
 
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
 	"github.com/google/go-cmp/cmp"
 	"sigs.k8s.io/kustomize/api/resource"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
@@ -157,3 +158,73 @@ type: kubernetes.io/dockerconfigjson
 		})
 	}
 }
+
+func TestWithSubstitutions(t *testing.T) {
+	kustomization := kustomizev1.Kustomization{
+		Spec: kustomizev1.KustomizationSpec{
+			PostBuild: &kustomizev1.PostBuild{
+				Substitute: map[string]string{"cluster_env": "prod", "region": "eu-west-1"},
+				SubstituteFrom: []kustomizev1.SubstituteReference{
+					{Kind: "ConfigMap", Name: "cluster-vars"},
+				},
+			},
+		},
+	}
+
+	k := withSubstitutions(kustomization, map[string]string{"cluster_env": "ci", "cluster_name": "kind"})
+	expected := &kustomizev1.PostBuild{
+		Substitute: map[string]string{"cluster_env": "ci", "cluster_name": "kind", "region": "eu-west-1"},
+	}
+	if diff := cmp.Diff(expected, k.Spec.PostBuild); diff != "" {
+		t.Errorf("unexpected post build (-want +got):\n%s", diff)
+	}
+	if len(kustomization.Spec.PostBuild.SubstituteFrom) != 1 || kustomization.Spec.PostBuild.Substitute["cluster_env"] != "prod" {
+		t.Error("expected the kustomization to be left unchanged")
+	}
+
+	k = withSubstitutions(kustomizev1.Kustomization{}, map[string]string{"cluster_env": "ci"})
+	if k.Spec.PostBuild == nil || k.Spec.PostBuild.Substitute["cluster_env"] != "ci" {
+		t.Errorf("expected the substitutions to be set, got %+v", k.Spec.PostBuild)
+	}
+
+	k = withSubstitutions(kustomization, nil)
+	if diff := cmp.Diff(kustomization, k); diff != "" {
+		t.Errorf("unexpected kustomization (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseExternalDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "arguments",
+			command:  "diff -u -N",
+			expected: []string{"diff", "-u", "-N"},
+		},
+		{
+			name:     "quoted arguments",
+			command:  `"/opt/my tools/differ" --label 'live state' -u`,
+			expected: []string{"/opt/my tools/differ", "--label", "live state", "-u"},
+		},
+		{
+			name:    "unterminated quote",
+			command: `diff --label 'live`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExternalDiff(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExternalDiff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("unexpected arguments (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -23,8 +23,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
@@ -35,6 +38,8 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/homeport/dyff/pkg/dyff"
 	"github.com/lucasb-eyer/go-colorful"
+	"gomodules.xyz/jsonpatch/v2"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
@@ -52,40 +57,157 @@ func (b *Builder) Manager() (*ssa.ResourceManager, error) {
 	return ssa.NewResourceManager(b.client, statusPoller, owner), nil
 }
 
+// DiffAction is the change made to an object by the reconciliation.
+type DiffAction string
+
+const (
+	CreatedDiffAction DiffAction = "created"
+	ChangedDiffAction DiffAction = "changed"
+	DeletedDiffAction DiffAction = "deleted"
+	// OrphanedDiffAction is set on the objects removed from the build that
+	// are left on the cluster because garbage collection is disabled.
+	OrphanedDiffAction DiffAction = "orphaned"
+)
+
+// ObjectDiff is the change made to an object by the reconciliation. For the
+// changed objects, Patch holds the JSON patch from the cluster state to the
+// result of the server-side dry-run.
+type ObjectDiff struct {
+	Action     DiffAction            `json:"action"`
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Namespace  string                `json:"namespace,omitempty"`
+	Name       string                `json:"name"`
+	Patch      []jsonpatch.Operation `json:"patch,omitempty"`
+
+	subject string
+	// liveFile and mergedFile hold the YAML of the changed objects, they're
+	// spilled to disk to keep the memory usage low on large builds
+	liveFile   string
+	mergedFile string
+}
+
+func newObjectDiff(action DiffAction, subject string, obj *unstructured.Unstructured) ObjectDiff {
+	return ObjectDiff{
+		Action:     action,
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		subject:    subject,
+	}
+}
+
+// Diff returns the human readable diff of the build with the cluster state,
+// and whether any object would be created, changed or deleted.
 func (b *Builder) Diff() (string, bool, error) {
-	output := strings.Builder{}
-	createdOrDrifted := false
-	res, err := b.Build()
+	spillDir, err := os.MkdirTemp("", "flux-diff")
 	if err != nil {
-		return "", createdOrDrifted, err
+		return "", false, err
+	}
+	defer cleanupDir(spillDir)
+
+	diffs, diffErrs := b.computeDiffs(spillDir)
+	output := strings.Builder{}
+	if err := writeDiffs(diffs, &output, b.externalDiff); err != nil {
+		return "", len(diffs) > 0, err
 	}
-	// convert the build result into Kubernetes unstructured objects
-	objects, err := ssa.ReadObjects(bytes.NewReader(res))
+	return output.String(), len(diffs) > 0, diffErrs
+}
+
+// DiffObjects builds the manifests, performs a server-side dry-run and
+// returns the objects that would be created, changed or deleted.
+func (b *Builder) DiffObjects() ([]ObjectDiff, error) {
+	return b.computeDiffs("")
+}
+
+// computeDiffs returns the objects that would be created, changed or deleted.
+// When spillDir is set, the live and merged YAML of the changed objects are
+// written to it for the human readable diff.
+func (b *Builder) computeDiffs(spillDir string) ([]ObjectDiff, error) {
+	// convert the build result into Kubernetes unstructured objects,
+	// one resource at a time so that the multi-doc YAML isn't held in memory
+	var objects []*unstructured.Unstructured
+	err := b.walk(context.Background(), func(_ int, data []byte) error {
+		objs, err := ssa.ReadObjects(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		objects = append(objects, objs...)
+		return nil
+	})
 	if err != nil {
-		return "", createdOrDrifted, err
+		return nil, err
 	}
 
 	resourceManager, err := b.Manager()
 	if err != nil {
-		return "", createdOrDrifted, err
+		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
 	defer cancel()
 
 	if err := ssa.SetNativeKindsDefaults(objects); err != nil {
-		return "", createdOrDrifted, err
+		return nil, err
 	}
 
-	var diffErrs error
 	// create an inventory of objects to be reconciled
 	newInventory := newInventory()
-	for _, obj := range objects {
-		diffOptions := ssa.DiffOptions{
-			Exclusions: map[string]string{
-				"kustomize.toolkit.fluxcd.io/reconcile": "disabled",
-			},
+	diffOptions := ssa.DiffOptions{
+		Exclusions: map[string]string{
+			"kustomize.toolkit.fluxcd.io/reconcile": "disabled",
+		},
+	}
+	diffs, diffErrs := diffObjects(ctx, resourceManager, objects, diffOptions, b.ignorePaths, newInventory, spillDir)
+
+	if diffErrs == nil {
+		removed, err := removedObjects(b.kustomization, newInventory, b.detectOrphans)
+		if err != nil {
+			return diffs, err
 		}
+		diffs = append(diffs, removed...)
+	}
+
+	return diffs, diffErrs
+}
+
+// removedObjects returns the objects of the Kustomization inventory that are
+// not in the new inventory. They're deleted when garbage collection is enabled,
+// otherwise they're orphaned and only returned with detectOrphans.
+func removedObjects(kustomization *kustomizev1.Kustomization, inventory *kustomizev1.ResourceInventory, detectOrphans bool) ([]ObjectDiff, error) {
+	prune := kustomization.Spec.Prune
+	if (!prune && !detectOrphans) || kustomization.Status.Inventory == nil {
+		return nil, nil
+	}
+
+	deletedObjects, err := diffInventory(kustomization.Status.Inventory, inventory)
+	if err != nil {
+		return nil, err
+	}
+
+	action := DeletedDiffAction
+	if !prune {
+		action = OrphanedDiffAction
+	}
+	var diffs []ObjectDiff
+	for _, object := range deletedObjects {
+		diffs = append(diffs, newObjectDiff(action, ssa.FmtUnstructured(object), object))
+	}
+	return diffs, nil
+}
+
+// diffObjects performs a server-side dry-run apply of the objects, returns
+// the created and changed objects, and adds the objects to the inventory.
+// The live and merged objects are written to spillDir when it's set, instead
+// of being kept in memory until the diffs are printed. Errors are gathered so
+// that all the diffs are returned.
+func diffObjects(ctx context.Context, resourceManager *ssa.ResourceManager, objects []*unstructured.Unstructured,
+	diffOptions ssa.DiffOptions, ignorePaths []*regexp.Regexp, inventory *kustomizev1.ResourceInventory,
+	spillDir string) ([]ObjectDiff, error) {
+	var diffs []ObjectDiff
+	var diffErrs error
+	for i, obj := range objects {
 		change, liveObject, mergedObject, err := resourceManager.Diff(ctx, obj, diffOptions)
 		if err != nil {
 			// gather errors and continue, as we want to see all the diffs
@@ -99,65 +221,209 @@ func (b *Builder) Diff() (string, bool, error) {
 			diffSopsSecret(obj, liveObject, mergedObject, change)
 		}
 
+		// drop the ignored fields, the object is unchanged if they're the only difference
+		if len(ignorePaths) > 0 && change.Action == string(ssa.ConfiguredAction) {
+			liveObject, mergedObject = liveObject.DeepCopy(), mergedObject.DeepCopy()
+			removeIgnoredPaths(liveObject.Object, "", ignorePaths)
+			removeIgnoredPaths(mergedObject.Object, "", ignorePaths)
+			if !hasDrifted(liveObject, mergedObject) {
+				change.Action = string(ssa.UnchangedAction)
+			}
+		}
+
 		if change.Action == string(ssa.CreatedAction) {
-			output.WriteString(writeString(fmt.Sprintf("► %s created\n", change.Subject), bunt.Green))
-			createdOrDrifted = true
+			diffs = append(diffs, newObjectDiff(CreatedDiffAction, change.Subject, obj))
 		}
 
 		if change.Action == string(ssa.ConfiguredAction) {
-			output.WriteString(writeString(fmt.Sprintf("► %s drifted\n", change.Subject), bunt.WhiteSmoke))
-			liveFile, mergedFile, tmpDir, err := writeYamls(liveObject, mergedObject)
+			objectDiff := newObjectDiff(ChangedDiffAction, change.Subject, obj)
+			objectDiff.Patch, err = jsonPatch(liveObject, mergedObject)
 			if err != nil {
-				return "", createdOrDrifted, err
+				diffErrs = multierror.Append(diffErrs, err)
+				continue
 			}
-			defer cleanupDir(tmpDir)
-
-			err = diff(liveFile, mergedFile, &output)
-			if err != nil {
-				return "", createdOrDrifted, err
+			if spillDir != "" {
+				objectDiff.liveFile, objectDiff.mergedFile, err = writeYamls(spillDir, strconv.Itoa(i), liveObject, mergedObject)
+				if err != nil {
+					diffErrs = multierror.Append(diffErrs, err)
+					continue
+				}
 			}
+			diffs = append(diffs, objectDiff)
+		}
+
+		addObjectsToInventory(inventory, change)
+	}
+
+	return diffs, diffErrs
+}
+
+// jsonPatch returns the JSON patch operations that turn the live object into
+// the merged one.
+func jsonPatch(liveObject, mergedObject *unstructured.Unstructured) ([]jsonpatch.Operation, error) {
+	live, err := liveObject.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergedObject.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.CreatePatch(live, merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the patch of %s: %w", ssa.FmtUnstructured(mergedObject), err)
+	}
+	// sort the operations for a stable output, keeping the order of the
+	// operations on the same list as they depend on the item indexes
+	sort.SliceStable(patch, func(i, j int) bool {
+		return listPath(patch[i].Path) < listPath(patch[j].Path)
+	})
+	return patch, nil
+}
 
-			createdOrDrifted = true
+// listPath returns the JSON pointer up to the first list index.
+func listPath(pointer string) string {
+	segments := strings.Split(pointer, "/")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			return strings.Join(segments[:i], "/")
 		}
+	}
+	return pointer
+}
 
-		addObjectsToInventory(newInventory, change)
+// writeDiffs writes the objects that would be created, changed or deleted to
+// output, followed by the human readable diff of the changed objects. The
+// diff is printed by the externalDiff command when it's set.
+func writeDiffs(diffs []ObjectDiff, output io.Writer, externalDiff []string) error {
+	for _, objectDiff := range diffs {
+		switch objectDiff.Action {
+		case CreatedDiffAction:
+			fmt.Fprint(output, writeString(fmt.Sprintf("► %s created\n", objectDiff.subject), bunt.Green))
+		case ChangedDiffAction:
+			fmt.Fprint(output, writeString(fmt.Sprintf("► %s drifted\n", objectDiff.subject), bunt.WhiteSmoke))
+			if len(externalDiff) > 0 {
+				if err := runExternalDiff(externalDiff, objectDiff.liveFile, objectDiff.mergedFile, output); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := diff(objectDiff.liveFile, objectDiff.mergedFile, output); err != nil {
+				return err
+			}
+		case DeletedDiffAction:
+			fmt.Fprint(output, writeString(fmt.Sprintf("► %s deleted\n", objectDiff.subject), bunt.OrangeRed))
+		case OrphanedDiffAction:
+			fmt.Fprint(output, writeString(fmt.Sprintf("► %s orphaned\n", objectDiff.subject), bunt.Gold))
+		}
 	}
+	return nil
+}
 
-	if b.kustomization.Spec.Prune && diffErrs == nil {
-		oldStatus := b.kustomization.Status.DeepCopy()
-		if oldStatus.Inventory != nil {
-			diffObjects, err := diffInventory(oldStatus.Inventory, newInventory)
-			if err != nil {
-				return "", createdOrDrifted, err
+// compileIgnorePaths converts the ignore path globs to regular expressions.
+// The paths are the dot separated field names and list indexes of the objects,
+// a '*' matches any field name or index and a '**' matches any number of them.
+func compileIgnorePaths(globs []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, glob := range globs {
+		var expr strings.Builder
+		expr.WriteString("^")
+		for i := 0; i < len(glob); i++ {
+			switch {
+			case strings.HasPrefix(glob[i:], "**"):
+				expr.WriteString(".*")
+				i++
+			case glob[i] == '*':
+				expr.WriteString("[^.]*")
+			case glob[i] == '?':
+				expr.WriteString("[^.]")
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(glob[i])))
 			}
-			for _, object := range diffObjects {
-				output.WriteString(writeString(fmt.Sprintf("► %s deleted\n", ssa.FmtUnstructured(object)), bunt.OrangeRed))
+		}
+		expr.WriteString("$")
+
+		re, err := regexp.Compile(expr.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore path %q: %w", glob, err)
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+// removeIgnoredPaths removes the fields matching one of the ignore paths from
+// the value. The list items are set to nil so that the indexes are kept.
+func removeIgnoredPaths(value interface{}, prefix string, ignorePaths []*regexp.Regexp) {
+	matches := func(path string) bool {
+		for _, re := range ignorePaths {
+			if re.MatchString(path) {
+				return true
 			}
 		}
+		return false
+	}
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
 	}
 
-	return output.String(), createdOrDrifted, diffErrs
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			path := join(key)
+			if matches(path) {
+				delete(v, key)
+				continue
+			}
+			removeIgnoredPaths(item, path, ignorePaths)
+		}
+	case []interface{}:
+		for i, item := range v {
+			path := join(strconv.Itoa(i))
+			if matches(path) {
+				v[i] = nil
+				continue
+			}
+			removeIgnoredPaths(item, path, ignorePaths)
+		}
+	}
 }
 
-func writeYamls(liveObject, mergedObject *unstructured.Unstructured) (string, string, string, error) {
-	tmpDir, err := os.MkdirTemp("", "")
-	if err != nil {
-		return "", "", "", err
+// hasDrifted compares the metadata labels and annotations, and the fields
+// other than metadata and status, of the objects.
+func hasDrifted(liveObject, mergedObject *unstructured.Unstructured) bool {
+	if !apiequality.Semantic.DeepEqual(liveObject.GetLabels(), mergedObject.GetLabels()) ||
+		!apiequality.Semantic.DeepEqual(liveObject.GetAnnotations(), mergedObject.GetAnnotations()) {
+		return true
+	}
+
+	live, merged := liveObject.DeepCopy(), mergedObject.DeepCopy()
+	for _, field := range []string{"metadata", "status"} {
+		unstructured.RemoveNestedField(live.Object, field)
+		unstructured.RemoveNestedField(merged.Object, field)
 	}
+	return !apiequality.Semantic.DeepEqual(live.Object, merged.Object)
+}
 
+// writeYamls writes the live and merged objects in dir, the file names are
+// prefixed with the given name.
+func writeYamls(dir, name string, liveObject, mergedObject *unstructured.Unstructured) (string, string, error) {
 	liveYAML, _ := yaml.Marshal(liveObject)
-	liveFile := filepath.Join(tmpDir, "live.yaml")
+	liveFile := filepath.Join(dir, name+"-live.yaml")
 	if err := os.WriteFile(liveFile, liveYAML, 0644); err != nil {
-		return "", "", "", err
+		return "", "", err
 	}
 
 	mergedYAML, _ := yaml.Marshal(mergedObject)
-	mergedFile := filepath.Join(tmpDir, "merged.yaml")
+	mergedFile := filepath.Join(dir, name+"-merged.yaml")
 	if err := os.WriteFile(mergedFile, mergedYAML, 0644); err != nil {
-		return "", "", "", err
+		return "", "", err
 	}
 
-	return liveFile, mergedFile, tmpDir, nil
+	return liveFile, mergedFile, nil
 }
 
 func writeString(t string, color colorful.Color) string {
@@ -198,6 +464,22 @@ func diff(liveFile, mergedFile string, output io.Writer) error {
 	return nil
 }
 
+// runExternalDiff runs the command with the live and merged files as the last
+// arguments. Like diff, the command is expected to exit with 1 when the files
+// differ.
+func runExternalDiff(command []string, liveFile, mergedFile string, output io.Writer) error {
+	cmd := exec.Command(command[0], append(command[1:], liveFile, mergedFile)...)
+	cmd.Stdout = output
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("external diff %q failed: %w", strings.Join(command, " "), err)
+	}
+	return nil
+}
+
 func diffSopsSecret(obj, liveObject, mergedObject *unstructured.Unstructured, change *ssa.ChangeSetEntry) {
 	// get both data and stringdata maps
 	data := obj.Object[dataField]
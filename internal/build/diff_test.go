@@ -0,0 +1,241 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"strings"
+	"testing"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRemoveIgnoredPaths(t *testing.T) {
+	ignorePaths, err := compileIgnorePaths([]string{
+		"webhooks.*.clientConfig.caBundle",
+		"metadata.annotations.checksum/*",
+		"spec.**.image",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"checksum/config":                   "abc",
+				"kubectl.kubernetes.io/restartedAt": "now",
+			},
+		},
+		"webhooks": []interface{}{
+			map[string]interface{}{
+				"name":         "validate",
+				"clientConfig": map[string]interface{}{"caBundle": "Cg==", "url": "https://webhook"},
+			},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:v1"},
+				},
+			},
+		},
+	}
+	removeIgnoredPaths(obj, "", ignorePaths)
+
+	expected := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/restartedAt": "now",
+			},
+		},
+		"webhooks": []interface{}{
+			map[string]interface{}{
+				"name":         "validate",
+				"clientConfig": map[string]interface{}{"url": "https://webhook"},
+			},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, obj); diff != "" {
+		t.Errorf("unexpected object (-want +got):\n%s", diff)
+	}
+}
+
+func TestHasDrifted(t *testing.T) {
+	object := func(replicas int64, annotations map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"replicas": replicas},
+		}}
+		u.SetName("podinfo")
+		u.SetAnnotations(annotations)
+		return u
+	}
+
+	live := object(1, map[string]string{"team": "dev"})
+	if hasDrifted(live, object(1, map[string]string{"team": "dev"})) {
+		t.Error("expected identical objects not to have drifted")
+	}
+	if !hasDrifted(live, object(2, map[string]string{"team": "dev"})) {
+		t.Error("expected a spec change to be a drift")
+	}
+	if !hasDrifted(live, object(1, nil)) {
+		t.Error("expected an annotation change to be a drift")
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"spec": map[string]interface{}{
+			"type": "ClusterIP",
+			"ports": []interface{}{
+				map[string]interface{}{"name": "http", "port": int64(9899)},
+			},
+		},
+	}}
+	merged := live.DeepCopy()
+	unstructured.SetNestedField(merged.Object, "NodePort", "spec", "type")
+	unstructured.SetNestedSlice(merged.Object, []interface{}{
+		map[string]interface{}{"name": "http", "port": int64(9898)},
+	}, "spec", "ports")
+
+	patch, err := jsonPatch(live, merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []jsonpatch.Operation{
+		{Operation: "replace", Path: "/spec/ports/0/port", Value: float64(9898)},
+		{Operation: "replace", Path: "/spec/type", Value: "NodePort"},
+	}
+	if diff := cmp.Diff(expected, patch); diff != "" {
+		t.Errorf("unexpected patch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteDiffs(t *testing.T) {
+	object := func(kind, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind(kind)
+		u.SetNamespace("default")
+		u.SetName(name)
+		return u
+	}
+
+	live, merged := object("ConfigMap", "changed"), object("ConfigMap", "changed")
+	merged.SetLabels(map[string]string{"app": "podinfo"})
+	changed := newObjectDiff(ChangedDiffAction, "ConfigMap/default/changed", merged)
+	var err error
+	changed.liveFile, changed.mergedFile, err = writeYamls(t.TempDir(), "0", live, merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs := []ObjectDiff{
+		newObjectDiff(CreatedDiffAction, "ConfigMap/default/new", object("ConfigMap", "new")),
+		newObjectDiff(DeletedDiffAction, "Secret/default/old", object("Secret", "old")),
+		changed,
+	}
+	var output strings.Builder
+	if err := writeDiffs(diffs, &output, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"► ConfigMap/default/new created", "► Secret/default/old deleted",
+		"► ConfigMap/default/changed drifted", "podinfo"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in the output, got %q", expected, output.String())
+		}
+	}
+	if diffs[1].Kind != "Secret" || diffs[1].Namespace != "default" || diffs[1].Name != "old" {
+		t.Errorf("unexpected object diff %+v", diffs[1])
+	}
+}
+
+func TestRemovedObjects(t *testing.T) {
+	kustomization := &kustomizev1.Kustomization{
+		Status: kustomizev1.KustomizationStatus{
+			Inventory: &kustomizev1.ResourceInventory{
+				Entries: []kustomizev1.ResourceRef{
+					{ID: "default_podinfo__Service", Version: "v1"},
+					{ID: "default_podinfo_apps_Deployment", Version: "v1"},
+				},
+			},
+		},
+	}
+	inventory := &kustomizev1.ResourceInventory{
+		Entries: []kustomizev1.ResourceRef{
+			{ID: "default_podinfo_apps_Deployment", Version: "v1"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		prune         bool
+		detectOrphans bool
+		expected      []DiffAction
+	}{
+		{name: "prune", prune: true, expected: []DiffAction{DeletedDiffAction}},
+		{name: "no prune", prune: false},
+		{name: "orphans", prune: false, detectOrphans: true, expected: []DiffAction{OrphanedDiffAction}},
+		{name: "prune and orphans", prune: true, detectOrphans: true, expected: []DiffAction{DeletedDiffAction}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kustomization.Spec.Prune = tt.prune
+			diffs, err := removedObjects(kustomization, inventory, tt.detectOrphans)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actions []DiffAction
+			for _, d := range diffs {
+				actions = append(actions, d.Action)
+				if d.Kind != "Service" || d.Namespace != "default" || d.Name != "podinfo" {
+					t.Errorf("unexpected object %s/%s/%s", d.Kind, d.Namespace, d.Name)
+				}
+			}
+			if diff := cmp.Diff(tt.expected, actions); diff != "" {
+				t.Errorf("unexpected actions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunExternalDiff(t *testing.T) {
+	var output strings.Builder
+	err := runExternalDiff([]string{"sh", "-c", "echo $0 $1; exit 1"}, "live.yaml", "merged.yaml", &output)
+	if err != nil {
+		t.Fatalf("unexpected error for an exit status of 1: %v", err)
+	}
+	if output.String() != "live.yaml merged.yaml\n" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+
+	if err := runExternalDiff([]string{"sh", "-c", "exit 2"}, "live.yaml", "merged.yaml", &output); err == nil {
+		t.Error("expected an error for an exit status of 2")
+	}
+}
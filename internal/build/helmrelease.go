@@ -0,0 +1,448 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+// HelmReleaseBuilder renders the manifests of a HelmRelease.
+// It retrieves the HelmRelease object from the k8s cluster, fetches the chart
+// artifact from source-controller, composes the values and renders the chart
+// with the Helm template engine, then applies the post renderers.
+type HelmReleaseBuilder struct {
+	client       client.WithWatch
+	restMapper   meta.RESTMapper
+	restConfig   *rest.Config
+	kubeVersion  string
+	name         string
+	namespace    string
+	chartPath    string
+	valuesFiles  []string
+	timeout      time.Duration
+	externalDiff []string
+	helmRelease  *helmv2.HelmRelease
+}
+
+type HelmReleaseBuilderOptionFunc func(b *HelmReleaseBuilder) error
+
+// WithChartPath makes the builder render the chart at the given local path,
+// instead of the chart artifact of the HelmRelease.
+func WithChartPath(path string) HelmReleaseBuilderOptionFunc {
+	return func(b *HelmReleaseBuilder) error {
+		b.chartPath = path
+		return nil
+	}
+}
+
+// WithValuesFiles makes the builder merge the given values files on top of
+// the values of the HelmRelease.
+func WithValuesFiles(files []string) HelmReleaseBuilderOptionFunc {
+	return func(b *HelmReleaseBuilder) error {
+		b.valuesFiles = files
+		return nil
+	}
+}
+
+func WithHelmReleaseTimeout(timeout time.Duration) HelmReleaseBuilderOptionFunc {
+	return func(b *HelmReleaseBuilder) error {
+		b.timeout = timeout
+		return nil
+	}
+}
+
+// WithHelmReleaseExternalDiff makes the diff of the changed objects printed by
+// the given command instead of dyff.
+func WithHelmReleaseExternalDiff(command string) HelmReleaseBuilderOptionFunc {
+	return func(b *HelmReleaseBuilder) error {
+		args, err := parseExternalDiff(command)
+		if err != nil {
+			return err
+		}
+		b.externalDiff = args
+		return nil
+	}
+}
+
+// NewHelmReleaseBuilder returns a new HelmReleaseBuilder
+func NewHelmReleaseBuilder(rcg *genericclioptions.ConfigFlags, name string, opts ...HelmReleaseBuilderOptionFunc) (*HelmReleaseBuilder, error) {
+	kubeClient, err := utils.KubeClient(rcg)
+	if err != nil {
+		return nil, err
+	}
+
+	restMapper, err := rcg.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := rcg.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &HelmReleaseBuilder{
+		client:     kubeClient,
+		restMapper: restMapper,
+		restConfig: restConfig,
+		name:       name,
+		namespace:  *rcg.Namespace,
+	}
+
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.timeout == 0 {
+		b.timeout = defaultTimeout
+	}
+
+	// render the chart for the version of the cluster, as helm-controller does
+	if discoveryClient, err := rcg.ToDiscoveryClient(); err == nil {
+		if version, err := discoveryClient.ServerVersion(); err == nil {
+			b.kubeVersion = version.GitVersion
+		}
+	}
+
+	return b, nil
+}
+
+// Build renders the manifests of the HelmRelease and returns them as a
+// multi-doc YAML.
+func (b *HelmReleaseBuilder) Build() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	hr := &helmv2.HelmRelease{}
+	if err := b.client.Get(ctx, types.NamespacedName{Namespace: b.namespace, Name: b.name}, hr); err != nil {
+		return nil, err
+	}
+
+	// store the HelmRelease object
+	b.helmRelease = hr
+
+	tmpDir, err := os.MkdirTemp("", hr.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	chartPath := b.chartPath
+	if chartPath == "" {
+		chartPath, err = b.downloadChart(ctx, hr, tmpDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values, err := b.composeValues(ctx, hr)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := b.renderChart(hr, chartPath, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, postRenderer := range hr.Spec.PostRenderers {
+		if postRenderer.Kustomize == nil {
+			continue
+		}
+		manifests, err = postRenderKustomize(manifests, *postRenderer.Kustomize)
+		if err != nil {
+			return nil, fmt.Errorf("post renderer failed: %w", err)
+		}
+	}
+
+	return manifests, nil
+}
+
+// downloadChart fetches the artifact of the HelmChart generated for the
+// HelmRelease through the API server proxy, and writes it to dir.
+func (b *HelmReleaseBuilder) downloadChart(ctx context.Context, hr *helmv2.HelmRelease, dir string) (string, error) {
+	namespacedName := types.NamespacedName{
+		Namespace: hr.Spec.Chart.GetNamespace(hr.Namespace),
+		Name:      hr.GetHelmChartName(),
+	}
+	var chart sourcev1.HelmChart
+	if err := b.client.Get(ctx, namespacedName, &chart); err != nil {
+		return "", fmt.Errorf("failed to get HelmChart %s: %w", namespacedName, err)
+	}
+	if chart.Status.Artifact == nil {
+		return "", fmt.Errorf("HelmChart %s has no artifact", namespacedName)
+	}
+
+	data, err := FetchArtifact(ctx, b.restConfig, *chart.Status.Artifact)
+	if err != nil {
+		return "", fmt.Errorf("failed to download the chart of HelmChart %s: %w", namespacedName, err)
+	}
+
+	chartPath := filepath.Join(dir, filepath.Base(chart.Status.Artifact.Path))
+	if err := os.WriteFile(chartPath, data, 0o600); err != nil {
+		return "", err
+	}
+	return chartPath, nil
+}
+
+// composeValues merges the values of the HelmRelease in the same order as
+// helm-controller: the values from the referenced ConfigMaps and Secrets, the
+// inline values, then the local values files.
+func (b *HelmReleaseBuilder) composeValues(ctx context.Context, hr *helmv2.HelmRelease) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, ref := range hr.Spec.ValuesFrom {
+		namespacedName := types.NamespacedName{Namespace: hr.Namespace, Name: ref.Name}
+		var data string
+		var found bool
+		switch ref.Kind {
+		case "ConfigMap":
+			var cm corev1.ConfigMap
+			if err := b.client.Get(ctx, namespacedName, &cm); err != nil {
+				if apierrors.IsNotFound(err) && ref.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("could not find %s '%s'", ref.Kind, namespacedName)
+			}
+			data, found = cm.Data[ref.GetValuesKey()]
+		case "Secret":
+			var secret corev1.Secret
+			if err := b.client.Get(ctx, namespacedName, &secret); err != nil {
+				if apierrors.IsNotFound(err) && ref.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("could not find %s '%s'", ref.Kind, namespacedName)
+			}
+			var value []byte
+			value, found = secret.Data[ref.GetValuesKey()]
+			data = string(value)
+		default:
+			return nil, fmt.Errorf("unsupported ValuesReference kind '%s'", ref.Kind)
+		}
+
+		if !found {
+			if ref.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("missing key '%s' in %s '%s'", ref.GetValuesKey(), ref.Kind, namespacedName)
+		}
+
+		if ref.TargetPath != "" {
+			// set the value the same way as helm --set, as helm-controller does
+			if err := strvals.ParseInto(ref.TargetPath+"="+data, result); err != nil {
+				return nil, fmt.Errorf("unable to merge value from key '%s' in %s '%s' into target path '%s': %w",
+					ref.GetValuesKey(), ref.Kind, namespacedName, ref.TargetPath, err)
+			}
+			continue
+		}
+
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(data), &values); err != nil {
+			return nil, fmt.Errorf("unable to read values from key '%s' in %s '%s': %w", ref.GetValuesKey(), ref.Kind, namespacedName, err)
+		}
+		result = mergeValues(result, values)
+	}
+
+	result = mergeValues(result, hr.GetValues())
+
+	for _, file := range b.valuesFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("unable to read values from %s: %w", file, err)
+		}
+		result = mergeValues(result, values)
+	}
+
+	return result, nil
+}
+
+// mergeValues merges b into a, the values of b take precedence.
+// Nested maps are merged recursively, any other value is replaced.
+func mergeValues(a, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if v, ok := v.(map[string]interface{}); ok {
+			if bv, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergeValues(bv, v)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// renderChart renders the chart with the Helm SDK, using the install options
+// of the release like helm-controller does. The output is the same as the one
+// of helm template: the manifests, the CRDs unless they're skipped, and the
+// hooks unless they're disabled, without the tests when they're not enabled.
+func (b *HelmReleaseBuilder) renderChart(hr *helmv2.HelmRelease, chartPath string, values map[string]interface{}) ([]byte, error) {
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	cfg := &action.Configuration{
+		Log: func(string, ...interface{}) {},
+	}
+	install := action.NewInstall(cfg)
+	install.DryRun = true
+	install.ClientOnly = true
+	install.Replace = true
+	install.ReleaseName = hr.GetReleaseName()
+	install.Namespace = hr.GetReleaseNamespace()
+	installSpec := hr.Spec.GetInstall()
+	install.IncludeCRDs = !installSpec.SkipCRDs && installSpec.CRDs != helmv2.Skip
+	install.DisableHooks = installSpec.DisableHooks
+	install.DisableOpenAPIValidation = installSpec.DisableOpenAPIValidation
+	if b.kubeVersion != "" {
+		kubeVersion, err := chartutil.ParseKubeVersion(b.kubeVersion)
+		if err != nil {
+			return nil, err
+		}
+		install.KubeVersion = kubeVersion
+	}
+
+	rel, err := install.Run(chrt, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	var manifests strings.Builder
+	fmt.Fprintln(&manifests, strings.TrimSpace(rel.Manifest))
+	if !install.DisableHooks {
+		skipTests := !hr.Spec.GetTest().Enable
+		for _, hook := range rel.Hooks {
+			if skipTests && isTestHook(hook) {
+				continue
+			}
+			fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
+		}
+	}
+	return []byte(manifests.String()), nil
+}
+
+func isTestHook(hook *release.Hook) bool {
+	for _, event := range hook.Events {
+		if event == release.HookTest {
+			return true
+		}
+	}
+	return false
+}
+
+// postRenderKustomize applies the patches and images of a Kustomize post
+// renderer to the manifests.
+func postRenderKustomize(manifests []byte, spec helmv2.Kustomize) ([]byte, error) {
+	kus := kustypes.Kustomization{
+		TypeMeta: kustypes.TypeMeta{
+			APIVersion: kustypes.KustomizationVersion,
+			Kind:       kustypes.KustomizationKind,
+		},
+		Resources: []string{"resources.yaml"},
+	}
+
+	for _, patch := range spec.PatchesStrategicMerge {
+		kus.PatchesStrategicMerge = append(kus.PatchesStrategicMerge, kustypes.PatchStrategicMerge(patch.Raw))
+	}
+
+	for _, patch := range spec.PatchesJSON6902 {
+		ops, err := json.Marshal(patch.Patch)
+		if err != nil {
+			return nil, err
+		}
+		kus.PatchesJson6902 = append(kus.PatchesJson6902, kustypes.Patch{
+			Patch: string(ops),
+			Target: &kustypes.Selector{
+				ResId: resid.NewResIdWithNamespace(
+					resid.Gvk{Group: patch.Target.Group, Version: patch.Target.Version, Kind: patch.Target.Kind},
+					patch.Target.Name, patch.Target.Namespace),
+				AnnotationSelector: patch.Target.AnnotationSelector,
+				LabelSelector:      patch.Target.LabelSelector,
+			},
+		})
+	}
+
+	for _, image := range spec.Images {
+		kus.Images = append(kus.Images, kustypes.Image{
+			Name:    image.Name,
+			NewName: image.NewName,
+			NewTag:  image.NewTag,
+			Digest:  image.Digest,
+		})
+	}
+
+	kusData, err := yaml.Marshal(kus)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := filesys.MakeFsInMemory()
+	if err := fs.WriteFile("resources.yaml", manifests); err != nil {
+		return nil, err
+	}
+	if err := fs.WriteFile("kustomization.yaml", kusData); err != nil {
+		return nil, err
+	}
+
+	buildOptions := &krusty.Options{
+		LoadRestrictions: kustypes.LoadRestrictionsNone,
+		PluginConfig:     kustypes.DisabledPluginConfig(),
+	}
+	m, err := krusty.MakeKustomizer(buildOptions).Run(fs, ".")
+	if err != nil {
+		return nil, err
+	}
+	return m.AsYaml()
+}
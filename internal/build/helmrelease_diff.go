@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/ssa"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+const (
+	helmControllerName  = "helm-controller"
+	helmControllerGroup = "helm.toolkit.fluxcd.io"
+
+	// helmHookAnnotation marks the objects managed by Helm as hooks, they are
+	// created and deleted by the release actions and can't drift.
+	helmHookAnnotation = "helm.sh/hook"
+
+	// helmResourcePolicyAnnotation set to keep makes Helm leave the object
+	// in the cluster when it's removed from the release.
+	helmResourcePolicyAnnotation = "helm.sh/resource-policy"
+)
+
+func (b *HelmReleaseBuilder) Manager() (*ssa.ResourceManager, error) {
+	statusPoller := polling.NewStatusPoller(b.client, b.restMapper, nil)
+	owner := ssa.Owner{
+		Field: helmControllerName,
+		Group: helmControllerGroup,
+	}
+
+	return ssa.NewResourceManager(b.client, statusPoller, owner), nil
+}
+
+// Diff returns the human readable diff of the rendered HelmRelease with the
+// cluster state, and whether any object would be created, changed or deleted.
+func (b *HelmReleaseBuilder) Diff() (string, bool, error) {
+	spillDir, err := os.MkdirTemp("", "flux-diff")
+	if err != nil {
+		return "", false, err
+	}
+	defer cleanupDir(spillDir)
+
+	diffs, diffErrs := b.computeDiffs(spillDir)
+	output := strings.Builder{}
+	if err := writeDiffs(diffs, &output, b.externalDiff); err != nil {
+		return "", len(diffs) > 0, err
+	}
+	return output.String(), len(diffs) > 0, diffErrs
+}
+
+// DiffObjects renders the HelmRelease, then performs a server-side dry-run of
+// the rendered objects and returns the objects that would be created or changed,
+// followed by the objects of the last release that would be deleted.
+// The Helm hooks are skipped.
+func (b *HelmReleaseBuilder) DiffObjects() ([]ObjectDiff, error) {
+	return b.computeDiffs("")
+}
+
+// computeDiffs returns the objects that would be created, changed or deleted,
+// the live and merged YAML of the changed objects are written to spillDir when
+// it's set.
+func (b *HelmReleaseBuilder) computeDiffs(spillDir string) ([]ObjectDiff, error) {
+	res, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := ssa.ReadObjects(bytes.NewReader(res))
+	if err != nil {
+		return nil, err
+	}
+	objects = b.releaseObjects(objects)
+
+	resourceManager, err := b.Manager()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	if err := ssa.SetNativeKindsDefaults(objects); err != nil {
+		return nil, err
+	}
+
+	inventory := newInventory()
+	diffs, diffErrs := diffObjects(ctx, resourceManager, objects, ssa.DiffOptions{}, nil, inventory, spillDir)
+
+	if diffErrs == nil {
+		removed, err := b.removedReleaseObjects(ctx, inventory)
+		if err != nil {
+			return diffs, err
+		}
+		diffs = append(diffs, removed...)
+	}
+
+	return diffs, diffErrs
+}
+
+// removedReleaseObjects returns the objects of the last release, read from
+// the Helm storage, that are not in the inventory of the rendered objects.
+// Helm deletes them on upgrade, unless they're annotated with the keep
+// resource policy, in which case they're orphaned.
+func (b *HelmReleaseBuilder) removedReleaseObjects(ctx context.Context, inventory *kustomizev1.ResourceInventory) ([]ObjectDiff, error) {
+	lastObjects, err := LastReleaseObjects(ctx, b.client, b.helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	lastInventory := newInventory()
+	kept := make(map[string]bool)
+	for _, obj := range b.releaseObjects(lastObjects) {
+		id := object.UnstructuredToObjMetadata(obj).String()
+		lastInventory.Entries = append(lastInventory.Entries, kustomizev1.ResourceRef{
+			ID:      id,
+			Version: obj.GroupVersionKind().Version,
+		})
+		if obj.GetAnnotations()[helmResourcePolicyAnnotation] == "keep" {
+			kept[id] = true
+		}
+	}
+
+	deletedObjects, err := diffInventory(lastInventory, inventory)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []ObjectDiff
+	for _, obj := range deletedObjects {
+		action := DeletedDiffAction
+		if kept[object.UnstructuredToObjMetadata(obj).String()] {
+			action = OrphanedDiffAction
+		}
+		diffs = append(diffs, newObjectDiff(action, ssa.FmtUnstructured(obj), obj))
+	}
+	return diffs, nil
+}
+
+// releaseObjects drops the Helm hooks from the objects, and sets the release
+// namespace on the namespaced objects that don't specify one, as Helm does
+// when installing the release.
+func (b *HelmReleaseBuilder) releaseObjects(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if _, ok := obj.GetAnnotations()[helmHookAnnotation]; ok {
+			continue
+		}
+
+		if obj.GetNamespace() == "" {
+			gvk := obj.GroupVersionKind()
+			mapping, err := b.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err == nil && mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+				obj.SetNamespace(b.helmRelease.GetReleaseNamespace())
+			}
+		}
+		result = append(result, obj)
+	}
+	return result
+}
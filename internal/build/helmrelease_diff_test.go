@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestReleaseObjects(t *testing.T) {
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	clusterRoleGVK := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	restMapper.Add(clusterRoleGVK, meta.RESTScopeRoot)
+
+	object := func(gvk schema.GroupVersionKind, name, namespace string, annotations map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetName(name)
+		u.SetNamespace(namespace)
+		u.SetAnnotations(annotations)
+		return u
+	}
+
+	b := &HelmReleaseBuilder{
+		restMapper: restMapper,
+		helmRelease: &helmv2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "flux-system"},
+			Spec:       helmv2.HelmReleaseSpec{TargetNamespace: "apps"},
+		},
+	}
+	objects := b.releaseObjects([]*unstructured.Unstructured{
+		object(configMapGVK, "config", "", nil),
+		object(configMapGVK, "other", "default", nil),
+		object(clusterRoleGVK, "reader", "", nil),
+		object(configMapGVK, "test", "", map[string]string{helmHookAnnotation: "test"}),
+	})
+
+	expected := []string{"apps/config", "default/other", "/reader"}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d objects, got %d", len(expected), len(objects))
+	}
+	for i, obj := range objects {
+		if got := obj.GetNamespace() + "/" + obj.GetName(); got != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], got)
+		}
+	}
+}
+
+func TestRemovedReleaseObjects(t *testing.T) {
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(configMapGVK, meta.RESTScopeNamespace)
+
+	// encode the release as the Helm secrets storage driver does
+	manifest := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+  annotations:
+    helm.sh/resource-policy: keep
+`
+	release, err := json.Marshal(map[string]interface{}{"name": "podinfo", "manifest": manifest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(release); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.podinfo.v2", Namespace: "apps"},
+			Data:       map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))},
+		},
+	).Build()
+
+	b := &HelmReleaseBuilder{
+		client:     kubeClient,
+		restMapper: restMapper,
+		helmRelease: &helmv2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+			Status:     helmv2.HelmReleaseStatus{LastReleaseRevision: 2},
+		},
+	}
+	inventory := &kustomizev1.ResourceInventory{
+		Entries: []kustomizev1.ResourceRef{
+			{ID: "apps_config__ConfigMap", Version: "v1"},
+			{ID: "apps_new__ConfigMap", Version: "v1"},
+		},
+	}
+
+	diffs, err := b.removedReleaseObjects(context.TODO(), inventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range diffs {
+		got = append(got, string(d.Action)+" "+d.subject)
+	}
+	expected := []string{"orphaned ConfigMap/apps/kept", "deleted ConfigMap/apps/old"}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected diffs (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	"github.com/fluxcd/pkg/apis/kustomize"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestComposeValues(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "apps"},
+			Data: map[string]string{
+				"values.yaml": "replicaCount: 1\nimage:\n  repository: podinfo\n  tag: 6.0.0\n",
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "apps"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		},
+	).Build()
+
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+		Spec: helmv2.HelmReleaseSpec{
+			ValuesFrom: []helmv2.ValuesReference{
+				{Kind: "ConfigMap", Name: "defaults"},
+				{Kind: "Secret", Name: "token", ValuesKey: "token", TargetPath: "auth.tokens[0]"},
+				{Kind: "ConfigMap", Name: "missing", Optional: true},
+			},
+			Values: &apiextensionsv1.JSON{Raw: []byte(`{"image":{"tag":"6.1.0"}}`)},
+		},
+	}
+
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("replicaCount: 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &HelmReleaseBuilder{client: kubeClient, valuesFiles: []string{valuesFile}}
+	values, err := b.composeValues(context.Background(), hr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"replicaCount": float64(3),
+		"image": map[string]interface{}{
+			"repository": "podinfo",
+			"tag":        "6.1.0",
+		},
+		"auth": map[string]interface{}{
+			"tokens": []interface{}{"s3cr3t"},
+		},
+	}
+	if diff := cmp.Diff(expected, values); diff != "" {
+		t.Errorf("unexpected values (-want +got):\n%s", diff)
+	}
+
+	hr.Spec.ValuesFrom = append(hr.Spec.ValuesFrom, helmv2.ValuesReference{Kind: "ConfigMap", Name: "missing"})
+	if _, err := b.composeValues(context.Background(), hr); err == nil {
+		t.Error("expected an error for a missing ConfigMap that isn't optional")
+	}
+}
+
+func TestRenderChart(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":          "apiVersion: v2\nname: podinfo\nversion: 6.0.0\n",
+		"templates/cm.yaml":   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n  namespace: {{ .Release.Namespace }}\ndata:\n  replicas: {{ .Values.replicas | quote }}\n",
+		"templates/test.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: {{ .Release.Name }}-test\n  annotations:\n    helm.sh/hook: test\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+		Spec: helmv2.HelmReleaseSpec{
+			TargetNamespace: "prod",
+		},
+	}
+
+	b := &HelmReleaseBuilder{}
+	manifests, err := b.renderChart(hr, chartPath, map[string]interface{}{"replicas": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"name: prod-podinfo", "namespace: prod", `replicas: "2"`} {
+		if !strings.Contains(string(manifests), want) {
+			t.Errorf("expected %q in the manifests:\n%s", want, manifests)
+		}
+	}
+	if strings.Contains(string(manifests), "podinfo-test") {
+		t.Errorf("unexpected test hook in the manifests:\n%s", manifests)
+	}
+
+	hr.Spec.Test = &helmv2.Test{Enable: true}
+	manifests, err = b.renderChart(hr, chartPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifests), "name: prod-podinfo-test") {
+		t.Errorf("expected the test hook in the manifests:\n%s", manifests)
+	}
+}
+
+func TestPostRenderKustomize(t *testing.T) {
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: apps
+spec:
+  template:
+    spec:
+      containers:
+      - name: podinfo
+        image: ghcr.io/stefanprodan/podinfo:6.0.0
+`
+	spec := helmv2.Kustomize{
+		PatchesStrategicMerge: []apiextensionsv1.JSON{
+			{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"podinfo","namespace":"apps"},"spec":{"replicas":2}}`)},
+		},
+		PatchesJSON6902: []kustomize.JSON6902Patch{
+			{
+				Target: kustomize.Selector{Kind: "Deployment", Name: "podinfo"},
+				Patch: []kustomize.JSON6902{
+					{Op: "add", Path: "/metadata/labels", Value: &apiextensionsv1.JSON{Raw: []byte(`{"team":"dev"}`)}},
+				},
+			},
+		},
+		Images: []kustomize.Image{
+			{Name: "ghcr.io/stefanprodan/podinfo", NewTag: "6.1.0"},
+		},
+	}
+
+	out, err := postRenderKustomize([]byte(manifests), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"replicas: 2", "team: dev", "image: ghcr.io/stefanprodan/podinfo:6.1.0"} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected %q in the post rendered manifests:\n%s", expected, out)
+		}
+	}
+}
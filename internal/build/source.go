@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/untar"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fetchSource downloads the artifact of the source referenced by the
+// Kustomization and extracts it in a temporary directory. It returns the
+// directory and the path of the Kustomization in it.
+func (b *Builder) fetchSource(ctx context.Context, kustomization *kustomizev1.Kustomization) (string, string, error) {
+	sourceRef := kustomization.Spec.SourceRef
+	namespacedName := types.NamespacedName{
+		Namespace: kustomization.Namespace,
+		Name:      sourceRef.Name,
+	}
+	if sourceRef.Namespace != "" {
+		namespacedName.Namespace = sourceRef.Namespace
+	}
+
+	var artifact *sourcev1.Artifact
+	switch sourceRef.Kind {
+	case sourcev1.GitRepositoryKind:
+		var source sourcev1.GitRepository
+		if err := b.client.Get(ctx, namespacedName, &source); err != nil {
+			return "", "", fmt.Errorf("failed to get %s %s: %w", sourceRef.Kind, namespacedName, err)
+		}
+		artifact = source.GetArtifact()
+	case sourcev1.BucketKind:
+		var source sourcev1.Bucket
+		if err := b.client.Get(ctx, namespacedName, &source); err != nil {
+			return "", "", fmt.Errorf("failed to get %s %s: %w", sourceRef.Kind, namespacedName, err)
+		}
+		artifact = source.GetArtifact()
+	default:
+		return "", "", fmt.Errorf("source kind '%s' is not supported", sourceRef.Kind)
+	}
+	if artifact == nil {
+		return "", "", fmt.Errorf("%s %s has no artifact", sourceRef.Kind, namespacedName)
+	}
+
+	data, err := FetchArtifact(ctx, b.restConfig, *artifact)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download the artifact of %s %s: %w", sourceRef.Kind, namespacedName, err)
+	}
+
+	dir, err := os.MkdirTemp("", kustomization.Name)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := untar.Untar(bytes.NewReader(data), dir); err != nil {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("failed to extract the artifact of %s %s: %w", sourceRef.Kind, namespacedName, err)
+	}
+
+	path, err := securejoin.SecureJoin(dir, kustomization.Spec.Path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	if fs, err := os.Stat(path); err != nil || !fs.IsDir() {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("path '%s' not found in the artifact of %s %s", kustomization.Spec.Path, sourceRef.Kind, namespacedName)
+	}
+	return dir, path, nil
+}
+
+// FetchArtifact downloads the artifact from the source-controller service
+// named in its URL, using the services proxy of the API server, then verifies
+// its checksum.
+func FetchArtifact(ctx context.Context, restConfig *rest.Config, artifact sourcev1.Artifact) ([]byte, error) {
+	u, err := url.Parse(artifact.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact URL %q: %w", artifact.URL, err)
+	}
+
+	// the service host is in the form <name>.<namespace>[.svc.cluster.local.]
+	host := strings.Split(u.Hostname(), ".")
+	if len(host) < 2 {
+		return nil, fmt.Errorf("invalid artifact URL %q: expected a service host", artifact.URL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	data, err := clientSet.CoreV1().Services(host[1]).ProxyGet(u.Scheme, host[0], port, u.Path, nil).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksum(data, artifact.Checksum); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// verifyChecksum compares the SHA1 or SHA256 checksum of the data with the
+// given one, and fails when the checksum is in another format.
+func verifyChecksum(data []byte, checksum string) error {
+	var sum string
+	switch len(checksum) {
+	case sha1.Size * 2:
+		s := sha1.Sum(data)
+		sum = hex.EncodeToString(s[:])
+	case sha256.Size * 2:
+		s := sha256.Sum256(data)
+		sum = hex.EncodeToString(s[:])
+	default:
+		return fmt.Errorf("unsupported checksum format '%s'", checksum)
+	}
+	if sum != checksum {
+		return fmt.Errorf("checksum mismatch, expected %s got %s", checksum, sum)
+	}
+	return nil
+}
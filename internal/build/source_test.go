@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+func TestFetchSourceErrors(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(utils.NewScheme()).WithObjects(
+		&sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "flux-system"},
+		},
+	).Build()
+	b := &Builder{client: kubeClient}
+
+	tests := []struct {
+		name      string
+		sourceRef kustomizev1.CrossNamespaceSourceReference
+		wantErr   string
+	}{
+		{
+			name:      "no artifact",
+			sourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "podinfo"},
+			wantErr:   "has no artifact",
+		},
+		{
+			name:      "not found",
+			sourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: sourcev1.BucketKind, Name: "podinfo"},
+			wantErr:   "failed to get Bucket",
+		},
+		{
+			name:      "unsupported kind",
+			sourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "OCIRepository", Name: "podinfo"},
+			wantErr:   "is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kustomization := &kustomizev1.Kustomization{
+				ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "flux-system"},
+				Spec:       kustomizev1.KustomizationSpec{SourceRef: tt.sourceRef, Path: "./kustomize"},
+			}
+			_, _, err := b.fetchSource(context.TODO(), kustomization)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fetchSource() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("chart")
+	sha1Sum := sha1.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  bool
+	}{
+		{name: "sha1", checksum: hex.EncodeToString(sha1Sum[:])},
+		{name: "sha256", checksum: hex.EncodeToString(sha256Sum[:])},
+		{name: "mismatch", checksum: strings.Repeat("0", sha256.Size*2), wantErr: true},
+		{name: "empty", checksum: "", wantErr: true},
+		{name: "unknown", checksum: strings.Repeat("0", 32), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChecksum(data, tt.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flags
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type EgressRule struct {
+	Host string
+	Port int
+}
+
+func (r *EgressRule) String() string {
+	if r.Host == "" {
+		return ""
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+}
+
+func (r *EgressRule) Set(str string) error {
+	if strings.TrimSpace(str) == "" {
+		return fmt.Errorf("no egress destination given, please specify %s", r.Description())
+	}
+
+	host, port, err := net.SplitHostPort(str)
+	if err != nil || host == "" {
+		return fmt.Errorf("invalid egress destination '%s', must be in format <host|cidr>:<port>", str)
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port '%s', must be a number between 1 and 65535", port)
+	}
+
+	if strings.Contains(host, "/") {
+		if _, _, err := net.ParseCIDR(host); err != nil {
+			return fmt.Errorf("invalid CIDR '%s': %w", host, err)
+		}
+	}
+
+	r.Host = host
+	r.Port = p
+	return nil
+}
+
+func (r *EgressRule) Type() string {
+	return "egressRule"
+}
+
+func (r *EgressRule) Description() string {
+	return "egress destination in the format '<host|cidr>:<port>', " +
+		"host names are resolved to the IP addresses they point to when the rule is generated"
+}
+
+// IsHostname returns true when the rule destination is a host name, rather
+// than an IP address or a CIDR.
+func (r *EgressRule) IsHostname() bool {
+	return !strings.Contains(r.Host, "/") && net.ParseIP(r.Host) == nil
+}
+
+// CIDRs returns the IP blocks of the rule destination.
+// Host names are resolved to the IP addresses they point to at the time of the call,
+// the returned blocks don't follow later changes of the DNS records.
+func (r *EgressRule) CIDRs() ([]string, error) {
+	if strings.Contains(r.Host, "/") {
+		return []string{r.Host}, nil
+	}
+
+	ips := []net.IP{net.ParseIP(r.Host)}
+	if ips[0] == nil {
+		var err error
+		ips, err = net.LookupIP(r.Host)
+		if err != nil {
+			return nil, fmt.Errorf("resolving '%s' failed: %w", r.Host, err)
+		}
+	}
+
+	var cidrs []string
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			cidrs = append(cidrs, ip.String()+"/32")
+		} else {
+			cidrs = append(cidrs, ip.String()+"/128")
+		}
+	}
+	return cidrs, nil
+}
@@ -0,0 +1,99 @@
+//go:build !e2e
+// +build !e2e
+
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEgressRule_Set(t *testing.T) {
+	tests := []struct {
+		name      string
+		str       string
+		expect    string
+		expectErr bool
+	}{
+		{"host", "github.com:443", "github.com:443", false},
+		{"cidr", "10.0.0.0/8:22", "10.0.0.0/8:22", false},
+		{"ipv6 cidr", "[fd00::/8]:443", "[fd00::/8]:443", false},
+		{"invalid cidr", "10.0.0.0/33:22", "", true},
+		{"no port", "github.com", "", true},
+		{"invalid port", "github.com:https", "", true},
+		{"port out of range", "github.com:70000", "", true},
+		{"empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r EgressRule
+			if err := r.Set(tt.str); (err != nil) != tt.expectErr {
+				t.Errorf("Set() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if str := r.String(); str != tt.expect {
+				t.Errorf("Set() = %v, expect %v", str, tt.expect)
+			}
+		})
+	}
+}
+
+func TestEgressRule_CIDRs(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		expect []string
+	}{
+		{"cidr", "10.0.0.0/8", []string{"10.0.0.0/8"}},
+		{"ipv4", "192.168.1.10", []string{"192.168.1.10/32"}},
+		{"ipv6", "fd00::1", []string{"fd00::1/128"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := EgressRule{Host: tt.host, Port: 443}
+			cidrs, err := r.CIDRs()
+			if err != nil {
+				t.Fatalf("CIDRs() error = %v", err)
+			}
+			if !reflect.DeepEqual(cidrs, tt.expect) {
+				t.Errorf("CIDRs() = %v, expect %v", cidrs, tt.expect)
+			}
+		})
+	}
+}
+
+func TestEgressRule_IsHostname(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		expect bool
+	}{
+		{"host", "github.com", true},
+		{"cidr", "10.0.0.0/8", false},
+		{"ipv4", "192.168.1.10", false},
+		{"ipv6", "fd00::1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := EgressRule{Host: tt.host, Port: 443}
+			if got := r.IsHostname(); got != tt.expect {
+				t.Errorf("IsHostname() = %v, expect %v", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var supportedHelmChartReconcileStrategies = []string{
+	sourcev1.ReconcileStrategyChartVersion,
+	sourcev1.ReconcileStrategyRevision,
+}
+
+type HelmChartReconcileStrategy string
+
+func (s *HelmChartReconcileStrategy) String() string {
+	return string(*s)
+}
+
+func (s *HelmChartReconcileStrategy) Set(str string) error {
+	if strings.TrimSpace(str) == "" {
+		return fmt.Errorf("no reconcile strategy given, must be one of: %s",
+			strings.Join(supportedHelmChartReconcileStrategies, ", "))
+	}
+	if !utils.ContainsItemString(supportedHelmChartReconcileStrategies, str) {
+		return fmt.Errorf("unsupported reconcile strategy '%s', must be one of: %s",
+			str, strings.Join(supportedHelmChartReconcileStrategies, ", "))
+	}
+	*s = HelmChartReconcileStrategy(str)
+	return nil
+}
+
+func (s *HelmChartReconcileStrategy) Type() string {
+	return "reconcileStrategy"
+}
+
+func (s *HelmChartReconcileStrategy) Description() string {
+	return fmt.Sprintf("the strategy used to determine when to package a new chart artifact, available options are: (%s)",
+		strings.Join(supportedHelmChartReconcileStrategies, ", "))
+}
@@ -26,14 +26,18 @@ import (
 var supportedHelmReleaseValuesFromKinds = []string{"Secret", "ConfigMap"}
 
 type HelmReleaseValuesFrom struct {
-	Kind string
-	Name string
+	Kind      string
+	Name      string
+	ValuesKey string
 }
 
 func (v *HelmReleaseValuesFrom) String() string {
 	if v.Name == "" {
 		return ""
 	}
+	if v.ValuesKey != "" {
+		return fmt.Sprintf("%s/%s:%s", v.Kind, v.Name, v.ValuesKey)
+	}
 	return fmt.Sprintf("%s/%s", v.Kind, v.Name)
 }
 
@@ -43,9 +47,17 @@ func (v *HelmReleaseValuesFrom) Set(str string) error {
 			v.Description())
 	}
 
-	sourceKind, sourceName := utils.ParseObjectKindName(str)
+	ref, valuesKey := str, ""
+	if i := strings.LastIndex(str, ":"); i > 0 {
+		ref, valuesKey = str[:i], str[i+1:]
+		if valuesKey == "" {
+			return fmt.Errorf("invalid Kubernetes object reference '%s', the values key cannot be empty", str)
+		}
+	}
+
+	sourceKind, sourceName := utils.ParseObjectKindName(ref)
 	if sourceKind == "" {
-		return fmt.Errorf("invalid Kubernetes object reference '%s', must be in format <kind>/<name>", str)
+		return fmt.Errorf("invalid Kubernetes object reference '%s', must be in format <kind>/<name>[:<values-key>]", str)
 	}
 	cleanSourceKind, ok := utils.ContainsEqualFoldItemString(supportedHelmReleaseValuesFromKinds, sourceKind)
 	if !ok {
@@ -55,6 +67,7 @@ func (v *HelmReleaseValuesFrom) Set(str string) error {
 
 	v.Name = sourceName
 	v.Kind = cleanSourceKind
+	v.ValuesKey = valuesKey
 
 	return nil
 }
@@ -65,8 +78,8 @@ func (v *HelmReleaseValuesFrom) Type() string {
 
 func (v *HelmReleaseValuesFrom) Description() string {
 	return fmt.Sprintf(
-		"Kubernetes object reference that contains the values.yaml data key in the format '<kind>/<name>', "+
-			"where kind must be one of: (%s)",
+		"Kubernetes object reference that contains the values in the format '<kind>/<name>[:<values-key>]', "+
+			"where kind must be one of: (%s) and the values key defaults to 'values.yaml'",
 		strings.Join(supportedHelmReleaseValuesFromKinds, ", "),
 	)
 }
@@ -32,6 +32,8 @@ func TestHelmReleaseValuesFrom_Set(t *testing.T) {
 	}{
 		{"supported", "Secret/foo", "Secret/foo", false},
 		{"lower case kind", "secret/foo", "Secret/foo", false},
+		{"values key", "ConfigMap/foo:values-prod.yaml", "ConfigMap/foo:values-prod.yaml", false},
+		{"empty values key", "ConfigMap/foo:", "", true},
 		{"unsupported", "Unsupported/kind", "", true},
 		{"invalid format", "Secret", "", true},
 		{"empty", "", "", true},
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+
+	"github.com/fluxcd/flux2/internal/utils"
+)
+
+var supportedRemediationStrategies = []string{
+	string(helmv2.RollbackRemediationStrategy),
+	string(helmv2.UninstallRemediationStrategy),
+}
+
+type RemediationStrategy string
+
+func (r *RemediationStrategy) String() string {
+	return string(*r)
+}
+
+func (r *RemediationStrategy) Set(str string) error {
+	if strings.TrimSpace(str) == "" {
+		return fmt.Errorf("no remediation strategy given, must be one of: %s",
+			strings.Join(supportedRemediationStrategies, ", "))
+	}
+	if !utils.ContainsItemString(supportedRemediationStrategies, str) {
+		return fmt.Errorf("unsupported remediation strategy '%s', must be one of: %s",
+			str, strings.Join(supportedRemediationStrategies, ", "))
+	}
+	*r = RemediationStrategy(str)
+	return nil
+}
+
+func (r *RemediationStrategy) Type() string {
+	return "remediationStrategy"
+}
+
+func (r *RemediationStrategy) Description() string {
+	return fmt.Sprintf("the action to perform when the upgrade retries are exhausted, available options are: (%s)",
+		strings.Join(supportedRemediationStrategies, ", "))
+}
@@ -33,6 +33,7 @@ func TestSourceBucketProvider_Set(t *testing.T) {
 		expectErr bool
 	}{
 		{"supported", sourcev1.GenericBucketProvider, sourcev1.GenericBucketProvider, false},
+		{"gcp", sourcev1.GoogleBucketProvider, sourcev1.GoogleBucketProvider, false},
 		{"unsupported", "unsupported", "", true},
 		{"empty", "", "", true},
 	}
@@ -90,6 +90,41 @@ func Apply(ctx context.Context, rcg genericclioptions.RESTClientGetter, manifest
 	return changeSet.String(), nil
 }
 
+// Diff performs a server-side apply dry-run of the given manifest and returns
+// the change set of the objects that would be created or configured.
+func Diff(ctx context.Context, rcg genericclioptions.RESTClientGetter, manifestPath string) (*ssa.ChangeSet, error) {
+	objs, err := readObjects(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no Kubernetes objects found at: %s", manifestPath)
+	}
+
+	if err := ssa.SetNativeKindsDefaults(objs); err != nil {
+		return nil, err
+	}
+
+	man, err := newManager(rcg)
+	if err != nil {
+		return nil, err
+	}
+
+	changeSet := ssa.NewChangeSet()
+	for _, u := range objs {
+		entry, _, _, err := man.Diff(ctx, u, ssa.DefaultDiffOptions())
+		if err != nil {
+			return nil, err
+		}
+		if entry.Action != string(ssa.UnchangedAction) {
+			changeSet.Add(*entry)
+		}
+	}
+
+	return changeSet, nil
+}
+
 func readObjects(manifestPath string) ([]*unstructured.Unstructured, error) {
 	if _, err := os.Stat(manifestPath); err != nil {
 		return nil, err
@@ -25,6 +25,8 @@ import (
 func TestGenerate(t *testing.T) {
 	opts := MakeDefaultOptions()
 	opts.TolerationKeys = []string{"node.kubernetes.io/controllers"}
+	opts.EgressRules = []EgressRule{{CIDR: "140.82.112.0/20", Port: 443}}
+	opts.PodDisruptionBudget = true
 	output, err := Generate(opts, "")
 	if err != nil {
 		t.Fatal(err)
@@ -41,6 +43,14 @@ func TestGenerate(t *testing.T) {
 		t.Errorf("toleration key '%s' not found", opts.TolerationKeys[0])
 	}
 
+	if !strings.Contains(output.Content, opts.EgressRules[0].CIDR) {
+		t.Errorf("egress CIDR '%s' not found", opts.EgressRules[0].CIDR)
+	}
+
+	if !strings.Contains(output.Content, "kind: PodDisruptionBudget") || !strings.Contains(output.Content, "maxUnavailable: 1") {
+		t.Errorf("pod disruption budgets not found")
+	}
+
 	warning := GetGenWarning(opts)
 	if !strings.HasPrefix(output.Content, warning) {
 		t.Errorf("Generation warning '%s' not found", warning)
@@ -88,6 +88,12 @@ func generate(base string, options Options) error {
 		return fmt.Errorf("generate node selector failed: %w", err)
 	}
 
+	if options.PodDisruptionBudget {
+		if err := execTemplate(options, pdbTmpl, path.Join(base, "pdb.yaml")); err != nil {
+			return fmt.Errorf("generate pod disruption budgets failed: %w", err)
+		}
+	}
+
 	if err := execTemplate(options, kustomizationTmpl, path.Join(base, "kustomization.yaml")); err != nil {
 		return fmt.Errorf("generate kustomization failed: %w", err)
 	}
@@ -36,6 +36,16 @@ type Options struct {
 	TargetPath             string
 	ClusterDomain          string
 	TolerationKeys         []string
+	EgressRules            []EgressRule
+	PodDisruptionBudget    bool
+	PDBMaxUnavailable      int
+}
+
+// EgressRule allows the controllers to open TCP connections
+// to the given IP block and port.
+type EgressRule struct {
+	CIDR string
+	Port int
 }
 
 func MakeDefaultOptions() Options {
@@ -56,6 +66,8 @@ func MakeDefaultOptions() Options {
 		Timeout:                time.Minute,
 		TargetPath:             "",
 		ClusterDomain:          "cluster.local",
+		PodDisruptionBudget:    false,
+		PDBMaxUnavailable:      1,
 	}
 }
 
@@ -41,6 +41,9 @@ resources:
   - namespace.yaml
 {{- if .NetworkPolicy }}
   - policies.yaml
+{{- end }}
+{{- if .PodDisruptionBudget }}
+  - pdb.yaml
 {{- end }}
   - roles
 {{- range .Components }}
@@ -104,6 +107,32 @@ patchesJson6902:
       value: --log-level={{$logLevel}}
 {{- end }}
 {{- end }}
+{{- if and .NetworkPolicy (gt (len .EgressRules) 0) }}
+- target:
+    group: networking.k8s.io
+    version: v1
+    kind: NetworkPolicy
+    name: allow-egress
+  patch: |-
+    - op: replace
+      path: /spec/egress
+      value:
+        - to:
+            - podSelector: {}
+        - ports:
+            - protocol: UDP
+              port: 53
+            - protocol: TCP
+              port: 53
+{{- range .EgressRules }}
+        - to:
+            - ipBlock:
+                cidr: {{.CIDR}}
+          ports:
+            - protocol: TCP
+              port: {{.Port}}
+{{- end }}
+{{- end }}
 
 {{- if $registry }}
 images:
@@ -160,6 +189,21 @@ fieldSpecs:
     create: true
 `
 
+var pdbTmpl = `{{- $maxUnavailable := .PDBMaxUnavailable }}
+{{- range .Components }}
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: {{.}}
+spec:
+  maxUnavailable: {{$maxUnavailable}}
+  selector:
+    matchLabels:
+      app: {{.}}
+{{- end }}
+`
+
 var namespaceTmpl = `---
 apiVersion: v1
 kind: Namespace